@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+)
+
+var clustersCmd = &cobra.Command{
+	Use:   "clusters",
+	Short: "List discovered clusters and cluster groups",
+	Long: `List the clusters discovered from the hub and the cluster groups they belong to.
+
+Cluster groups are defined in the config file and referenced as @name in
+--clusters and --exclude:
+
+  clusterGroups:
+    prod-eu: [prod-fra-*, prod-ams-1]
+    gpu:
+      selector: accel=nvidia
+    prod-eu-gpu: ["@prod-eu&@gpu"]
+
+Examples:
+  # List clusters with their groups
+  kubectl mc clusters
+
+  # List cluster groups and their members
+  kubectl mc clusters --groups
+
+  # Preview which clusters a selection targets
+  kubectl mc clusters --clusters '@prod-eu&!@gpu'`,
+	Args: cobra.NoArgs,
+	RunE: runClusters,
+}
+
+func init() {
+	rootCmd.AddCommand(clustersCmd)
+
+	clustersCmd.Flags().StringSliceVar(&clustersFlag, "clusters", []string{}, "comma-separated list of cluster names, patterns or @groups, combined with & and ! (e.g. '@prod&!@gpu')")
+	clustersCmd.Flags().StringSliceVar(&excludeFlag, "exclude", []string{}, "comma-separated list of cluster names, patterns or @groups to exclude")
+	clustersCmd.Flags().Bool("groups", false, "list cluster groups and their members instead of clusters")
+}
+
+func runClusters(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	hubContext, err := cmd.Flags().GetString("hub-context")
+	if err != nil {
+		return fmt.Errorf("failed to get hub-context flag: %w", err)
+	}
+
+	hubNamespace, err := cmd.Flags().GetString("hub-namespace")
+	if err != nil {
+		return fmt.Errorf("failed to get hub-namespace flag: %w", err)
+	}
+
+	hubClientFactory, err := client.NewFactory(hubContext, kubeConfigFlags)
+	if err != nil {
+		return fmt.Errorf("failed to create hub client factory: %w", err)
+	}
+
+	dynamicClient, err := hubClientFactory.DynamicClient()
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client for hub: %w", err)
+	}
+
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	clusters, err := newDiscovery(dynamicClient, hubContext, hubNamespace, refresh).ListClusters(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to discover clusters: %w", err)
+	}
+
+	clusters, err = filterClusters(clusters, clustersFlag, excludeFlag)
+	if err != nil {
+		return err
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+
+	groups := clusterGroups(pluginConfig)
+	selector := discovery.NewSelector(groups)
+	if showGroups, _ := cmd.Flags().GetBool("groups"); showGroups {
+		return printClusterGroups(os.Stdout, selector, groups, clusters)
+	}
+	return printClusters(os.Stdout, selector, clusters)
+}
+
+// printClusters prints a row per cluster with its health, version and groups
+func printClusters(w io.Writer, selector *discovery.Selector, clusters []discovery.ClusterInfo) error {
+	tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tHEALTHY\tVERSION\tGROUPS")
+	for _, cluster := range clusters {
+		groups, err := selector.GroupsOf(cluster)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", cluster.Name, cluster.Healthy, valueOrNone(cluster.KubernetesVersion), valueOrNone(strings.Join(groups, ",")))
+	}
+	return tw.Flush()
+}
+
+// printClusterGroups prints a row per group with its definition and the clusters it selects
+func printClusterGroups(w io.Writer, selector *discovery.Selector, groups map[string]discovery.ClusterGroup, clusters []discovery.ClusterInfo) error {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tDEFINITION\tCLUSTERS")
+	for _, name := range names {
+		members, err := selector.Members(name, clusters)
+		if err != nil {
+			return err
+		}
+		memberNames := make([]string, 0, len(members))
+		for _, member := range members {
+			memberNames = append(memberNames, member.Name)
+		}
+		fmt.Fprintf(tw, "@%s\t%s\t%s\n", name, groups[name], valueOrNone(strings.Join(memberNames, ",")))
+	}
+	return tw.Flush()
+}
+
+// valueOrNone returns <none> for empty values, like kubectl
+func valueOrNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "View and edit the kubectl-mc configuration file",
+	Long: `View and edit the kubectl-mc configuration file.
+
+The configuration is read from --config, $KUBECTL_MC_CONFIG or
+$HOME/.kube/kubectl-mc-config.yaml. KUBECTL_MC_* environment variables override
+the file, and flags override both.
+
+Examples:
+  # Show the effective configuration
+  kubectl mc config view
+
+  # Query at most 20 clusters at once
+  kubectl mc config set execution.maxConcurrency 20
+
+  # Check the configuration file for mistakes
+  kubectl mc config validate`,
+	// The config commands must work even when the file is invalid, so they
+	// skip the configuration loading every other command does
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+}
+
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Print the effective configuration",
+	Long: `Print the configuration file merged with the defaults and the KUBECTL_MC_*
+environment variables. Use --file-only to ignore the environment.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigView,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a value in the configuration file",
+	Long: fmt.Sprintf(`Set a value in the configuration file, creating the file if needed.
+
+Keys (environment variable):
+%s`, configKeysHelp()),
+	Args: cobra.ExactArgs(2),
+	RunE: runConfigSet,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate [FILE]",
+	Short: "Check a configuration file",
+	Long: `Check a configuration file, by default the one in use, for unknown fields and
+invalid values.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configViewCmd, configSetCmd, configValidateCmd)
+
+	configViewCmd.Flags().Bool("file-only", false, "ignore the KUBECTL_MC_* environment variables")
+}
+
+func runConfigView(cmd *cobra.Command, args []string) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := validateConfig(cfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if fileOnly, _ := cmd.Flags().GetBool("file-only"); !fileOnly {
+		if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+			return err
+		}
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	_, err = cmd.OutOrStdout().Write(data)
+	return err
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Read(path)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Set(args[0], args[1]); err != nil {
+		return err
+	}
+	if err := cfg.Save(path, args[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Set %s to %s in %s\n", args[0], args[1], path)
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		path = args[0]
+	}
+
+	if err := config.ValidateFile(path); err != nil {
+		return fmt.Errorf("%s is invalid: %w", path, err)
+	}
+	cfg, err := config.Read(path)
+	if err != nil {
+		return err
+	}
+	if err := validateConfig(cfg); err != nil {
+		return fmt.Errorf("%s is invalid: %w", path, err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", path)
+	return nil
+}
+
+// configKeysHelp lists the configurable keys and their environment variables
+func configKeysHelp() string {
+	envVars := config.EnvVars()
+	lines := make([]string, 0, len(envVars))
+	for _, key := range config.Keys() {
+		lines = append(lines, fmt.Sprintf("  %-26s %s", key, envVars[key]))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -4,11 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/suchpuppet/kubectl-mc/pkg/aggregator"
 	"github.com/suchpuppet/kubectl-mc/pkg/client"
-	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
 	"github.com/suchpuppet/kubectl-mc/pkg/executor"
 	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
 )
@@ -28,7 +28,14 @@ Examples:
   kubectl mc describe pods -n default
 
   # Describe a deployment
-  kubectl mc describe deployment my-app`,
+  kubectl mc describe deployment my-app
+
+  # Describe pods matching a label selector or a wildcard
+  kubectl mc describe pods -l app=nginx
+  kubectl mc describe pod nginx-*
+
+  # Describe custom resources
+  kubectl mc describe widgets.example.com my-widget`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: runDescribe,
 	}
@@ -38,12 +45,18 @@ func init() {
 	rootCmd.AddCommand(describeCmd)
 
 	// Add cluster filtering flags (reuse same flags as get)
-	describeCmd.Flags().StringSliceVar(&clustersFlag, "clusters", []string{}, "comma-separated list of cluster names or patterns")
-	describeCmd.Flags().StringSliceVar(&excludeFlag, "exclude", []string{}, "comma-separated list of cluster names or patterns to exclude")
+	describeCmd.Flags().StringSliceVar(&clustersFlag, "clusters", []string{}, "comma-separated list of cluster names, patterns or @groups, combined with & and ! (e.g. '@prod&!@gpu')")
+	describeCmd.Flags().StringSliceVar(&excludeFlag, "exclude", []string{}, "comma-separated list of cluster names, patterns or @groups to exclude")
 	describeCmd.Flags().BoolVar(&allClusters, "all-clusters", false, "target all clusters (explicit confirmation)")
 
 	// Add all-namespaces flag (kubectl standard -A)
 	describeCmd.Flags().BoolP("all-namespaces", "A", false, "query resources across all namespaces")
+	addNamespaceModeFlag(describeCmd)
+
+	// Add label selector flag (kubectl standard -l)
+	describeCmd.Flags().StringP("selector", "l", "", "label selector to filter resources (e.g. -l app=nginx)")
+
+	addQuietErrorsFlag(describeCmd)
 }
 
 func runDescribe(cmd *cobra.Command, args []string) error {
@@ -72,7 +85,8 @@ func runDescribe(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create discovery client
-	discoveryClient := discovery.NewClusterProfileDiscovery(dynamicClient, hubNamespace)
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	discoveryClient := newDiscovery(dynamicClient, hubContext, hubNamespace, refresh)
 
 	// Discover clusters
 	clusters, err := discoveryClient.ListClusters(ctx)
@@ -94,10 +108,13 @@ func runDescribe(cmd *cobra.Command, args []string) error {
 	}
 
 	// Filter clusters based on flags
-	filteredClusters := filterClusters(clusters, clustersFlag, excludeFlag)
+	filteredClusters, err := filterClusters(clusters, clustersFlag, excludeFlag)
+	if err != nil {
+		return err
+	}
 
 	// Create executor
-	exec := executor.NewExecutor(mappingManager, kubeConfigFlags)
+	exec := newExecutor(mappingManager)
 
 	// Extract resource type and name from args
 	resource := args[0]
@@ -106,29 +123,21 @@ func runDescribe(cmd *cobra.Command, args []string) error {
 		resourceName = args[1]
 	}
 
-	// Determine namespace to use
-	var namespace string
-	allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
-
-	if allNamespaces {
-		// -A flag: query all namespaces
-		namespace = ""
-	} else if cmd.Flags().Changed("namespace") {
-		// -n flag explicitly set: use that namespace
-		namespace, _ = cmd.Flags().GetString("namespace")
-	} else {
-		// Neither flag set: use kubeconfig default namespace
-		// Pass the namespace from kubeConfigFlags which respects kubeconfig context
-		if kubeConfigFlags.Namespace != nil && *kubeConfigFlags.Namespace != "" {
-			namespace = *kubeConfigFlags.Namespace
-		} else {
-			// No namespace in kubeconfig either, default to "default"
-			namespace = "default"
-		}
+	selector, _ := cmd.Flags().GetString("selector")
+	if selector != "" && resourceName != "" && !strings.ContainsAny(resourceName, "*?[") {
+		return fmt.Errorf("name cannot be provided when a selector is specified")
 	}
 
 	// Execute describe across all clusters
-	results, err := exec.Describe(ctx, filteredClusters, resource, resourceName, namespace)
+	query := executor.ResourceQuery{
+		Resource:      resource,
+		Name:          resourceName,
+		LabelSelector: selector,
+	}
+	if err := applyNamespaces(cmd, &query); err != nil {
+		return err
+	}
+	results, err := exec.Describe(ctx, filteredClusters, query)
 	if err != nil {
 		return fmt.Errorf("failed to execute describe: %w", err)
 	}
@@ -139,14 +148,6 @@ func runDescribe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to aggregate results: %w", err)
 	}
 
-	// Only print errors if ALL clusters failed (when at least one succeeded, silently ignore failures)
-	if results.Summary.Failed > 0 && results.Summary.Successful == 0 {
-		fmt.Fprintf(os.Stderr, "\nError: Failed to query all %d clusters\n", results.Summary.Total)
-		for cluster, err := range results.Summary.Errors {
-			fmt.Fprintf(os.Stderr, "  - %s: %v\n", cluster, err)
-		}
-		return fmt.Errorf("all clusters failed")
-	}
-
-	return nil
+	quietErrors, _ := cmd.Flags().GetBool("quiet-errors")
+	return checkClusterFailures(cmd, os.Stderr, results, quietErrors)
 }
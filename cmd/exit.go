@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/aggregator"
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+)
+
+// Exit codes let scripts tell a complete answer from an incomplete one
+const (
+	// ExitSuccess means every cluster answered
+	ExitSuccess = 0
+
+	// ExitError means the command failed before or regardless of the clusters,
+	// e.g. an invalid flag or an unreachable hub
+	ExitError = 1
+
+	// ExitPartialFailure means results were printed but some clusters failed
+	ExitPartialFailure = 2
+
+	// ExitTotalFailure means every cluster failed
+	ExitTotalFailure = 3
+)
+
+// clusterFailureError reports that some or all clusters failed
+type clusterFailureError struct {
+	failed  int
+	partial int
+	total   int
+}
+
+func (e *clusterFailureError) Error() string {
+	switch {
+	case e.total > 0 && e.failed == e.total:
+		return fmt.Sprintf("all %d clusters failed", e.total)
+	case e.failed > 0:
+		return fmt.Sprintf("%d of %d clusters failed", e.failed, e.total)
+	default:
+		return fmt.Sprintf("%d of %d clusters returned partial results", e.partial, e.total)
+	}
+}
+
+// exitCode returns the exit code matching how many clusters failed
+func (e *clusterFailureError) exitCode() int {
+	if e.total > 0 && e.failed == e.total {
+		return ExitTotalFailure
+	}
+	return ExitPartialFailure
+}
+
+// ExitCode returns the process exit code for the error returned by Execute
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+	var failure *clusterFailureError
+	if errors.As(err, &failure) {
+		return failure.exitCode()
+	}
+	return ExitError
+}
+
+// checkClusterFailures reports failed clusters on stderr, unless quiet, and
+// returns an error carrying the partial or total failure exit code. Clusters
+// that only failed in part make the result a partial failure.
+func checkClusterFailures(cmd *cobra.Command, stderr io.Writer, results *executor.AggregatedResults, quiet bool) error {
+	errs := aggregator.ClusterErrors(results)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	if !quiet {
+		aggregator.PrintClusterErrors(stderr, results, colorizeStderr())
+	}
+
+	failure := &clusterFailureError{failed: results.Summary.Failed, total: results.Summary.Total}
+	for _, e := range errs {
+		if e.Partial {
+			failure.partial++
+		}
+	}
+
+	// The failures were already listed, or deliberately silenced; a partial
+	// failure still printed results, so it is not worth an error line either
+	if !quiet || failure.exitCode() == ExitPartialFailure {
+		cmd.SilenceErrors = true
+	}
+	return failure
+}
+
+// addQuietErrorsFlag adds the --quiet-errors flag to a command that queries clusters
+func addQuietErrorsFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("quiet-errors", false, "do not list failed clusters on stderr (the exit code still reports them)")
+}
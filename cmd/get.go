@@ -4,7 +4,8 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/suchpuppet/kubectl-mc/pkg/aggregator"
@@ -30,14 +31,50 @@ Examples:
 
   # Get a specific pod
   kubectl mc get pod nginx
+
+  # List several resource types at once, one table per kind
+  kubectl mc get pods,services,deployments
+  kubectl mc get all -n default
   
   # List pods across all namespaces
   kubectl mc get pods -A
+
+  # Several namespaces, namespace patterns, or each cluster's own namespace
+  kubectl mc get deployments -n team-a,team-b
+  kubectl mc get deployments -n 'team-*'
+  kubectl mc get deployments --namespace-mode=per-cluster
   
+  # Filter by label selector
+  kubectl mc get pods -l app=nginx
+
   # Use wildcards in resource names
   kubectl mc get pod nginx-*
   kubectl mc get deployment app-???-prod
   
+  # Print objects as JSON, YAML or names; each object records its cluster
+  kubectl mc get pods -o yaml
+  kubectl mc get pods -o wide
+  kubectl mc get pods -o jsonpath='{range .items[*]}{.cluster}{"\t"}{.metadata.name}{"\n"}{end}'
+  kubectl mc get pods -o custom-columns=CLUSTER:.cluster,NAME:.metadata.name,NODE:.spec.nodeName
+
+  # Find the most restarted and the oldest pods across the fleet
+  kubectl mc get pods -A --sort-by=.status.containerStatuses[0].restartCount
+  kubectl mc get pods -A --sort-by=.metadata.creationTimestamp
+
+  # One section per cluster, with label columns and no headers
+  kubectl mc get deployments --group-by=cluster -L app,team
+  kubectl mc get pods --no-headers --cluster-column=last --show-labels
+
+  # One row per replicated deployment, flagging clusters where it is missing or differs
+  kubectl mc get deploy -A --merge
+
+  # Stream one JSON object per line as each cluster responds
+  kubectl mc get pods -A -o jsonl | jq -r 'select(.error == null) | .cluster + " " + .object.metadata.name'
+
+  # Watch pods across all clusters as they change
+  kubectl mc get pods -w
+  kubectl mc get deployment nginx --watch-only
+
   # Filter by cluster patterns (supports wildcards)
   kubectl mc get pods --clusters=prod-*
   kubectl mc get deployments --exclude=*-staging`,
@@ -55,16 +92,39 @@ func init() {
 	rootCmd.AddCommand(getCmd)
 
 	// Add cluster filtering flags
-	getCmd.Flags().StringSliceVar(&clustersFlag, "clusters", []string{}, "comma-separated list of cluster names or patterns")
-	getCmd.Flags().StringSliceVar(&excludeFlag, "exclude", []string{}, "comma-separated list of cluster names or patterns to exclude")
+	getCmd.Flags().StringSliceVar(&clustersFlag, "clusters", []string{}, "comma-separated list of cluster names, patterns or @groups, combined with & and ! (e.g. '@prod&!@gpu')")
+	getCmd.Flags().StringSliceVar(&excludeFlag, "exclude", []string{}, "comma-separated list of cluster names, patterns or @groups to exclude")
 	getCmd.Flags().BoolVar(&allClusters, "all-clusters", false, "target all clusters (explicit confirmation)")
 
 	// Add all-namespaces flag (kubectl standard -A)
 	getCmd.Flags().BoolP("all-namespaces", "A", false, "query resources across all namespaces")
+	addNamespaceModeFlag(getCmd)
+
+	// Add label selector flag (kubectl standard -l)
+	getCmd.Flags().StringP("selector", "l", "", "label selector to filter resources (e.g. -l app=nginx)")
+
+	// Add output format flag (kubectl standard -o)
+	getCmd.Flags().StringP("output", "o", "", "output format: json|jsonl|yaml|name|wide|jsonpath=...|go-template=...|custom-columns=... (and the -file variants)")
+
+	// Add sorting and column flags (kubectl standard --sort-by, --no-headers, --show-labels, -L)
+	getCmd.Flags().String("sort-by", "", "sort the merged results of all clusters by a jsonpath expression (e.g. .metadata.creationTimestamp)")
+	getCmd.Flags().String("group-by", aggregator.GroupByNone, "print a section per cluster or namespace: cluster|namespace|none")
+	getCmd.Flags().Bool("no-headers", false, "don't print column headers")
+	getCmd.Flags().Bool("show-labels", false, "show all labels as the last column")
+	getCmd.Flags().StringSliceP("label-columns", "L", []string{}, "comma-separated list of labels to print as columns")
+	getCmd.Flags().String("cluster-column", "", "position of the CLUSTER column: first|last|hidden (default from config, first)")
+	getCmd.Flags().Bool("merge", false, "print one row per object found in several clusters, flagging clusters where it is missing or differs")
+
+	// Add watch flags (kubectl standard -w)
+	getCmd.Flags().BoolP("watch", "w", false, "after listing the requested resources, watch for changes on every cluster")
+	getCmd.Flags().Bool("watch-only", false, "watch for changes on every cluster, without listing the current resources first")
+
+	addQuietErrorsFlag(getCmd)
 }
 
 func runGet(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
 	// Get hub context
 	hubContext, err := cmd.Flags().GetString("hub-context")
@@ -89,7 +149,8 @@ func runGet(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create discovery client
-	discoveryClient := discovery.NewClusterProfileDiscovery(dynamicClient, hubNamespace)
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	discoveryClient := newDiscovery(dynamicClient, hubContext, hubNamespace, refresh)
 
 	// Discover clusters
 	clusters, err := discoveryClient.ListClusters(ctx)
@@ -111,10 +172,13 @@ func runGet(cmd *cobra.Command, args []string) error {
 	}
 
 	// Filter clusters based on flags
-	filteredClusters := filterClusters(clusters, clustersFlag, excludeFlag)
+	filteredClusters, err := filterClusters(clusters, clustersFlag, excludeFlag)
+	if err != nil {
+		return err
+	}
 
 	// Create executor
-	exec := executor.NewExecutor(mappingManager, kubeConfigFlags)
+	exec := newExecutor(mappingManager)
 
 	// Extract resource type and name from args
 	resource := args[0]
@@ -123,89 +187,186 @@ func runGet(cmd *cobra.Command, args []string) error {
 		resourceName = args[1]
 	}
 
-	// Determine namespace to use
-	var namespace string
-	allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
+	selector, _ := cmd.Flags().GetString("selector")
+	if selector != "" && resourceName != "" && !strings.ContainsAny(resourceName, "*?[") {
+		return fmt.Errorf("name cannot be provided when a selector is specified")
+	}
 
-	if allNamespaces {
-		// -A flag: query all namespaces
-		namespace = ""
-	} else if cmd.Flags().Changed("namespace") {
-		// -n flag explicitly set: use that namespace
-		namespace, _ = cmd.Flags().GetString("namespace")
-	} else {
-		// Neither flag set: use kubeconfig default namespace
-		// Pass the namespace from kubeConfigFlags which respects kubeconfig context
-		if kubeConfigFlags.Namespace != nil && *kubeConfigFlags.Namespace != "" {
-			namespace = *kubeConfigFlags.Namespace
-		} else {
-			// No namespace in kubeconfig either, default to "default"
-			namespace = "default"
-		}
+	output, _ := cmd.Flags().GetString("output")
+	format, err := aggregator.ParseOutputFormat(output)
+	if err != nil {
+		return err
+	}
+
+	sortBy, _ := cmd.Flags().GetString("sort-by")
+	groupBy, _ := cmd.Flags().GetString("group-by")
+	noHeaders, _ := cmd.Flags().GetBool("no-headers")
+	showLabels, _ := cmd.Flags().GetBool("show-labels")
+	labelColumns, _ := cmd.Flags().GetStringSlice("label-columns")
+	merge, _ := cmd.Flags().GetBool("merge")
+	quietErrors, _ := cmd.Flags().GetBool("quiet-errors")
+
+	tableOptions := aggregator.TableOptions{
+		Wide:         format.Name == aggregator.OutputWide,
+		SortBy:       sortBy,
+		GroupBy:      groupBy,
+		NoHeaders:    noHeaders,
+		ShowLabels:   showLabels,
+		LabelColumns: labelColumns,
+		// --cluster-column was applied on top of output.clusterColumn by loadConfig
+		ClusterColumn: pluginConfig.Output.ClusterColumn,
+		Merge:         merge,
+	}
+	if err := tableOptions.Validate(); err != nil {
+		return err
+	}
+	if merge && !format.IsTable() {
+		return fmt.Errorf("--merge is only supported with table output")
 	}
 
 	// Execute get across all clusters
-	results, err := exec.Get(ctx, filteredClusters, resource, resourceName, namespace)
+	query := executor.ResourceQuery{
+		Resource:      resource,
+		Name:          resourceName,
+		LabelSelector: selector,
+		// Server-side tables only carry object metadata, so other formats need full objects
+		AsTable: format.IsTable(),
+		// Sorting by a field outside the metadata, and comparing merged copies,
+		// need the full objects too
+		TableObjects: sortBy != "" || merge,
+	}
+	if err := applyNamespaces(cmd, &query); err != nil {
+		return err
+	}
+
+	watchFlag, _ := cmd.Flags().GetBool("watch")
+	watchOnly, _ := cmd.Flags().GetBool("watch-only")
+	if watchFlag || watchOnly {
+		if merge {
+			return fmt.Errorf("--merge is not supported in watch mode")
+		}
+		if len(query.Namespaces) > 1 {
+			return fmt.Errorf("watch supports a single namespace per cluster; use -A to watch all namespaces")
+		}
+		var printer watchEventPrinter
+		switch {
+		case format.Name == aggregator.OutputJSONLines:
+			printer = aggregator.NewJSONLinesPrinter(os.Stdout, hubDisplayName(hubContext))
+		case format.IsTable():
+			printer = aggregator.NewWatchPrinter(os.Stdout, os.Stderr)
+		default:
+			return fmt.Errorf("watch does not support output format %q", output)
+		}
+		return runWatch(ctx, exec, filteredClusters, query, watchOnly, printer)
+	}
+
+	// JSON lines are written as each cluster responds rather than after all have
+	if format.Name == aggregator.OutputJSONLines {
+		printer := aggregator.NewJSONLinesPrinter(os.Stdout, hubDisplayName(hubContext))
+		var printErr error
+		results, err := exec.GetStream(ctx, filteredClusters, query, func(result executor.ClusterResult) {
+			if printErr == nil {
+				printErr = printer.PrintResult(result)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("failed to execute get: %w", err)
+		}
+		if printErr != nil {
+			return fmt.Errorf("failed to print results: %w", printErr)
+		}
+		return checkClusterFailures(cmd, os.Stderr, results, quietErrors)
+	}
+
+	results, err := exec.Get(ctx, filteredClusters, query)
 	if err != nil {
 		return fmt.Errorf("failed to execute get: %w", err)
 	}
 
 	// Aggregate and format results
-	agg := aggregator.NewTableAggregator(os.Stdout)
-	if err := agg.AggregateGetResults(results, resource); err != nil {
-		return fmt.Errorf("failed to aggregate results: %w", err)
-	}
-
-	// Only print errors if ALL clusters failed (when at least one succeeded, silently ignore failures)
-	if results.Summary.Failed > 0 && results.Summary.Successful == 0 {
-		fmt.Fprintf(os.Stderr, "\nError: Failed to query all %d clusters\n", results.Summary.Total)
-		for cluster, err := range results.Summary.Errors {
-			fmt.Fprintf(os.Stderr, "  - %s: %v\n", cluster, err)
+	if format.IsTable() {
+		agg := aggregator.NewTableAggregator(os.Stdout)
+		if err := agg.SetOptions(tableOptions); err != nil {
+			return err
+		}
+		if err := agg.AggregateGetResults(results, resource); err != nil {
+			return fmt.Errorf("failed to aggregate results: %w", err)
+		}
+	} else {
+		printer, err := aggregator.NewObjectPrinter(os.Stdout, format)
+		if err != nil {
+			return err
+		}
+		if err := printer.SetSortBy(sortBy); err != nil {
+			return err
+		}
+		if err := printer.PrintGetResults(results, resource); err != nil {
+			return fmt.Errorf("failed to print results: %w", err)
 		}
-		return fmt.Errorf("all clusters failed")
 	}
 
-	return nil
+	// Results from the clusters that answered are printed first, then the failures
+	return checkClusterFailures(cmd, os.Stderr, results, quietErrors)
 }
 
-// filterClusters applies cluster filtering based on --clusters and --exclude flags
-func filterClusters(clusters []discovery.ClusterInfo, include, exclude []string) []discovery.ClusterInfo {
-	// If no filtering specified, return all clusters
-	if len(include) == 0 && len(exclude) == 0 {
-		return clusters
+// watchEventPrinter prints the events of a multi-cluster watch
+type watchEventPrinter interface {
+	PrintEvent(event executor.WatchEvent) error
+}
+
+// runWatch streams changes to the queried resources from every cluster until interrupted
+func runWatch(ctx context.Context, exec *executor.Executor, clusters []discovery.ClusterInfo, query executor.ResourceQuery, watchOnly bool, printer watchEventPrinter) error {
+	resource := strings.ToLower(query.Resource)
+	if strings.Contains(resource, ",") || resource == "all" {
+		return fmt.Errorf("watch is only supported on a single resource type, got %q", query.Resource)
 	}
 
-	filtered := make([]discovery.ClusterInfo, 0)
+	failed := 0
 
-	for _, cluster := range clusters {
-		// Skip if in exclude list
-		if matchesAny(cluster.Name, exclude) {
-			continue
+	for event := range exec.Watch(ctx, clusters, query, executor.WatchOptions{WatchOnly: watchOnly}) {
+		if event.Status == executor.WatchStatusFailed {
+			failed++
 		}
-
-		// Include if no include list specified, or if matches include list
-		if len(include) == 0 || matchesAny(cluster.Name, include) {
-			filtered = append(filtered, cluster)
+		if err := printer.PrintEvent(event); err != nil {
+			return fmt.Errorf("failed to print watch event: %w", err)
 		}
 	}
 
-	return filtered
+	// Failures were printed as they happened; the error sets the exit code.
+	// The stream only ends on its own when no cluster could be watched.
+	if failed > 0 {
+		return &clusterFailureError{failed: failed, total: len(clusters)}
+	}
+
+	return nil
 }
 
-// matchesAny checks if a string matches any of the patterns.
-// Supports glob patterns: * (any chars), ? (single char), [abc] (char class)
-func matchesAny(str string, patterns []string) bool {
-	for _, pattern := range patterns {
-		// Try exact match first
-		if str == pattern {
-			return true
-		}
+// hubDisplayName returns the name of the hub context, resolving the current
+// kubeconfig context when no hub context was given
+func hubDisplayName(hubContext string) string {
+	if hubContext != "" {
+		return hubContext
+	}
+	rawConfig, err := kubeConfigFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return ""
+	}
+	return rawConfig.CurrentContext
+}
 
-		// Try glob pattern match (supports *, ?, [abc], etc.)
-		matched, err := filepath.Match(pattern, str)
-		if err == nil && matched {
-			return true
-		}
+// filterClusters applies cluster filtering based on the --clusters and --exclude
+// flags, resolving @group references against the configured cluster groups
+func filterClusters(clusters []discovery.ClusterInfo, include, exclude []string) ([]discovery.ClusterInfo, error) {
+	// The hub profile's defaults apply when no selection is given
+	if len(include) == 0 {
+		include = hubProfile.Clusters
+	}
+	if len(exclude) == 0 {
+		exclude = hubProfile.Exclude
+	}
+	filtered, err := discovery.NewSelector(clusterGroups(pluginConfig)).Filter(clusters, include, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster selection: %w", err)
 	}
-	return false
+	return filtered, nil
 }
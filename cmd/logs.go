@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/aggregator"
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+)
+
+// chronologicalDelay is how long followed lines are held back so lines from
+// slower clusters can still be merged in timestamp order
+const chronologicalDelay = 2 * time.Second
+
+var (
+	// logsCmd represents the logs command
+	logsCmd = &cobra.Command{
+		Use:   "logs [pod | type/name]",
+		Short: "Print container logs across multiple clusters",
+		Long: `Print the logs of matching pods on every discovered cluster. Each line is
+prefixed with [cluster/pod/container].
+
+Examples:
+  # Logs of a pod, wherever it runs
+  kubectl mc logs nginx
+
+  # Logs of every pod matching a wildcard or a label selector
+  kubectl mc logs nginx-*
+  kubectl mc logs -l app=nginx --all-containers
+
+  # Logs of the pods of a deployment on every cluster
+  kubectl mc logs deploy/nginx -c nginx
+
+  # Follow the last 10 lines of each container
+  kubectl mc logs deploy/nginx -f --tail=10
+
+  # Merge lines from all clusters in timestamp order
+  kubectl mc logs -l app=api --since=10m --chronological`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runLogs,
+	}
+)
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	// Add cluster filtering flags (reuse same flags as get)
+	logsCmd.Flags().StringSliceVar(&clustersFlag, "clusters", []string{}, "comma-separated list of cluster names, patterns or @groups, combined with & and ! (e.g. '@prod&!@gpu')")
+	logsCmd.Flags().StringSliceVar(&excludeFlag, "exclude", []string{}, "comma-separated list of cluster names, patterns or @groups to exclude")
+	logsCmd.Flags().BoolVar(&allClusters, "all-clusters", false, "target all clusters (explicit confirmation)")
+
+	// Add pod selection flags (kubectl standard -A, -l, -c)
+	logsCmd.Flags().BoolP("all-namespaces", "A", false, "read logs of pods in all namespaces")
+	addNamespaceModeFlag(logsCmd)
+	logsCmd.Flags().StringP("selector", "l", "", "label selector to filter pods (e.g. -l app=nginx)")
+	logsCmd.Flags().StringP("container", "c", "", "print the logs of this container")
+	logsCmd.Flags().Bool("all-containers", false, "print the logs of all containers in each pod")
+
+	// Add log retrieval flags (kubectl standard)
+	logsCmd.Flags().BoolP("follow", "f", false, "stream the logs as they are written")
+	logsCmd.Flags().Duration("since", 0, "only return logs newer than a relative duration like 5s, 2m, or 3h")
+	logsCmd.Flags().Int64("tail", -1, "lines of recent log to display per container, -1 shows all lines")
+	logsCmd.Flags().Bool("timestamps", false, "include timestamps on each line")
+	logsCmd.Flags().Bool("chronological", false, "merge lines from all clusters in timestamp order")
+
+	addQuietErrorsFlag(logsCmd)
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	selector, _ := cmd.Flags().GetString("selector")
+	if len(args) == 0 && selector == "" {
+		return fmt.Errorf("a pod, type/name or selector (-l) is required")
+	}
+
+	// Accept "nginx", "nginx-*", "pod/nginx" and "deploy/nginx", as kubectl does
+	resource := "pods"
+	var resourceName string
+	if len(args) > 0 {
+		resourceName = args[0]
+		if kind, name, found := strings.Cut(args[0], "/"); found {
+			resource, resourceName = kind, name
+		}
+	}
+	if selector != "" && resourceName != "" && !strings.ContainsAny(resourceName, "*?[") {
+		return fmt.Errorf("name cannot be provided when a selector is specified")
+	}
+
+	// Get hub context
+	hubContext, err := cmd.Flags().GetString("hub-context")
+	if err != nil {
+		return fmt.Errorf("failed to get hub-context flag: %w", err)
+	}
+
+	hubNamespace, err := cmd.Flags().GetString("hub-namespace")
+	if err != nil {
+		return fmt.Errorf("failed to get hub-namespace flag: %w", err)
+	}
+
+	// Create hub client
+	hubClientFactory, err := client.NewFactory(hubContext, kubeConfigFlags)
+	if err != nil {
+		return fmt.Errorf("failed to create hub client factory: %w", err)
+	}
+
+	dynamicClient, err := hubClientFactory.DynamicClient()
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client for hub: %w", err)
+	}
+
+	// Discover clusters
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	clusters, err := newDiscovery(dynamicClient, hubContext, hubNamespace, refresh).ListClusters(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to discover clusters: %w", err)
+	}
+
+	if len(clusters) == 0 {
+		fmt.Fprintf(os.Stderr, "No clusters discovered from hub\n")
+		return nil
+	}
+
+	// Load kubeconfig mappings
+	mappingManager, err := kubeconfig.NewManager("")
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	}
+
+	// Filter clusters based on flags
+	filteredClusters, err := filterClusters(clusters, clustersFlag, excludeFlag)
+	if err != nil {
+		return err
+	}
+
+	// Create executor
+	exec := newExecutor(mappingManager)
+
+	container, _ := cmd.Flags().GetString("container")
+	allContainers, _ := cmd.Flags().GetBool("all-containers")
+	follow, _ := cmd.Flags().GetBool("follow")
+	since, _ := cmd.Flags().GetDuration("since")
+	tail, _ := cmd.Flags().GetInt64("tail")
+	timestamps, _ := cmd.Flags().GetBool("timestamps")
+	chronological, _ := cmd.Flags().GetBool("chronological")
+
+	if container != "" && allContainers {
+		return fmt.Errorf("--container and --all-containers cannot be used together")
+	}
+
+	query := executor.ResourceQuery{
+		Resource:      resource,
+		Name:          resourceName,
+		LabelSelector: selector,
+	}
+	if err := applyNamespaces(cmd, &query); err != nil {
+		return err
+	}
+
+	// Merging chronologically needs timestamps even when they are not printed
+	opts := executor.LogOptions{
+		Container:     container,
+		AllContainers: allContainers,
+		Follow:        follow,
+		Since:         since,
+		Tail:          tail,
+		Timestamps:    timestamps || chronological,
+	}
+
+	printer := aggregator.NewLogPrinter(os.Stdout, timestamps, chronological)
+
+	var ticker <-chan time.Time
+	if chronological && follow {
+		t := time.NewTicker(chronologicalDelay / 4)
+		defer t.Stop()
+		ticker = t.C
+	}
+
+	quietErrors, _ := cmd.Flags().GetBool("quiet-errors")
+
+	// A cluster that printed lines before failing only failed in part
+	printed := make(map[string]bool)
+	failures := make(map[string]error)
+
+	noPods := 0
+	lines := exec.Logs(ctx, filteredClusters, query, opts)
+	for done := false; !done; {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				done = true
+				break
+			}
+
+			// A pod usually only runs on some clusters; only report it when it runs nowhere
+			if errors.Is(line.Error, executor.ErrNoPodsFound) {
+				noPods++
+				continue
+			}
+			if line.Error != nil {
+				failures[line.Cluster] = line.Error
+				// Followed logs may stream for a long time; say so right away
+				if follow && !quietErrors {
+					fmt.Fprintf(os.Stderr, "Error from cluster %s: %v\n", line.Cluster, line.Error)
+				}
+				continue
+			}
+
+			printed[line.Cluster] = true
+			if err := printer.Print(line); err != nil {
+				return fmt.Errorf("failed to print logs: %w", err)
+			}
+		case <-ticker:
+			if err := printer.Flush(time.Now().Add(-chronologicalDelay)); err != nil {
+				return fmt.Errorf("failed to print logs: %w", err)
+			}
+		}
+	}
+
+	if err := printer.Close(); err != nil {
+		return fmt.Errorf("failed to print logs: %w", err)
+	}
+
+	if len(filteredClusters) > 0 && noPods == len(filteredClusters) {
+		return fmt.Errorf("no pods found on any cluster")
+	}
+
+	results := executor.NewAggregatedResults(filteredClusters)
+	for _, cluster := range filteredClusters {
+		err := failures[cluster.Name]
+		results.AddResult(executor.ClusterResult{
+			ClusterName: cluster.Name,
+			Success:     err == nil || printed[cluster.Name],
+			Error:       err,
+		})
+	}
+	return checkClusterFailures(cmd, os.Stderr, results, quietErrors)
+}
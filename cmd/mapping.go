@@ -0,0 +1,477 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/version"
+)
+
+var mappingCmd = &cobra.Command{
+	Use:   "mapping",
+	Short: "Manage cluster-to-context mappings",
+	Long: `Manage the mappings between ClusterProfile names and kubeconfig contexts stored
+in ~/.kube/kubectl-mc-clusters.yaml.
+
+Every subcommand accepts -o yaml or -o json. The output of list, remove and
+prune is a mapping file that set -f imports again.
+
+Examples:
+  # List mappings
+  kubectl mc mapping list
+
+  # Map a cluster to a context
+  kubectl mc mapping set prod-us-1 arn:aws:eks:us-east-1:123:cluster/prod-us-1
+
+  # Export mappings and import them on another machine
+  kubectl mc mapping list -o yaml > mappings.yaml
+  kubectl mc mapping set -f mappings.yaml
+
+  # Remove mappings of clusters the hub no longer reports
+  kubectl mc mapping prune --dry-run
+
+  # Check that every mapped context exists and is reachable
+  kubectl mc mapping validate`,
+}
+
+var mappingListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List cluster-to-context mappings",
+	Args:    cobra.NoArgs,
+	RunE:    runMappingList,
+}
+
+var mappingSetCmd = &cobra.Command{
+	Use:   "set CLUSTER CONTEXT | set -f FILE",
+	Short: "Map a cluster to a context, or import mappings from a file",
+	Long: `Map a cluster to a kubeconfig context, or import the mappings of a file
+written by kubectl mc mapping list -o yaml. Imported mappings replace existing
+mappings of the same clusters; --replace removes all other mappings.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if file, _ := cmd.Flags().GetString("filename"); file != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	RunE: runMappingSet,
+}
+
+var mappingRemoveCmd = &cobra.Command{
+	Use:     "remove CLUSTER...",
+	Aliases: []string{"rm"},
+	Short:   "Remove cluster-to-context mappings",
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runMappingRemove,
+}
+
+var mappingPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove mappings of clusters no longer on the hub",
+	Long: `Remove the mappings of clusters the hub no longer has a ClusterProfile for.
+
+When the hub reports no clusters at all, nothing is removed unless --force is
+given, since an empty hub more likely means the wrong hub or namespace.`,
+	Args: cobra.NoArgs,
+	RunE: runMappingPrune,
+}
+
+var mappingValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that every mapped context exists and is reachable",
+	Args:  cobra.NoArgs,
+	RunE:  runMappingValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(mappingCmd)
+	mappingCmd.AddCommand(mappingListCmd, mappingSetCmd, mappingRemoveCmd, mappingPruneCmd, mappingValidateCmd)
+
+	for _, cmd := range []*cobra.Command{mappingListCmd, mappingSetCmd, mappingRemoveCmd, mappingPruneCmd, mappingValidateCmd} {
+		cmd.Flags().StringP("output", "o", "", "output format: yaml, json, or wide for list")
+	}
+
+	mappingSetCmd.Flags().StringP("filename", "f", "", "mapping file to import, - for stdin")
+	mappingSetCmd.Flags().Bool("replace", false, "with -f, remove the mappings of clusters not in the file")
+	mappingPruneCmd.Flags().Bool("dry-run", false, "print the mappings that would be removed without removing them")
+	mappingPruneCmd.Flags().Bool("force", false, "remove all mappings even when the hub reports no clusters")
+}
+
+func runMappingList(cmd *cobra.Command, args []string) error {
+	mappingManager, err := kubeconfig.NewManager("")
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	return printMappings(cmd.OutOrStdout(), output, mappingManager.Export())
+}
+
+func runMappingSet(cmd *cobra.Command, args []string) error {
+	mappingManager, err := kubeconfig.NewManager("")
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	}
+
+	var mappings []kubeconfig.ClusterMapping
+	if file, _ := cmd.Flags().GetString("filename"); file != "" {
+		data, err := readMappingFile(cmd, file)
+		if err != nil {
+			return err
+		}
+		if mappings, err = kubeconfig.ParseMappings(data); err != nil {
+			return fmt.Errorf("invalid mapping file %s: %w", file, err)
+		}
+
+		replace, _ := cmd.Flags().GetBool("replace")
+		if err := mappingManager.ImportMappings(mappings, replace); err != nil {
+			return fmt.Errorf("failed to import mappings: %w", err)
+		}
+	} else {
+		mapping, _ := mappingManager.GetMapping(args[0])
+		if mapping.Namespace == "" {
+			mapping.Namespace, _ = cmd.Flags().GetString("hub-namespace")
+		}
+		if err := mappingManager.SetMapping(args[0], args[1], mapping.Namespace); err != nil {
+			return fmt.Errorf("failed to save mapping: %w", err)
+		}
+		mapping, _ = mappingManager.GetMapping(args[0])
+		mappings = []kubeconfig.ClusterMapping{mapping}
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output != "" {
+		return printMappings(cmd.OutOrStdout(), output, mappingDocument(mappings))
+	}
+	for _, mapping := range mappings {
+		fmt.Fprintf(cmd.OutOrStdout(), "Mapped %s to context %s\n", mapping.Name, mapping.Context)
+	}
+	return nil
+}
+
+func runMappingRemove(cmd *cobra.Command, args []string) error {
+	mappingManager, err := kubeconfig.NewManager("")
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	}
+
+	removed, err := mappingManager.RemoveMappings(args...)
+	if err != nil {
+		return err
+	}
+	return printRemovedMappings(cmd, removed, "Removed")
+}
+
+func runMappingPrune(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	clusters, err := discoverHubClusters(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	mappingManager, err := kubeconfig.NewManager("")
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	}
+
+	if force, _ := cmd.Flags().GetBool("force"); len(clusters) == 0 && !force {
+		return fmt.Errorf("the hub reports no clusters; check --hub-context and --hub-namespace, or pass --force to remove all mappings")
+	}
+
+	onHub := make(map[string]bool, len(clusters))
+	for _, cluster := range clusters {
+		onHub[cluster.Name] = true
+	}
+	var stale []kubeconfig.ClusterMapping
+	var names []string
+	for _, mapping := range mappingManager.ListMappings() {
+		if !onHub[mapping.Name] {
+			stale = append(stale, mapping)
+			names = append(names, mapping.Name)
+		}
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return printRemovedMappings(cmd, stale, "Would remove")
+	}
+	if len(names) == 0 {
+		return printRemovedMappings(cmd, nil, "Removed")
+	}
+
+	removed, err := mappingManager.RemoveMappings(names...)
+	if err != nil {
+		return err
+	}
+	return printRemovedMappings(cmd, removed, "Removed")
+}
+
+// mappingCheck is the validation result of one mapping
+type mappingCheck struct {
+	Name      string `json:"name" yaml:"name"`
+	Context   string `json:"context" yaml:"context"`
+	Exists    bool   `json:"exists" yaml:"exists"`
+	Reachable bool   `json:"reachable" yaml:"reachable"`
+	Version   string `json:"version,omitempty" yaml:"version,omitempty"`
+	Error     string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// status summarizes the check for the table
+func (c mappingCheck) status() string {
+	switch {
+	case !c.Exists && c.Error != "":
+		return c.Error
+	case !c.Exists:
+		return "context not found in kubeconfig"
+	case !c.Reachable:
+		return "unreachable: " + c.Error
+	default:
+		return "ok"
+	}
+}
+
+func runMappingValidate(cmd *cobra.Command, args []string) error {
+	mappingManager, err := kubeconfig.NewManager("")
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	}
+
+	mappings := mappingManager.ListMappings()
+	checks := make([]mappingCheck, len(mappings))
+	var reachable []discovery.ClusterInfo
+	for i, mapping := range mappings {
+		checks[i] = mappingCheck{Name: mapping.Name, Context: mapping.Context}
+		// Mappings may name their own kubeconfig file
+		factory, err := client.NewFactoryForMapping(mapping, kubeConfigFlags)
+		if err != nil {
+			checks[i].Error = err.Error()
+			continue
+		}
+		rawConfig, err := factory.RawConfig()
+		if err != nil {
+			checks[i].Error = fmt.Sprintf("failed to load kubeconfig: %v", err)
+			continue
+		}
+		if _, ok := rawConfig.Contexts[mapping.Context]; ok {
+			checks[i].Exists = true
+			reachable = append(reachable, discovery.ClusterInfo{Name: mapping.Name, Namespace: mapping.Namespace})
+		}
+	}
+
+	// Contact the clusters with existing contexts in parallel, with the configured
+	// concurrency and timeout. Identities are checked by setup --verify instead.
+	clients := client.NewKubeconfigProvider(mappingManager, kubeConfigFlags)
+	exec := executor.NewExecutor(clients)
+	exec.SetConfig(executorConfig(pluginConfig))
+	results := exec.Run(context.Background(), reachable, func(ctx context.Context, target executor.ClusterTarget) executor.ClusterResult {
+		clientset, err := clients.Clientset(target.Cluster)
+		if err != nil {
+			return executor.ClusterResult{Error: err}
+		}
+		data, err := clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+		if err != nil {
+			return executor.ClusterResult{Error: err}
+		}
+		var info version.Info
+		if err := json.Unmarshal(data, &info); err != nil {
+			return executor.ClusterResult{Error: fmt.Errorf("failed to parse server version: %w", err)}
+		}
+		return executor.ClusterResult{Success: true, Output: info.GitVersion}
+	})
+
+	byName := make(map[string]executor.ClusterResult, len(results.Results))
+	for _, result := range results.Results {
+		byName[result.ClusterName] = result
+	}
+	var failed int
+	for i := range checks {
+		if result, ok := byName[checks[i].Name]; ok {
+			checks[i].Reachable = result.Success
+			checks[i].Version = result.Output
+			if result.Error != nil {
+				checks[i].Error = result.Error.Error()
+			}
+		}
+		if !checks[i].Reachable {
+			failed++
+		}
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if err := printMappingChecks(cmd.OutOrStdout(), output, checks); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cluster mappings are invalid", failed, len(checks))
+	}
+	return nil
+}
+
+// discoverHubClusters lists the clusters of the hub selected by the hub flags
+func discoverHubClusters(ctx context.Context, cmd *cobra.Command) ([]discovery.ClusterInfo, error) {
+	hubContext, err := cmd.Flags().GetString("hub-context")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hub-context flag: %w", err)
+	}
+
+	hubNamespace, err := cmd.Flags().GetString("hub-namespace")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hub-namespace flag: %w", err)
+	}
+
+	hubClientFactory, err := client.NewFactory(hubContext, kubeConfigFlags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hub client factory: %w", err)
+	}
+
+	dynamicClient, err := hubClientFactory.DynamicClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client for hub: %w", err)
+	}
+
+	// Pruning acts on what the hub reports now, never on a cached list
+	clusters, err := newDiscovery(dynamicClient, hubContext, hubNamespace, true).ListClusters(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover clusters: %w", err)
+	}
+	return clusters, nil
+}
+
+// readMappingFile reads a mapping file, or stdin for -
+func readMappingFile(cmd *cobra.Command, file string) ([]byte, error) {
+	if file == "-" {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mappings from stdin: %w", err)
+		}
+		return data, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mapping file: %w", err)
+	}
+	return data, nil
+}
+
+// mappingDocument wraps mappings in a mapping file, so exported mappings can be imported
+func mappingDocument(mappings []kubeconfig.ClusterMapping) kubeconfig.MappingConfig {
+	if mappings == nil {
+		mappings = []kubeconfig.ClusterMapping{}
+	}
+	return kubeconfig.MappingConfig{
+		APIVersion: "kubectl-mc.k8s.io/v1alpha1",
+		Kind:       "ClusterMapping",
+		Clusters:   mappings,
+	}
+}
+
+// printRemovedMappings reports removed mappings, as a mapping file with -o
+func printRemovedMappings(cmd *cobra.Command, removed []kubeconfig.ClusterMapping, verb string) error {
+	output, _ := cmd.Flags().GetString("output")
+	if output != "" {
+		return printMappings(cmd.OutOrStdout(), output, mappingDocument(removed))
+	}
+
+	if len(removed) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No mappings to remove")
+		return nil
+	}
+	for _, mapping := range removed {
+		fmt.Fprintf(cmd.OutOrStdout(), "%s mapping %s (context %s)\n", verb, mapping.Name, mapping.Context)
+	}
+	return nil
+}
+
+// printMappings prints a mapping file as a table, or as YAML or JSON
+func printMappings(w io.Writer, output string, doc kubeconfig.MappingConfig) error {
+	switch output {
+	case "yaml", "json":
+		return printStructured(w, output, doc)
+	case "", "wide":
+	default:
+		return fmt.Errorf("unsupported output format %q (supported: yaml, json, wide)", output)
+	}
+
+	if len(doc.Clusters) == 0 {
+		fmt.Fprintln(w, "No cluster mappings; create them with kubectl mc setup or kubectl mc mapping set")
+		return nil
+	}
+
+	mappings := append([]kubeconfig.ClusterMapping{}, doc.Clusters...)
+	sort.Slice(mappings, func(i, j int) bool {
+		return mappings[i].Name < mappings[j].Name
+	})
+
+	tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	header := []string{"CLUSTER", "CONTEXT", "NAMESPACE"}
+	if output == "wide" {
+		header = append(header, "KUBECONFIG", "USER", "DEFAULT NAMESPACE", "AS", "PROXY", "KUBE-SYSTEM UID")
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, mapping := range mappings {
+		row := []string{mapping.Name, mapping.Context, valueOrNone(mapping.Namespace)}
+		if output == "wide" {
+			as := mapping.As
+			if len(mapping.AsGroups) > 0 {
+				as += " (" + strings.Join(mapping.AsGroups, ",") + ")"
+			}
+			row = append(row,
+				valueOrNone(mapping.Kubeconfig),
+				valueOrNone(mapping.User),
+				valueOrNone(mapping.DefaultNamespace),
+				valueOrNone(as),
+				valueOrNone(mapping.ProxyURL),
+				valueOrNone(mapping.KubeSystemUID),
+			)
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printMappingChecks prints validation results as a table, or as YAML or JSON
+func printMappingChecks(w io.Writer, output string, checks []mappingCheck) error {
+	switch output {
+	case "yaml", "json":
+		return printStructured(w, output, checks)
+	case "":
+	default:
+		return fmt.Errorf("unsupported output format %q (supported: yaml, json)", output)
+	}
+
+	tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tCONTEXT\tVERSION\tSTATUS")
+	for _, check := range checks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", check.Name, check.Context, valueOrNone(check.Version), check.status())
+	}
+	return tw.Flush()
+}
+
+// printStructured writes v as YAML or JSON
+func printStructured(w io.Writer, output string, v interface{}) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "    ")
+		return encoder.Encode(v)
+	}
+
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", output, err)
+	}
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/config"
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+)
+
+// addNamespaceModeFlag adds --namespace-mode to a command that takes -n and -A
+func addNamespaceModeFlag(cmd *cobra.Command) {
+	cmd.Flags().String("namespace-mode", "", "namespace queried without -n: single (the same on every cluster) or per-cluster (each cluster's mapped context namespace) (default from config, single)")
+}
+
+// applyNamespaces sets the namespaces query targets from -A, -n and the
+// namespace mode. -n takes a comma-separated list of namespaces and glob
+// patterns, e.g. -n team-a,team-b or -n 'team-*'.
+func applyNamespaces(cmd *cobra.Command, query *executor.ResourceQuery) error {
+	allNamespaces, _ := cmd.Flags().GetBool("all-namespaces")
+	namespaceChanged := cmd.Flags().Changed("namespace")
+	perCluster := pluginConfig.Execution.NamespaceMode == config.NamespaceModePerCluster
+
+	if cmd.Flags().Changed("namespace-mode") && perCluster && (allNamespaces || namespaceChanged) {
+		return fmt.Errorf("--namespace-mode=per-cluster cannot be combined with -n or -A")
+	}
+
+	switch {
+	case allNamespaces:
+		// -A flag: query all namespaces
+		query.Namespace = ""
+	case namespaceChanged:
+		// -n flag explicitly set: one namespace, or several and patterns
+		value, _ := cmd.Flags().GetString("namespace")
+		namespaces := splitNamespaces(value)
+		switch {
+		case len(namespaces) == 0:
+			return fmt.Errorf("-n %q names no namespace; use -A to query all namespaces", value)
+		case len(namespaces) == 1 && !strings.ContainsAny(namespaces[0], "*?["):
+			query.Namespace = namespaces[0]
+		default:
+			query.Namespaces = namespaces
+		}
+	case perCluster:
+		// Each cluster uses the namespace of its own context
+		query.PerClusterNamespace = true
+	default:
+		// Neither flag set: use kubeconfig default namespace
+		// Pass the namespace from kubeConfigFlags which respects kubeconfig context
+		if kubeConfigFlags.Namespace != nil && *kubeConfigFlags.Namespace != "" {
+			query.Namespace = *kubeConfigFlags.Namespace
+		} else {
+			// No namespace in kubeconfig either, default to "default"
+			query.Namespace = "default"
+		}
+	}
+	return nil
+}
+
+// splitNamespaces splits a comma-separated -n value, dropping empty entries
+func splitNamespaces(value string) []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(value, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	"github.com/suchpuppet/kubectl-mc/pkg/proxy"
+)
+
+const (
+	// defaultAcceptHosts only accepts requests addressed to the loopback interface, like kubectl proxy
+	defaultAcceptHosts = `^localhost$,^127\.0\.0\.1$,^\[?::1\]?$`
+
+	// defaultRejectPaths refuses exec, attach and port forwarding into pods.
+	// kubectl proxy rejects exec and attach; port forwarding is added because
+	// every cluster is reached with the credentials of its mapping.
+	defaultRejectPaths = `^/api/.*/pods/.*/exec,^/api/.*/pods/.*/attach,^/api/.*/pods/.*/portforward`
+
+	// defaultRejectMethods rejects no method, like kubectl proxy
+	defaultRejectMethods = `^$`
+)
+
+var proxyCmd = &cobra.Command{
+	Use:   "proxy",
+	Short: "Serve the Kubernetes API of the whole fleet on a local address",
+	Long: `Run a local HTTP server that accepts Kubernetes API requests and fans them
+out to the selected clusters, so existing tools and dashboards can read the
+fleet through one endpoint.
+
+GET requests are sent to every cluster. Lists are merged, with each object
+annotated with kubectl-mc.k8s.io/cluster, and server-side tables gain a
+Cluster column. A single object is returned when exactly one cluster has it.
+Discovery requests are answered by the first cluster that responds. Clusters
+that fail are reported as Warning headers.
+
+Requests under /clusters/<name>/ are proxied to that cluster alone, including
+watches and writes. Each cluster is reached with the credentials of its
+mapping; credentials sent to the proxy are dropped. Like kubectl proxy, exec
+and attach are rejected, and so is port forwarding; --reject-paths and
+--reject-methods change what is refused, e.g. --reject-methods '^POST$,^PUT$,^PATCH$,^DELETE$'
+for a read-only proxy.
+
+The clusters are discovered once, when the proxy starts.
+
+Examples:
+  # Serve the fleet on 127.0.0.1:8001
+  kubectl mc proxy
+
+  # List pods of every cluster, with a CLUSTER column
+  kubectl --server http://127.0.0.1:8001 get pods -A
+
+  # Read a single cluster
+  curl http://127.0.0.1:8001/clusters/prod-east/api/v1/namespaces/default/pods
+
+  # Serve production clusters on another port
+  kubectl mc proxy --clusters 'prod-*' --port 8080`,
+	Args: cobra.NoArgs,
+	RunE: runProxy,
+}
+
+func init() {
+	rootCmd.AddCommand(proxyCmd)
+
+	proxyCmd.Flags().StringSliceVar(&clustersFlag, "clusters", []string{}, "comma-separated list of cluster names, patterns or @groups, combined with & and ! (e.g. '@prod&!@gpu')")
+	proxyCmd.Flags().StringSliceVar(&excludeFlag, "exclude", []string{}, "comma-separated list of cluster names, patterns or @groups to exclude")
+	proxyCmd.Flags().String("address", "127.0.0.1", "the IP address to serve on")
+	proxyCmd.Flags().IntP("port", "p", 8001, "the port to serve on; 0 picks a random port")
+	proxyCmd.Flags().String("accept-hosts", defaultAcceptHosts, "comma-separated regular expressions of the hosts the proxy accepts requests for")
+	proxyCmd.Flags().String("reject-paths", defaultRejectPaths, "comma-separated regular expressions of the API paths the proxy rejects, matched without /clusters/<name>")
+	proxyCmd.Flags().String("reject-methods", defaultRejectMethods, "comma-separated regular expressions of the HTTP methods the proxy rejects")
+}
+
+func runProxy(cmd *cobra.Command, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	address, _ := cmd.Flags().GetString("address")
+	port, _ := cmd.Flags().GetInt("port")
+	acceptHosts, err := parsePatterns(cmd, "accept-hosts")
+	if err != nil {
+		return err
+	}
+	rejectPaths, err := parsePatterns(cmd, "reject-paths")
+	if err != nil {
+		return err
+	}
+	rejectMethods, err := parsePatterns(cmd, "reject-methods")
+	if err != nil {
+		return err
+	}
+
+	hubContext, err := cmd.Flags().GetString("hub-context")
+	if err != nil {
+		return fmt.Errorf("failed to get hub-context flag: %w", err)
+	}
+
+	hubNamespace, err := cmd.Flags().GetString("hub-namespace")
+	if err != nil {
+		return fmt.Errorf("failed to get hub-namespace flag: %w", err)
+	}
+
+	hubClientFactory, err := client.NewFactory(hubContext, kubeConfigFlags)
+	if err != nil {
+		return fmt.Errorf("failed to create hub client factory: %w", err)
+	}
+
+	dynamicClient, err := hubClientFactory.DynamicClient()
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client for hub: %w", err)
+	}
+
+	refresh, _ := cmd.Flags().GetBool("refresh")
+	clusters, err := newDiscovery(dynamicClient, hubContext, hubNamespace, refresh).ListClusters(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to discover clusters: %w", err)
+	}
+
+	clusters, err = filterClusters(clusters, clustersFlag, excludeFlag)
+	if err != nil {
+		return err
+	}
+	if len(clusters) == 0 {
+		return fmt.Errorf("no clusters to serve")
+	}
+
+	mappingManager, err := kubeconfig.NewManager("")
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	}
+
+	clients := client.NewKubeconfigProvider(mappingManager, kubeConfigFlags)
+	server := proxy.NewServer(newExecutorForClients(clients, mappingManager), clients, clusters)
+	server.SetAcceptHosts(acceptHosts)
+	server.SetRejectPaths(rejectPaths)
+	server.SetRejectMethods(rejectMethods)
+
+	listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	httpServer := &http.Server{
+		Handler:           server,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- httpServer.Serve(listener)
+	}()
+	fmt.Fprintf(os.Stderr, "Serving %d cluster(s) on http://%s\n", len(clusters), listener.Addr())
+
+	select {
+	case err := <-served:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("failed to stop the proxy: %w", err)
+	}
+	return nil
+}
+
+// parsePatterns compiles the comma-separated regular expressions of a flag;
+// an empty value yields no patterns
+func parsePatterns(cmd *cobra.Command, flag string) ([]*regexp.Regexp, error) {
+	value, err := cmd.Flags().GetString(flag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s flag: %w", flag, err)
+	}
+
+	var patterns []*regexp.Regexp
+	for _, expr := range strings.Split(value, ",") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s pattern %q: %w", flag, expr, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
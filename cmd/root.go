@@ -3,9 +3,18 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/config"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	"golang.org/x/term"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/dynamic"
 )
 
 var (
@@ -14,6 +23,14 @@ var (
 
 	// kubeConfigFlags provides Kubernetes configuration flags
 	kubeConfigFlags *genericclioptions.ConfigFlags
+
+	// pluginConfig is the configuration loaded before each command, with the
+	// KUBECTL_MC_* environment variables and flags applied
+	pluginConfig = config.Default()
+
+	// hubProfile is the hub profile selected by --hub, $KUBECTL_MC_HUB or
+	// currentHub; its cluster defaults apply when --clusters and --exclude are unset
+	hubProfile config.HubProfile
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -30,7 +47,8 @@ Example:
   kubectl mc get pods
   kubectl mc get deployments -n default
   kubectl mc describe pod nginx`,
-	SilenceUsage: true,
+	SilenceUsage:      true,
+	PersistentPreRunE: loadConfig,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -40,32 +58,202 @@ func Execute() error {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	// Initialize kubeconfig flags
 	kubeConfigFlags = genericclioptions.NewConfigFlags(true)
 
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kube/kubectl-mc-config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $KUBECTL_MC_CONFIG or $HOME/.kube/kubectl-mc-config.yaml)")
+	rootCmd.PersistentFlags().String("hub", "", "saved hub profile to use (default from $KUBECTL_MC_HUB or kubectl mc use-hub)")
 	rootCmd.PersistentFlags().String("hub-context", "", "kubernetes context for the hub cluster")
 	rootCmd.PersistentFlags().String("hub-namespace", "open-cluster-management", "namespace where ClusterProfile resources are located")
 
+	// Execution flags override the config file and environment
+	rootCmd.PersistentFlags().Int("concurrency", 0, "maximum number of clusters queried at once (default from config, 10)")
+	rootCmd.PersistentFlags().Duration("cluster-timeout", 0, "timeout for the operation on each cluster (default from config, 30s)")
+	rootCmd.PersistentFlags().Bool("refresh", false, "ignore the cached list of discovered clusters and query the hub")
+
 	// Add standard kubectl flags
 	kubeConfigFlags.AddFlags(rootCmd.PersistentFlags())
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
+// configPath returns the configuration file location: --config, then
+// $KUBECTL_MC_CONFIG, then the default
+func configPath() (string, error) {
 	if cfgFile != "" {
-		// Use config file from the flag if specified
-		// TODO: Implement config file loading
-	} else {
-		// Use default config location
-		home, err := os.UserHomeDir()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			return
+		return cfgFile, nil
+	}
+	if path := os.Getenv(config.PathEnvVar); path != "" {
+		return path, nil
+	}
+	return config.DefaultPath()
+}
+
+// loadConfig loads the configuration file and applies, in increasing order of
+// precedence, the KUBECTL_MC_* environment variables and the flags
+func loadConfig(cmd *cobra.Command, args []string) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := validateConfig(cfg); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+	if flags.Changed("hub") {
+		hub, _ := flags.GetString("hub")
+		if err := cfg.UseHub(hub); err != nil {
+			return fmt.Errorf("invalid --hub: %w", err)
+		}
+	}
+	profile, err := applyHubProfile(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	if flags.Changed("concurrency") {
+		concurrency, _ := flags.GetInt("concurrency")
+		cfg.Execution.MaxConcurrency = concurrency
+	}
+	if flags.Changed("cluster-timeout") {
+		timeout, _ := flags.GetDuration("cluster-timeout")
+		cfg.Execution.Timeout = config.Duration(timeout)
+	}
+	if flags.Changed("namespace-mode") {
+		cfg.Execution.NamespaceMode, _ = flags.GetString("namespace-mode")
+	}
+	if flags.Changed("cluster-column") {
+		cfg.Output.ClusterColumn, _ = flags.GetString("cluster-column")
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid flags: %w", err)
+	}
+
+	pluginConfig = cfg
+	hubProfile = profile
+	return nil
+}
+
+// applyHubProfile fills --hub-context and --hub-namespace from the active hub
+// profile unless they were given, and applies its discovery backend unless
+// $KUBECTL_MC_DISCOVERY_API is set. Without a profile, the hub context recorded
+// in the cluster mapping file is used.
+func applyHubProfile(cmd *cobra.Command, cfg *config.Config) (config.HubProfile, error) {
+	flags := cmd.Flags()
+	if flags.Lookup("hub-context") == nil {
+		return config.HubProfile{}, nil
+	}
+
+	_, profile, ok := cfg.ActiveHub()
+	if ok {
+		if !flags.Changed("hub-context") && profile.Context != "" {
+			if err := flags.Set("hub-context", profile.Context); err != nil {
+				return profile, fmt.Errorf("failed to set hub context: %w", err)
+			}
+		}
+		if !flags.Changed("hub-namespace") && profile.Namespace != "" {
+			if err := flags.Set("hub-namespace", profile.Namespace); err != nil {
+				return profile, fmt.Errorf("failed to set hub namespace: %w", err)
+			}
+		}
+		if _, set := os.LookupEnv(config.EnvVars()["discovery.api"]); !set && profile.DiscoveryAPI != "" {
+			cfg.Discovery.API = profile.DiscoveryAPI
 		}
-		cfgFile = home + "/.kube/kubectl-mc-config.yaml"
+		return profile, nil
+	}
+
+	if hubContext, _ := flags.GetString("hub-context"); hubContext == "" {
+		if mappingManager, err := kubeconfig.NewManager(""); err == nil && mappingManager.GetHubContext() != "" {
+			if err := flags.Set("hub-context", mappingManager.GetHubContext()); err != nil {
+				return profile, fmt.Errorf("failed to set hub context: %w", err)
+			}
+		}
+	}
+	return profile, nil
+}
+
+// newExecutor creates an executor reaching clusters through their mapped
+// contexts, using the configured execution settings and identity checks
+func newExecutor(mappingManager *kubeconfig.Manager) *executor.Executor {
+	return newExecutorForClients(client.NewKubeconfigProvider(mappingManager, kubeConfigFlags), mappingManager)
+}
+
+// newExecutorForClients creates an executor reaching clusters through clients,
+// configured like newExecutor
+func newExecutorForClients(clients client.ClusterClientProvider, mappingManager *kubeconfig.Manager) *executor.Executor {
+	exec := executor.NewExecutor(clients)
+	exec.SetConfig(executorConfig(pluginConfig))
+	if pluginConfig.Execution.VerifyIdentity {
+		exec.SetVerifier(client.NewIdentityVerifier(clients, mappingManager))
+	}
+	return exec
+}
+
+// executorConfig returns the execution settings in the form the executor takes
+func executorConfig(cfg *config.Config) executor.ExecutorConfig {
+	return executor.NewConfig(cfg.Execution.MaxConcurrency, time.Duration(cfg.Execution.Timeout), cfg.Execution.ContinueOnError)
+}
+
+// clusterGroups returns the configured cluster groups in the form the selector takes
+func clusterGroups(cfg *config.Config) map[string]discovery.ClusterGroup {
+	groups := make(map[string]discovery.ClusterGroup, len(cfg.ClusterGroups))
+	for name, group := range cfg.ClusterGroups {
+		groups[name] = discovery.ClusterGroup{Clusters: group.Clusters, Selector: group.Selector}
+	}
+	return groups
+}
+
+// validateConfig checks the settings the config package leaves to the
+// packages that evaluate them: context templates, cluster groups and the
+// cluster selections of hub profiles
+func validateConfig(cfg *config.Config) error {
+	if _, err := kubeconfig.NewMatcher(cfg.Setup.ContextTemplates); err != nil {
+		return fmt.Errorf("invalid setup.contextTemplates: %w", err)
+	}
+
+	groups := clusterGroups(cfg)
+	if err := discovery.ValidateGroups(groups); err != nil {
+		return fmt.Errorf("invalid clusterGroups: %w", err)
+	}
+
+	selector := discovery.NewSelector(groups)
+	for name, hub := range cfg.Hubs {
+		if _, err := selector.Filter(nil, hub.Clusters, hub.Exclude); err != nil {
+			return fmt.Errorf("invalid clusters for hub %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// newDiscovery creates the discovery client for a hub, caching discovered
+// clusters for the configured TTL. refresh (--refresh) bypasses the cache.
+func newDiscovery(dynamicClient dynamic.Interface, hubContext, hubNamespace string, refresh bool) discovery.Discovery {
+	clusterProfiles := discovery.NewClusterProfileDiscovery(dynamicClient, hubNamespace)
+
+	ttl := time.Duration(pluginConfig.Discovery.CacheTTL)
+	home, err := os.UserHomeDir()
+	if ttl == 0 || err != nil {
+		return clusterProfiles
+	}
+
+	cacheDir := filepath.Join(home, ".kube", "cache", "kubectl-mc", "discovery")
+	cached := discovery.NewCachedDiscovery(clusterProfiles, discovery.CachePath(cacheDir, hubDisplayName(hubContext), hubNamespace), ttl)
+	cached.SetRefresh(refresh)
+	return cached
+}
+
+// colorizeStderr reports whether warnings on stderr may be highlighted
+func colorizeStderr() bool {
+	if !pluginConfig.Output.Colorize || os.Getenv("NO_COLOR") != "" {
+		return false
 	}
+	return term.IsTerminal(int(os.Stderr.Fd()))
 }
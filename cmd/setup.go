@@ -3,14 +3,20 @@ package cmd
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/suchpuppet/kubectl-mc/pkg/client"
 	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
 	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	"golang.org/x/term"
 )
 
 var setupCmd = &cobra.Command{
@@ -21,13 +27,46 @@ var setupCmd = &cobra.Command{
 This command discovers clusters from the hub and prompts you to map each cluster
 to a kubeconfig context name.
 
-Example:
-  kubectl mc setup`,
+With --auto, clusters are matched to kubeconfig contexts without prompting, by
+trying in order:
+  1. server          the API server URL published in the ClusterProfile's accessProviders
+  2. ca-fingerprint  the SHA-256 fingerprint of the published CA certificate
+  3. context-name    a context named like the cluster
+  4. cluster-name    a context whose kubeconfig cluster is named like the cluster
+  5. template        a context named by --context-template or setup.contextTemplates
+When a step finds several contexts, the following steps narrow them down. The
+plan is printed before anything is saved; clusters without a single matching
+context are reported and left unchanged.
+
+Each new mapping is checked against the cluster's identity: the About API
+ClusterProperty cluster.clusterset.k8s.io must name the cluster, and the UID of
+the kube-system namespace is recorded. Commands refuse clusters whose context
+reaches another cluster. --verify re-checks all mappings.
+
+Examples:
+  # Map clusters interactively
+  kubectl mc setup
+
+  # Preview the automatic mapping
+  kubectl mc setup --auto --dry-run
+
+  # Check that every mapped context still reaches its cluster
+  kubectl mc setup --verify
+
+  # Map clusters in CI, trying EKS context names when nothing else matches
+  kubectl mc setup --auto --yes --context-template 'arn:aws:eks:*:*:cluster/{{.Name}}'`,
+	Args: cobra.NoArgs,
 	RunE: runSetup,
 }
 
 func init() {
 	rootCmd.AddCommand(setupCmd)
+
+	setupCmd.Flags().Bool("auto", false, "match clusters to kubeconfig contexts without prompting")
+	setupCmd.Flags().Bool("dry-run", false, "with --auto, print the plan without saving it")
+	setupCmd.Flags().BoolP("yes", "y", false, "with --auto, save the plan without asking for confirmation")
+	setupCmd.Flags().Bool("verify", false, "check that every mapped context reaches its cluster, recording kube-system UIDs not yet recorded")
+	setupCmd.Flags().StringArray("context-template", nil, "with --auto, template rendering a context name from a cluster, e.g. 'kind-{{.Name}}' (default from setup.contextTemplates)")
 }
 
 func runSetup(cmd *cobra.Command, args []string) error {
@@ -55,8 +94,8 @@ func runSetup(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create dynamic client for hub: %w", err)
 	}
 
-	// Create discovery client
-	discoveryClient := discovery.NewClusterProfileDiscovery(dynamicClient, hubNamespace)
+	// Create discovery client; setup always asks the hub, refreshing the cache
+	discoveryClient := newDiscovery(dynamicClient, hubContext, hubNamespace, true)
 
 	// Discover clusters
 	clusters, err := discoveryClient.ListClusters(ctx)
@@ -64,6 +103,16 @@ func runSetup(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to discover clusters: %w", err)
 	}
 
+	// Load existing mappings
+	mappingManager, err := kubeconfig.NewManager("")
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	}
+
+	if verify, _ := cmd.Flags().GetBool("verify"); verify {
+		return runVerifySetup(ctx, cmd, clusters, mappingManager)
+	}
+
 	if len(clusters) == 0 {
 		fmt.Println("No clusters discovered from hub")
 		return nil
@@ -71,15 +120,16 @@ func runSetup(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("Discovered %d cluster(s)\n\n", len(clusters))
 
-	// Load existing mappings
-	mappingManager, err := kubeconfig.NewManager("")
-	if err != nil {
-		return fmt.Errorf("failed to load kubeconfig mappings: %w", err)
+	verifier := client.NewIdentityVerifier(client.NewKubeconfigProvider(mappingManager, kubeConfigFlags), mappingManager)
+
+	if auto, _ := cmd.Flags().GetBool("auto"); auto {
+		return runAutoSetup(ctx, cmd, clusters, mappingManager, verifier)
 	}
 
 	// Interactive setup
 	reader := bufio.NewReader(os.Stdin)
 
+	var refused []string
 	for _, cluster := range clusters {
 		// Check if mapping already exists
 		existingContext, err := mappingManager.GetContext(cluster.Name)
@@ -112,12 +162,26 @@ func runSetup(cmd *cobra.Command, args []string) error {
 		}
 
 		// Save mapping
-		if err := mappingManager.SetMapping(cluster.Name, contextName, cluster.Namespace); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to save mapping for %s: %v\n", cluster.Name, err)
+		warning, err := saveMapping(ctx, verifier, mappingManager, cluster, contextName)
+		var mismatch *client.IdentityMismatchError
+		switch {
+		case errors.As(err, &mismatch):
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			refused = append(refused, cluster.Name)
+			continue
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 			continue
 		}
 
 		fmt.Printf("✓ Mapped '%s' to context '%s'\n", cluster.Name, contextName)
+		if warning != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", warning)
+		}
+	}
+
+	if len(refused) > 0 {
+		return fmt.Errorf("mappings not saved for %s: their contexts reach other clusters", strings.Join(refused, ", "))
 	}
 
 	fmt.Println("\nSetup complete!")
@@ -126,3 +190,249 @@ func runSetup(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// setupAction is a planned change to the mapping of one cluster
+type setupAction struct {
+	match    kubeconfig.ContextMatch
+	existing string
+}
+
+// changed reports whether the action creates or updates a mapping
+func (a setupAction) changed() bool {
+	return a.match.Matched() && a.match.Context != a.existing
+}
+
+// description summarizes the action for the plan
+func (a setupAction) description() string {
+	switch {
+	case len(a.match.Candidates) > 1:
+		return fmt.Sprintf("skip: ambiguous, matches %s", strings.Join(a.match.Candidates, ", "))
+	case !a.match.Matched() && a.existing != "":
+		return fmt.Sprintf("skip: no matching context, keeping %s", a.existing)
+	case !a.match.Matched():
+		return "skip: no matching context"
+	case a.existing == "":
+		return "create"
+	case a.existing == a.match.Context:
+		return "unchanged"
+	default:
+		return fmt.Sprintf("update, was %s", a.existing)
+	}
+}
+
+// runAutoSetup matches clusters to kubeconfig contexts, prints the plan and
+// saves it once confirmed
+func runAutoSetup(ctx context.Context, cmd *cobra.Command, clusters []discovery.ClusterInfo, mappingManager *kubeconfig.Manager, verifier *client.IdentityVerifier) error {
+	templates := pluginConfig.Setup.ContextTemplates
+	if cmd.Flags().Changed("context-template") {
+		templates, _ = cmd.Flags().GetStringArray("context-template")
+	}
+	matcher, err := kubeconfig.NewMatcher(templates)
+	if err != nil {
+		return err
+	}
+
+	rawConfig, err := kubeConfigFlags.ToRawKubeConfigLoader().RawConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Name < clusters[j].Name
+	})
+	matches, err := matcher.Match(clusters, kubeconfig.KubeContexts(rawConfig))
+	if err != nil {
+		return err
+	}
+
+	actions := make([]setupAction, 0, len(matches))
+	var changes int
+	var unmatched []string
+	for _, match := range matches {
+		action := setupAction{match: match}
+		action.existing, _ = mappingManager.GetContext(match.Cluster.Name)
+		if action.changed() {
+			changes++
+		}
+		if !match.Matched() {
+			unmatched = append(unmatched, match.Cluster.Name)
+		}
+		actions = append(actions, action)
+	}
+
+	out := cmd.OutOrStdout()
+	if err := printSetupPlan(out, actions); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d mapping(s) to create or update, %d cluster(s) unmatched\n", changes, len(unmatched))
+	if len(unmatched) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: no single context matches %s; map them with kubectl mc setup or --context-template\n", strings.Join(unmatched, ", "))
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		fmt.Fprintln(out, "Dry run, no mappings saved")
+		return nil
+	}
+	if changes == 0 {
+		return nil
+	}
+
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("refusing to save mappings without confirmation; pass --yes or --dry-run")
+		}
+		fmt.Fprintf(out, "Save %d mapping(s)? [y/N]: ", changes)
+		response, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		response = strings.TrimSpace(strings.ToLower(response))
+		if response != "y" && response != "yes" {
+			fmt.Fprintln(out, "No mappings saved")
+			return nil
+		}
+	}
+
+	var saved int
+	var refused []string
+	for _, action := range actions {
+		if !action.changed() {
+			continue
+		}
+		warning, err := saveMapping(ctx, verifier, mappingManager, action.match.Cluster, action.match.Context)
+		var mismatch *client.IdentityMismatchError
+		switch {
+		case errors.As(err, &mismatch):
+			fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+			refused = append(refused, action.match.Cluster.Name)
+			continue
+		case err != nil:
+			return err
+		}
+		saved++
+		if warning != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", warning)
+		}
+	}
+	fmt.Fprintf(out, "Saved %d mapping(s) to ~/.kube/kubectl-mc-clusters.yaml\n", saved)
+
+	if len(refused) > 0 {
+		return fmt.Errorf("mappings not saved for %s: their contexts reach other clusters", strings.Join(refused, ", "))
+	}
+	return nil
+}
+
+// printSetupPlan prints a row per cluster with its matched context and the planned action
+func printSetupPlan(w io.Writer, actions []setupAction) error {
+	tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tCONTEXT\tMATCHED BY\tACTION")
+	for _, action := range actions {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", action.match.Cluster.Name, valueOrNone(action.match.Context),
+			valueOrNone(strings.Join(action.match.MatchedBy, ",")), action.description())
+	}
+	return tw.Flush()
+}
+
+// recordIdentity verifies the cluster a mapping reaches and records its
+// kube-system UID if none is recorded yet. It returns the verification status.
+func recordIdentity(ctx context.Context, verifier *client.IdentityVerifier, mappingManager *kubeconfig.Manager, cluster discovery.ClusterInfo) (string, error) {
+	if timeout := time.Duration(pluginConfig.Execution.Timeout); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	identity, verified, err := verifier.Verify(ctx, cluster)
+	if err != nil {
+		return "", err
+	}
+
+	mapping, _ := mappingManager.GetMapping(cluster.Name)
+	if identity.KubeSystemUID != "" && mapping.KubeSystemUID == "" {
+		if err := mappingManager.SetKubeSystemUID(cluster.Name, identity.KubeSystemUID); err != nil {
+			return "", fmt.Errorf("failed to record identity of %s: %w", cluster.Name, err)
+		}
+		if !verified {
+			return "recorded", nil
+		}
+	}
+	if verified {
+		return "verified", nil
+	}
+	return "unverified", nil
+}
+
+// saveMapping maps a cluster to a context and verifies the cluster the context
+// reaches. A mapping whose context reaches another cluster is rolled back and
+// its *client.IdentityMismatchError returned; a check that cannot complete
+// leaves the mapping saved and is returned as warning.
+func saveMapping(ctx context.Context, verifier *client.IdentityVerifier, mappingManager *kubeconfig.Manager, cluster discovery.ClusterInfo, contextName string) (warning error, err error) {
+	previous, existed := mappingManager.GetMapping(cluster.Name)
+	if err := mappingManager.SetMapping(cluster.Name, contextName, cluster.Namespace); err != nil {
+		return nil, fmt.Errorf("failed to save mapping for %s: %w", cluster.Name, err)
+	}
+
+	_, err = recordIdentity(ctx, verifier, mappingManager, cluster)
+	var mismatch *client.IdentityMismatchError
+	if !errors.As(err, &mismatch) {
+		return err, nil
+	}
+
+	var rollbackErr error
+	if existed {
+		rollbackErr = mappingManager.ImportMappings([]kubeconfig.ClusterMapping{previous}, false)
+	} else {
+		_, rollbackErr = mappingManager.RemoveMappings(cluster.Name)
+	}
+	if rollbackErr != nil {
+		return nil, fmt.Errorf("%w; failed to roll back the mapping: %v", err, rollbackErr)
+	}
+	return nil, err
+}
+
+// runVerifySetup checks every mapping, printing a row per mapped cluster, and
+// fails when any context reaches the wrong cluster or cannot be checked
+func runVerifySetup(ctx context.Context, cmd *cobra.Command, clusters []discovery.ClusterInfo, mappingManager *kubeconfig.Manager) error {
+	mappings := mappingManager.ListMappings()
+	if len(mappings) == 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "No cluster mappings to verify; create them with kubectl mc setup")
+		return nil
+	}
+
+	discovered := make(map[string]discovery.ClusterInfo, len(clusters))
+	for _, cluster := range clusters {
+		discovered[cluster.Name] = cluster
+	}
+
+	verifier := client.NewIdentityVerifier(client.NewKubeconfigProvider(mappingManager, kubeConfigFlags), mappingManager)
+
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 6, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "CLUSTER\tCONTEXT\tSTATUS")
+	var failed int
+	for _, mapping := range mappings {
+		cluster, ok := discovered[mapping.Name]
+		if !ok {
+			cluster = discovery.ClusterInfo{Name: mapping.Name, Namespace: mapping.Namespace}
+		}
+
+		status, err := recordIdentity(ctx, verifier, mappingManager, cluster)
+		if err != nil {
+			failed++
+			var mismatch *client.IdentityMismatchError
+			if errors.As(err, &mismatch) {
+				status = "MISMATCH: " + mismatch.Reason
+			} else {
+				status = "error: " + err.Error()
+			}
+		}
+		if !ok {
+			status += " (not discovered)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", mapping.Name, mapping.Context, status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d cluster mappings failed verification", failed, len(mappings))
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/suchpuppet/kubectl-mc/pkg/config"
+)
+
+var useHubCmd = &cobra.Command{
+	Use:   "use-hub [NAME]",
+	Short: "Switch between saved hub profiles",
+	Long: `Switch between saved hub profiles, like kubectl config use-context.
+
+A hub profile saves the hub's kubeconfig context, the namespace of its
+ClusterProfiles, its discovery backend and default --clusters and --exclude
+selections. Give --hub-context, --hub-namespace, --discovery-api, --clusters or
+--exclude to create or update the named profile.
+
+Commands use the current profile unless --hub, --hub-context or --hub-namespace
+are given. $KUBECTL_MC_HUB overrides the current profile.
+
+Examples:
+  # List saved hub profiles
+  kubectl mc use-hub
+
+  # Save a profile for the production hub and switch to it
+  kubectl mc use-hub prod --hub-context prod-hub --hub-namespace fleet --exclude '*-canary'
+
+  # Switch to a saved profile
+  kubectl mc use-hub staging
+
+  # Query another hub for a single command
+  kubectl mc get pods --hub staging
+
+  # Stop using a saved profile
+  kubectl mc use-hub --unset`,
+	Args: cobra.MaximumNArgs(1),
+	// Profiles must be editable even when the current one is broken, so this
+	// command skips the configuration loading every other command does
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	RunE:              runUseHub,
+}
+
+func init() {
+	rootCmd.AddCommand(useHubCmd)
+
+	useHubCmd.Flags().String("discovery-api", "", "discovery backend for the hub (default from discovery.api)")
+	useHubCmd.Flags().StringSlice("clusters", nil, "default cluster names, patterns or @groups to target on this hub")
+	useHubCmd.Flags().StringSlice("exclude", nil, "default cluster names, patterns or @groups to exclude on this hub")
+	useHubCmd.Flags().Bool("unset", false, "stop using a saved hub profile")
+}
+
+func runUseHub(cmd *cobra.Command, args []string) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Read(path)
+	if err != nil {
+		return err
+	}
+
+	flags := cmd.Flags()
+	if unset, _ := flags.GetBool("unset"); unset {
+		if len(args) > 0 {
+			return fmt.Errorf("--unset does not take a hub name")
+		}
+		if err := switchHub(cfg, path, ""); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Unset the current hub in %s\n", path)
+		return nil
+	}
+
+	if len(args) == 0 {
+		return printHubProfiles(cmd.OutOrStdout(), cfg)
+	}
+	name := args[0]
+
+	edited := false
+	for _, flag := range []string{"hub-context", "hub-namespace", "discovery-api", "clusters", "exclude"} {
+		edited = edited || flags.Changed(flag)
+	}
+
+	profile, exists := cfg.Hubs[name]
+	if !exists && !edited {
+		return fmt.Errorf("no hub profile named %q; create it with kubectl mc use-hub %s --hub-context CONTEXT", name, name)
+	}
+	if edited {
+		if flags.Changed("hub-context") {
+			profile.Context, _ = flags.GetString("hub-context")
+		}
+		if flags.Changed("hub-namespace") {
+			profile.Namespace, _ = flags.GetString("hub-namespace")
+		}
+		if flags.Changed("discovery-api") {
+			profile.DiscoveryAPI, _ = flags.GetString("discovery-api")
+		}
+		if flags.Changed("clusters") {
+			profile.Clusters, _ = flags.GetStringSlice("clusters")
+		}
+		if flags.Changed("exclude") {
+			profile.Exclude, _ = flags.GetStringSlice("exclude")
+		}
+		if err := cfg.SetHub(name, profile); err != nil {
+			return fmt.Errorf("invalid hub profile: %w", err)
+		}
+		if err := validateConfig(cfg); err != nil {
+			return fmt.Errorf("invalid hub profile: %w", err)
+		}
+	}
+
+	if err := switchHub(cfg, path, name); err != nil {
+		return err
+	}
+
+	if edited && exists {
+		fmt.Fprintf(cmd.OutOrStdout(), "Updated hub %q and switched to it\n", name)
+	} else if edited {
+		fmt.Fprintf(cmd.OutOrStdout(), "Saved hub %q and switched to it\n", name)
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Switched to hub %q\n", name)
+	}
+	return nil
+}
+
+// switchHub makes a profile current. The current profile lives only in the
+// config file; the mapping file's hubContext is left alone as a legacy fallback.
+func switchHub(cfg *config.Config, path, name string) error {
+	if err := cfg.UseHub(name); err != nil {
+		return err
+	}
+	return cfg.Save(path, "hubs", "currentHub")
+}
+
+// printHubProfiles prints a row per saved hub profile, marking the current one
+func printHubProfiles(w io.Writer, cfg *config.Config) error {
+	if len(cfg.Hubs) == 0 {
+		fmt.Fprintln(w, "No hub profiles saved; create one with kubectl mc use-hub NAME --hub-context CONTEXT")
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Hubs))
+	for name := range cfg.Hubs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 6, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, "CURRENT\tNAME\tCONTEXT\tNAMESPACE\tCLUSTERS\tEXCLUDE")
+	for _, name := range names {
+		hub := cfg.Hubs[name]
+		current := ""
+		if name == cfg.CurrentHub {
+			current = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", current, name, valueOrNone(hub.Context), valueOrNone(hub.Namespace),
+			valueOrNone(strings.Join(hub.Clusters, ",")), valueOrNone(strings.Join(hub.Exclude, ",")))
+	}
+	return tw.Flush()
+}
@@ -7,7 +7,5 @@ import (
 )
 
 func main() {
-	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
-	}
+	os.Exit(cmd.ExitCode(cmd.Execute()))
 }
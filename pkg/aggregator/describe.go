@@ -21,8 +21,8 @@ func NewDescribeAggregator(writer io.Writer) *DescribeAggregator {
 	}
 }
 
-// AggregateDescribeResults aggregates and formats describe results across clusters
-// Returns error only if ALL clusters failed. If at least one cluster returns results, it's considered success.
+// AggregateDescribeResults aggregates and formats describe results across clusters.
+// Failed clusters are skipped; the caller reports them with PrintClusterErrors.
 func (a *DescribeAggregator) AggregateDescribeResults(results *executor.AggregatedResults, resourceType string) error {
 	// Sort results by cluster name for consistent output
 	sortedResults := make([]executor.ClusterResult, len(results.Results))
@@ -36,7 +36,7 @@ func (a *DescribeAggregator) AggregateDescribeResults(results *executor.Aggregat
 
 	// Print results from each cluster
 	for _, result := range sortedResults {
-		// Skip failed results silently - we only care if we get at least one success
+		// Failed clusters are reported by the caller
 		if !result.Success {
 			continue
 		}
@@ -61,11 +61,10 @@ func (a *DescribeAggregator) AggregateDescribeResults(results *executor.Aggregat
 		hasOutput = true
 	}
 
-	// Only return error if NO cluster had any results AND there were failures
-	if !hasOutput {
-		if results.Summary.Total > 0 && results.Summary.Failed == results.Summary.Total {
-			return fmt.Errorf("failed to describe resource in all %d clusters", results.Summary.Total)
-		}
+	// Failed clusters are reported by the caller, so only say nothing was found
+	// when at least one cluster answered
+	allFailed := results.Summary.Total > 0 && results.Summary.Failed == results.Summary.Total
+	if !hasOutput && !allFailed {
 		fmt.Fprintln(a.writer, "No resources found")
 	}
 
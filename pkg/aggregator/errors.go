@@ -0,0 +1,108 @@
+package aggregator
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+)
+
+// errorsField is the List field holding the cluster errors in json, yaml,
+// jsonpath and go-template output (e.g. {.errors[*].cluster})
+const errorsField = "errors"
+
+// ANSI escapes highlighting the failure block on terminals
+const (
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// ClusterError is the machine-readable record of a cluster that failed
+type ClusterError struct {
+	Cluster string `json:"cluster"`
+	Error   string `json:"error"`
+
+	// Partial is set when the cluster returned results but part of the
+	// operation failed, e.g. one of several resource types
+	Partial bool `json:"partial,omitempty"`
+}
+
+// ClusterErrors returns the errors of all failed and partially failed clusters, sorted by cluster
+func ClusterErrors(results *executor.AggregatedResults) []ClusterError {
+	var errs []ClusterError
+	for _, result := range results.Results {
+		switch {
+		case !result.Success && result.Error != nil:
+			errs = append(errs, ClusterError{Cluster: result.ClusterName, Error: result.Error.Error()})
+		case !result.Success:
+			errs = append(errs, ClusterError{Cluster: result.ClusterName, Error: "unknown error"})
+		case result.Error != nil:
+			errs = append(errs, ClusterError{Cluster: result.ClusterName, Error: result.Error.Error(), Partial: true})
+		}
+	}
+
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Cluster < errs[j].Cluster
+	})
+	return errs
+}
+
+// errorRecordPrefix starts the line recording each failed cluster in the
+// failure block, e.g. "error/east: connection refused"
+const errorRecordPrefix = "error/"
+
+// PrintClusterErrors writes a block listing the clusters that failed and why,
+// so an incomplete answer is never mistaken for a complete one. Nothing is
+// written when every cluster succeeded. colorize highlights the heading.
+//
+// After the heading, each cluster gets a stable, machine-readable line:
+// "error/<cluster>: <reason>", or "error/<cluster> (partial): <reason>" when
+// the cluster returned results. It is the error record of the formats without
+// an errors field: table, wide, name and custom-columns.
+func PrintClusterErrors(w io.Writer, results *executor.AggregatedResults, colorize bool) {
+	errs := ClusterErrors(results)
+	if len(errs) == 0 {
+		return
+	}
+
+	var heading, color string
+	summary := results.Summary
+	switch {
+	case summary.Total > 0 && summary.Failed == summary.Total:
+		heading, color = fmt.Sprintf("Error: all %d clusters failed:", summary.Total), colorRed
+	case summary.Failed > 0:
+		heading, color = fmt.Sprintf("Warning: %d of %d clusters failed, results are incomplete:", summary.Failed, summary.Total), colorYellow
+	default:
+		heading, color = fmt.Sprintf("Warning: %d of %d clusters returned partial results:", len(errs), summary.Total), colorYellow
+	}
+	if colorize {
+		heading = color + heading + colorReset
+	}
+	fmt.Fprintf(w, "\n%s\n", heading)
+
+	for _, e := range errs {
+		if e.Partial {
+			fmt.Fprintf(w, "%s%s (partial): %s\n", errorRecordPrefix, e.Cluster, e.Error)
+		} else {
+			fmt.Fprintf(w, "%s%s: %s\n", errorRecordPrefix, e.Cluster, e.Error)
+		}
+	}
+}
+
+// errorsSection returns the cluster errors as a List field value
+func errorsSection(errs []ClusterError) []interface{} {
+	section := make([]interface{}, 0, len(errs))
+	for _, e := range errs {
+		entry := map[string]interface{}{
+			"cluster": e.Cluster,
+			"error":   e.Error,
+		}
+		if e.Partial {
+			entry["partial"] = true
+		}
+		section = append(section, entry)
+	}
+	return section
+}
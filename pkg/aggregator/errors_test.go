@@ -0,0 +1,258 @@
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// errorTestResults returns results where one cluster failed and one only partly succeeded
+func errorTestResults() *executor.AggregatedResults {
+	results := executor.NewAggregatedResults(nil)
+	results.Summary.Total = 4
+	results.AddResult(executor.ClusterResult{ClusterName: "east", Success: false, Error: errors.New("connection refused")})
+	results.AddResult(executor.ClusterResult{ClusterName: "central", Success: true})
+	results.AddResult(executor.ClusterResult{ClusterName: "west", Success: true, Error: errors.New("widgets: forbidden")})
+	results.AddResult(executor.ClusterResult{ClusterName: "apac", Success: false})
+	return results
+}
+
+func TestClusterErrors(t *testing.T) {
+	errs := ClusterErrors(errorTestResults())
+
+	expected := []ClusterError{
+		{Cluster: "apac", Error: "unknown error"},
+		{Cluster: "east", Error: "connection refused"},
+		{Cluster: "west", Error: "widgets: forbidden", Partial: true},
+	}
+	if len(errs) != len(expected) {
+		t.Fatalf("expected %d errors, got %v", len(expected), errs)
+	}
+	for i := range expected {
+		if errs[i] != expected[i] {
+			t.Errorf("error %d: expected %+v, got %+v", i, expected[i], errs[i])
+		}
+	}
+}
+
+func TestPrintClusterErrors(t *testing.T) {
+	allFailed := executor.NewAggregatedResults(nil)
+	allFailed.Summary.Total = 1
+	allFailed.AddResult(executor.ClusterResult{ClusterName: "east", Error: errors.New("timeout")})
+
+	partialOnly := executor.NewAggregatedResults(nil)
+	partialOnly.Summary.Total = 2
+	partialOnly.AddResult(executor.ClusterResult{ClusterName: "east", Success: true})
+	partialOnly.AddResult(executor.ClusterResult{ClusterName: "west", Success: true, Error: errors.New("widgets: forbidden")})
+
+	succeeded := executor.NewAggregatedResults(nil)
+	succeeded.Summary.Total = 1
+	succeeded.AddResult(executor.ClusterResult{ClusterName: "east", Success: true})
+
+	tests := []struct {
+		name     string
+		results  *executor.AggregatedResults
+		expected []string
+	}{
+		{
+			name:    "some clusters failed",
+			results: errorTestResults(),
+			expected: []string{
+				"Warning: 2 of 4 clusters failed, results are incomplete:",
+				"error/apac: unknown error",
+				"error/east: connection refused",
+				"error/west (partial): widgets: forbidden",
+			},
+		},
+		{
+			name:     "all clusters failed",
+			results:  allFailed,
+			expected: []string{"Error: all 1 clusters failed:", "error/east: timeout"},
+		},
+		{
+			name:     "partial results only",
+			results:  partialOnly,
+			expected: []string{"Warning: 1 of 2 clusters returned partial results:", "error/west (partial): widgets: forbidden"},
+		},
+		{
+			name:    "no failures",
+			results: succeeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			PrintClusterErrors(buf, tt.results, false)
+
+			got := strings.TrimSpace(buf.String())
+			if want := strings.Join(tt.expected, "\n"); got != strings.TrimSpace(want) {
+				t.Errorf("expected:\n%s\ngot:\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestObjectPrinter_ErrorsSection(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer, err := NewObjectPrinter(buf, OutputFormat{Name: OutputJSON})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := printer.PrintGetResults(errorTestResults(), "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var list struct {
+		Errors []ClusterError `json:"errors"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	if len(list.Errors) != 3 || list.Errors[1].Cluster != "east" || list.Errors[1].Error != "connection refused" || !list.Errors[2].Partial {
+		t.Errorf("unexpected errors section: %+v", list.Errors)
+	}
+
+	// Templates can read the section too
+	buf.Reset()
+	printer, err = NewObjectPrinter(buf, OutputFormat{Name: OutputJSONPath, Template: "{.errors[*].cluster}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := printer.PrintGetResults(errorTestResults(), "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := buf.String(); got != "apac east west" {
+		t.Errorf("expected failed clusters from jsonpath, got %q", got)
+	}
+
+	// The section is left out when every cluster succeeded
+	buf.Reset()
+	printer, err = NewObjectPrinter(buf, OutputFormat{Name: OutputJSON})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	succeeded := &executor.AggregatedResults{Results: []executor.ClusterResult{{ClusterName: "east", Success: true}}}
+	if err := printer.PrintGetResults(succeeded, "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), `"errors"`) {
+		t.Errorf("expected no errors section, got %s", buf.String())
+	}
+}
+
+func TestPrintClusterErrors_Colorize(t *testing.T) {
+	buf := &bytes.Buffer{}
+	PrintClusterErrors(buf, errorTestResults(), true)
+
+	if !strings.Contains(buf.String(), colorYellow+"Warning: 2 of 4 clusters failed, results are incomplete:"+colorReset) {
+		t.Errorf("expected a highlighted heading, got %q", buf.String())
+	}
+}
+
+func TestClusterErrorRecords_PerFormat(t *testing.T) {
+	pod := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "nginx", "namespace": "default"},
+	}}
+	newResults := func() *executor.AggregatedResults {
+		results := errorTestResults()
+		results.Results[1].Items = []unstructured.Unstructured{pod}
+		return results
+	}
+
+	printObjects := func(format OutputFormat) func(io.Writer, *executor.AggregatedResults) error {
+		return func(w io.Writer, results *executor.AggregatedResults) error {
+			printer, err := NewObjectPrinter(w, format)
+			if err != nil {
+				return err
+			}
+			return printer.PrintGetResults(results, "pods")
+		}
+	}
+	printTable := func(wide bool) func(io.Writer, *executor.AggregatedResults) error {
+		return func(w io.Writer, results *executor.AggregatedResults) error {
+			agg := NewTableAggregator(w)
+			if err := agg.SetOptions(TableOptions{Wide: wide}); err != nil {
+				return err
+			}
+			return agg.AggregateGetResults(results, "pods")
+		}
+	}
+
+	tests := []struct {
+		name  string
+		print func(io.Writer, *executor.AggregatedResults) error
+
+		// inStream is set for formats that also record the errors on stdout
+		inStream bool
+	}{
+		{name: "table", print: printTable(false)},
+		{name: "wide", print: printTable(true)},
+		{name: "name", print: printObjects(OutputFormat{Name: OutputName})},
+		{name: "custom-columns", print: printObjects(OutputFormat{Name: OutputCustomColumns, Template: "NAME:.metadata.name"})},
+		{
+			name: "jsonl",
+			print: func(w io.Writer, results *executor.AggregatedResults) error {
+				printer := NewJSONLinesPrinter(w, "hub")
+				for _, result := range results.Results {
+					if err := printer.PrintResult(result); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			inStream: true,
+		},
+	}
+
+	expected := "apac,east,west"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := newResults()
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if err := tt.print(stdout, results); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			PrintClusterErrors(stderr, results, false)
+
+			if !strings.Contains(stdout.String(), "nginx") {
+				t.Errorf("expected the successful cluster's items, got %q", stdout.String())
+			}
+
+			var recorded []string
+			for _, line := range strings.Split(stderr.String(), "\n") {
+				if record, ok := strings.CutPrefix(line, errorRecordPrefix); ok {
+					cluster, _, _ := strings.Cut(record, ":")
+					recorded = append(recorded, strings.TrimSuffix(cluster, " (partial)"))
+				}
+			}
+			if got := strings.Join(recorded, ","); got != expected {
+				t.Errorf("expected error records for %s on stderr, got %q", expected, got)
+			}
+
+			if tt.inStream {
+				var streamed []string
+				for _, line := range decodeLines(t, stdout.String()) {
+					if _, ok := line["error"]; ok {
+						streamed = append(streamed, line["cluster"].(string))
+					}
+				}
+				sort.Strings(streamed)
+				if got := strings.Join(streamed, ","); got != expected {
+					t.Errorf("expected error lines for %s on stdout, got %q", expected, got)
+				}
+			} else if strings.Contains(stdout.String(), errorRecordPrefix) {
+				t.Errorf("expected stdout to stay a plain listing, got %q", stdout.String())
+			}
+		})
+	}
+}
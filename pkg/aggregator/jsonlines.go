@@ -0,0 +1,88 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+)
+
+// jsonLine is one line of -o jsonl output. Object lines carry Kind and Object;
+// error lines only carry Cluster and Error.
+type jsonLine struct {
+	Cluster string `json:"cluster"`
+	Hub     string `json:"hub,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+
+	// Type is the watch event type (ADDED, MODIFIED, DELETED) in watch mode
+	Type string `json:"type,omitempty"`
+
+	Object map[string]interface{} `json:"object,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// JSONLinesPrinter writes newline-delimited JSON, one object per line, as
+// results arrive. It never buffers, so it suits streaming and watch mode.
+type JSONLinesPrinter struct {
+	writer io.Writer
+	hub    string
+}
+
+// NewJSONLinesPrinter creates a printer that tags every object with the hub it was discovered from
+func NewJSONLinesPrinter(writer io.Writer, hub string) *JSONLinesPrinter {
+	return &JSONLinesPrinter{
+		writer: writer,
+		hub:    hub,
+	}
+}
+
+// PrintResult writes a line per item of a cluster result, followed by an
+// error line when the cluster failed entirely or in part
+func (p *JSONLinesPrinter) PrintResult(result executor.ClusterResult) error {
+	if result.Success {
+		for _, item := range result.Items {
+			line := jsonLine{Cluster: result.ClusterName, Hub: p.hub, Kind: item.GetKind(), Object: item.Object}
+			if err := p.write(line); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case result.Error != nil:
+		return p.write(jsonLine{Cluster: result.ClusterName, Error: result.Error.Error()})
+	case !result.Success:
+		return p.write(jsonLine{Cluster: result.ClusterName, Error: "unknown error"})
+	}
+	return nil
+}
+
+// PrintEvent writes a line for a watch event. Status events are only written
+// when they report an error.
+func (p *JSONLinesPrinter) PrintEvent(event executor.WatchEvent) error {
+	if event.IsStatus() {
+		if event.Error == nil {
+			return nil
+		}
+		return p.write(jsonLine{Cluster: event.Cluster, Error: event.Error.Error()})
+	}
+
+	return p.write(jsonLine{
+		Cluster: event.Cluster,
+		Hub:     p.hub,
+		Kind:    event.Object.GetKind(),
+		Type:    string(event.Type),
+		Object:  event.Object.Object,
+	})
+}
+
+// write encodes a single line
+func (p *JSONLinesPrinter) write(line jsonLine) error {
+	data, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s output: %w", OutputJSONLines, err)
+	}
+	_, err = fmt.Fprintf(p.writer, "%s\n", data)
+	return err
+}
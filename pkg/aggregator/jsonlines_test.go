@@ -0,0 +1,96 @@
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// decodeLines decodes every line of JSON lines output
+func decodeLines(t *testing.T, output string) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	for _, raw := range strings.Split(strings.TrimSpace(output), "\n") {
+		line := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(raw), &line); err != nil {
+			t.Fatalf("line is not valid JSON: %v: %s", err, raw)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestJSONLinesPrinter_PrintResult(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer := NewJSONLinesPrinter(buf, "hub-context")
+
+	pod := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": "nginx", "namespace": "default"},
+	}}
+
+	results := []executor.ClusterResult{
+		{ClusterName: "cluster1", Success: true, Items: []unstructured.Unstructured{pod}},
+		{ClusterName: "cluster2", Success: false, Error: errors.New("connection refused")},
+	}
+	for _, result := range results {
+		if err := printer.PrintResult(result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	lines := decodeLines(t, buf.String())
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+
+	if lines[0]["cluster"] != "cluster1" || lines[0]["hub"] != "hub-context" || lines[0]["kind"] != "Pod" {
+		t.Errorf("unexpected object line: %v", lines[0])
+	}
+	if name, _, _ := unstructured.NestedString(lines[0], "object", "metadata", "name"); name != "nginx" {
+		t.Errorf("expected object nginx, got %q", name)
+	}
+	if _, found := lines[0]["error"]; found {
+		t.Errorf("object lines must not carry an error: %v", lines[0])
+	}
+
+	expectedError := map[string]interface{}{"cluster": "cluster2", "error": "connection refused"}
+	if len(lines[1]) != len(expectedError) || lines[1]["cluster"] != "cluster2" || lines[1]["error"] != "connection refused" {
+		t.Errorf("expected error line %v, got %v", expectedError, lines[1])
+	}
+}
+
+func TestJSONLinesPrinter_PrintEvent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer := NewJSONLinesPrinter(buf, "hub")
+
+	events := []executor.WatchEvent{
+		{Cluster: "cluster1", Type: watch.Modified, Object: newWatchPod("nginx", "Running")},
+		{Cluster: "cluster1", Status: executor.WatchStatusConnected},
+		{Cluster: "cluster2", Status: executor.WatchStatusFailed, Error: errors.New("unauthorized")},
+	}
+	for _, event := range events {
+		if err := printer.PrintEvent(event); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	lines := decodeLines(t, buf.String())
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d:\n%s", len(lines), buf.String())
+	}
+	if lines[0]["type"] != "MODIFIED" || lines[0]["kind"] != "Pod" {
+		t.Errorf("unexpected event line: %v", lines[0])
+	}
+	if lines[1]["cluster"] != "cluster2" || lines[1]["error"] != "unauthorized" {
+		t.Errorf("unexpected error line: %v", lines[1])
+	}
+}
@@ -0,0 +1,251 @@
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Values accepted by --group-by
+const (
+	GroupByNone      = "none"
+	GroupByCluster   = "cluster"
+	GroupByNamespace = "namespace"
+)
+
+// Values accepted by --cluster-column
+const (
+	ClusterColumnFirst  = "first"
+	ClusterColumnLast   = "last"
+	ClusterColumnHidden = "hidden"
+)
+
+// TableOptions controls how multi-cluster tables are sorted and laid out
+type TableOptions struct {
+	// Wide adds the extra columns of kubectl get -o wide
+	Wide bool
+
+	// SortBy is a jsonpath expression rows are sorted by across all clusters
+	// (e.g. .metadata.creationTimestamp). Rows are sorted by cluster, namespace
+	// and name when it is empty.
+	SortBy string
+
+	// GroupBy splits tables into sections per cluster or namespace
+	GroupBy string
+
+	// NoHeaders omits the column headers
+	NoHeaders bool
+
+	// ShowLabels adds a LABELS column with all labels of each object
+	ShowLabels bool
+
+	// LabelColumns adds a column per label key, like kubectl get -L
+	LabelColumns []string
+
+	// ClusterColumn places the CLUSTER column first (the default), last, or hides it
+	ClusterColumn string
+
+	// Merge collapses copies of an object found in several clusters into one
+	// row with a CLUSTERS summary, and flags missing or diverging copies
+	Merge bool
+}
+
+// Validate checks the group-by and cluster-column values and the sort-by expression
+func (o TableOptions) Validate() error {
+	switch o.GroupBy {
+	case "", GroupByNone, GroupByCluster, GroupByNamespace:
+	default:
+		return fmt.Errorf("invalid --group-by value %q (allowed: cluster, namespace, none)", o.GroupBy)
+	}
+
+	switch o.ClusterColumn {
+	case "", ClusterColumnFirst, ClusterColumnLast, ClusterColumnHidden:
+	default:
+		return fmt.Errorf("invalid --cluster-column value %q (allowed: first, last, hidden)", o.ClusterColumn)
+	}
+
+	if o.Merge && o.GroupBy == GroupByCluster {
+		return fmt.Errorf("--merge cannot be combined with --group-by=cluster")
+	}
+
+	if o.SortBy != "" {
+		if _, err := newFieldSorter(o.SortBy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// kindTable is the table of one resource type, before the multi-cluster
+// columns (CLUSTER, NAMESPACE and labels) are added
+type kindTable struct {
+	headers []string
+	rows    []kindRow
+}
+
+// kindRow is a row of a kindTable, with a cell per header
+type kindRow struct {
+	ItemWithCluster
+	cells []string
+}
+
+// tableSection is a group of rows printed under a section header
+type tableSection struct {
+	title string
+	rows  []kindRow
+}
+
+// printTable sorts, groups and prints a table
+func (a *TableAggregator) printTable(t kindTable) error {
+	var notes []mergeNote
+	if a.options.Merge {
+		sort.SliceStable(t.rows, func(i, j int) bool {
+			return lessItem(t.rows[i].ItemWithCluster, t.rows[j].ItemWithCluster)
+		})
+		t.rows, notes = a.mergeRows(t.headers, t.rows)
+	}
+
+	if err := a.sortRows(t.rows); err != nil {
+		return err
+	}
+
+	for i, section := range a.sections(t.rows) {
+		if i > 0 {
+			fmt.Fprintln(a.writer)
+		}
+		if section.title != "" {
+			fmt.Fprintln(a.writer, section.title)
+		}
+		if err := a.printRows(t.headers, section.rows); err != nil {
+			return err
+		}
+	}
+
+	a.printMergeNotes(notes)
+	return nil
+}
+
+// sortRows orders rows by the sort-by expression, falling back to cluster,
+// namespace and name for rows with equal values. Merged rows are already in
+// namespace and name order.
+func (a *TableAggregator) sortRows(rows []kindRow) error {
+	if !a.options.Merge {
+		sort.SliceStable(rows, func(i, j int) bool {
+			return lessItem(rows[i].ItemWithCluster, rows[j].ItemWithCluster)
+		})
+	}
+	if a.options.SortBy == "" {
+		return nil
+	}
+	return sortByField(rows, func(row kindRow) ItemWithCluster { return row.ItemWithCluster }, a.options.SortBy)
+}
+
+// sections splits sorted rows by cluster or namespace. Sections are ordered by
+// name and keep the row order within each section.
+func (a *TableAggregator) sections(rows []kindRow) []tableSection {
+	var key func(row kindRow) string
+	switch a.options.GroupBy {
+	case GroupByCluster:
+		key = func(row kindRow) string { return "Cluster: " + row.Cluster }
+	case GroupByNamespace:
+		key = func(row kindRow) string { return "Namespace: " + valueOrNone(row.Item.GetNamespace()) }
+	default:
+		return []tableSection{{rows: rows}}
+	}
+
+	var sections []tableSection
+	index := make(map[string]int)
+	for _, row := range rows {
+		title := key(row)
+		i, ok := index[title]
+		if !ok {
+			i = len(sections)
+			index[title] = i
+			sections = append(sections, tableSection{title: title})
+		}
+		sections[i].rows = append(sections[i].rows, row)
+	}
+
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i].title < sections[j].title
+	})
+	return sections
+}
+
+// printRows writes rows with the CLUSTER, NAMESPACE and label columns placed
+// around the resource columns
+func (a *TableAggregator) printRows(headers []string, rows []kindRow) error {
+	// A column repeating the section header is left out
+	clusterColumn := a.options.ClusterColumn
+	if a.options.GroupBy == GroupByCluster {
+		clusterColumn = ClusterColumnHidden
+	}
+
+	namespaced := false
+	if a.options.GroupBy != GroupByNamespace {
+		for _, row := range rows {
+			if row.Item.GetNamespace() != "" {
+				namespaced = true
+				break
+			}
+		}
+	}
+
+	line := func(cluster, namespace string, cells, labelCells []string) string {
+		var fields []string
+		if clusterColumn == "" || clusterColumn == ClusterColumnFirst {
+			fields = append(fields, cluster)
+		}
+		if namespaced {
+			fields = append(fields, namespace)
+		}
+		fields = append(fields, cells...)
+		fields = append(fields, labelCells...)
+		if clusterColumn == ClusterColumnLast {
+			fields = append(fields, cluster)
+		}
+		return strings.Join(fields, "\t")
+	}
+
+	w := tabwriter.NewWriter(a.writer, tabwriterMinWidth, tabwriterWidth, tabwriterPadding, ' ', 0)
+
+	if !a.options.NoHeaders {
+		labelHeaders := labelColumnHeaders(a.options.LabelColumns)
+		if a.options.ShowLabels {
+			labelHeaders = append(labelHeaders, "LABELS")
+		}
+		clusterHeader := "CLUSTER"
+		if a.options.Merge {
+			clusterHeader = "CLUSTERS"
+		}
+		fmt.Fprintln(w, line(clusterHeader, "NAMESPACE", headers, labelHeaders))
+	}
+
+	for _, row := range rows {
+		objectLabels := row.Item.GetLabels()
+		var labelCells []string
+		for _, key := range a.options.LabelColumns {
+			labelCells = append(labelCells, objectLabels[key])
+		}
+		if a.options.ShowLabels {
+			labelCells = append(labelCells, labels.FormatLabels(objectLabels))
+		}
+		fmt.Fprintln(w, line(row.Cluster, row.Item.GetNamespace(), row.cells, labelCells))
+	}
+
+	return w.Flush()
+}
+
+// labelColumnHeaders returns the headers of -L columns: the upper-cased last
+// path segment of each label key, as kubectl prints them
+func labelColumnHeaders(keys []string) []string {
+	headers := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts := strings.Split(key, "/")
+		headers = append(headers, strings.ToUpper(parts[len(parts)-1]))
+	}
+	return headers
+}
@@ -0,0 +1,241 @@
+package aggregator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// layoutTestResults returns pods with labels, restarts and creation times in three clusters
+func layoutTestResults() *executor.AggregatedResults {
+	pod := func(name, namespace, created string, restarts int64, podLabels map[string]interface{}) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata": map[string]interface{}{
+				"name":              name,
+				"namespace":         namespace,
+				"creationTimestamp": created,
+				"labels":            podLabels,
+			},
+			"status": map[string]interface{}{
+				"phase": "Running",
+				"containerStatuses": []interface{}{
+					map[string]interface{}{"ready": true, "restartCount": restarts},
+				},
+			},
+		}}
+	}
+
+	return &executor.AggregatedResults{
+		Results: []executor.ClusterResult{
+			{ClusterName: "cluster1", Success: true, Items: []unstructured.Unstructured{
+				pod("api", "prod", "2024-03-01T00:00:00Z", 12, map[string]interface{}{"app": "api", "team": "core"}),
+				pod("web", "default", "2024-01-01T00:00:00Z", 0, map[string]interface{}{"app": "web"}),
+			}},
+			{ClusterName: "cluster2", Success: true, Items: []unstructured.Unstructured{
+				pod("api", "prod", "2024-02-01T00:00:00Z", 3, map[string]interface{}{"app": "api"}),
+			}},
+		},
+	}
+}
+
+// printLayout prints layoutTestResults with the given options
+func printLayout(t *testing.T, options TableOptions) []string {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+	if err := agg.SetOptions(options); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := agg.AggregateGetResults(layoutTestResults(), "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// rowKeys returns "cluster/name" for every row, reading the given columns
+func rowKeys(lines []string, clusterColumn, nameColumn int) []string {
+	var keys []string
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		keys = append(keys, fields[clusterColumn]+"/"+fields[nameColumn])
+	}
+	return keys
+}
+
+func TestTableOptions_SortBy(t *testing.T) {
+	tests := []struct {
+		sortBy   string
+		expected []string
+	}{
+		{sortBy: "", expected: []string{"cluster1/web", "cluster1/api", "cluster2/api"}},
+		{sortBy: ".metadata.creationTimestamp", expected: []string{"cluster1/web", "cluster2/api", "cluster1/api"}},
+		{sortBy: "{.status.containerStatuses[0].restartCount}", expected: []string{"cluster1/web", "cluster2/api", "cluster1/api"}},
+		{sortBy: ".metadata.name", expected: []string{"cluster1/api", "cluster2/api", "cluster1/web"}},
+		// Missing fields sort first, then ties keep the default order
+		{sortBy: ".metadata.labels.team", expected: []string{"cluster1/web", "cluster2/api", "cluster1/api"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sortBy, func(t *testing.T) {
+			lines := printLayout(t, TableOptions{SortBy: tt.sortBy})
+			got := rowKeys(lines[1:], 0, 2)
+			if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("expected order %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTableOptions_GroupByCluster(t *testing.T) {
+	lines := printLayout(t, TableOptions{GroupBy: GroupByCluster})
+
+	expected := []string{
+		"Cluster: cluster1",
+		"NAMESPACE",
+		"default",
+		"prod",
+		"",
+		"Cluster: cluster2",
+		"NAMESPACE",
+		"prod",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got:\n%s", len(expected), strings.Join(lines, "\n"))
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("line %d: expected prefix %q, got %q", i, prefix, lines[i])
+		}
+	}
+
+	// The cluster is in the section header rather than a column
+	if strings.Contains(lines[1], "CLUSTER") {
+		t.Errorf("expected no CLUSTER column when grouping by cluster, got %q", lines[1])
+	}
+}
+
+func TestTableOptions_GroupByNamespace(t *testing.T) {
+	lines := printLayout(t, TableOptions{GroupBy: GroupByNamespace, NoHeaders: true})
+
+	expected := []string{"Namespace: default", "cluster1", "", "Namespace: prod", "cluster1", "cluster2"}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got:\n%s", len(expected), strings.Join(lines, "\n"))
+	}
+	for i, prefix := range expected {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("line %d: expected prefix %q, got %q", i, prefix, lines[i])
+		}
+	}
+}
+
+func TestTableOptions_Columns(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  TableOptions
+		header   string
+		firstRow string
+	}{
+		{
+			name:     "default",
+			options:  TableOptions{},
+			header:   "CLUSTER NAMESPACE NAME READY STATUS RESTARTS AGE",
+			firstRow: "cluster1 default web",
+		},
+		{
+			name:     "cluster last",
+			options:  TableOptions{ClusterColumn: ClusterColumnLast},
+			header:   "NAMESPACE NAME READY STATUS RESTARTS AGE CLUSTER",
+			firstRow: "default web",
+		},
+		{
+			name:     "cluster hidden",
+			options:  TableOptions{ClusterColumn: ClusterColumnHidden},
+			header:   "NAMESPACE NAME READY STATUS RESTARTS AGE",
+			firstRow: "default web",
+		},
+		{
+			name:     "label columns",
+			options:  TableOptions{LabelColumns: []string{"example.com/app", "team"}, ShowLabels: true, ClusterColumn: ClusterColumnLast},
+			header:   "NAMESPACE NAME READY STATUS RESTARTS AGE APP TEAM LABELS CLUSTER",
+			firstRow: "default web",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := printLayout(t, tt.options)
+			if got := strings.Join(strings.Fields(lines[0]), " "); got != tt.header {
+				t.Errorf("expected header %q, got %q", tt.header, got)
+			}
+			if !strings.HasPrefix(strings.Join(strings.Fields(lines[1]), " "), tt.firstRow) {
+				t.Errorf("expected first row to start with %q, got %q", tt.firstRow, lines[1])
+			}
+		})
+	}
+}
+
+func TestTableOptions_Labels(t *testing.T) {
+	lines := printLayout(t, TableOptions{LabelColumns: []string{"team"}, ShowLabels: true, NoHeaders: true})
+
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 rows without headers, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	// cluster1/api has both labels; cluster1/web has no team label
+	if !strings.HasSuffix(lines[1], "core   app=api,team=core") {
+		t.Errorf("unexpected label columns: %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[0], "app=web") || strings.Contains(lines[0], "core") {
+		t.Errorf("unexpected label columns: %q", lines[0])
+	}
+}
+
+func TestTableOptions_Validate(t *testing.T) {
+	tests := []struct {
+		options TableOptions
+		wantErr bool
+	}{
+		{options: TableOptions{}},
+		{options: TableOptions{GroupBy: GroupByNone, ClusterColumn: ClusterColumnFirst, SortBy: "{.metadata.name}"}},
+		{options: TableOptions{GroupBy: "kind"}, wantErr: true},
+		{options: TableOptions{ClusterColumn: "middle"}, wantErr: true},
+		{options: TableOptions{SortBy: "{.metadata.name"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := tt.options.Validate()
+		if tt.wantErr && err == nil {
+			t.Errorf("%+v: expected error", tt.options)
+		}
+		if !tt.wantErr && err != nil {
+			t.Errorf("%+v: unexpected error: %v", tt.options, err)
+		}
+	}
+}
+
+func TestLessValue(t *testing.T) {
+	tests := []struct {
+		a, b     interface{}
+		expected bool
+	}{
+		{a: int64(2), b: int64(10), expected: true},
+		{a: int64(10), b: float64(2.5), expected: false},
+		{a: "a", b: "b", expected: true},
+		{a: nil, b: "a", expected: true},
+		{a: "a", b: nil, expected: false},
+		{a: nil, b: nil, expected: false},
+		{a: true, b: false, expected: false},
+	}
+
+	for _, tt := range tests {
+		if got := lessValue(tt.a, tt.b); got != tt.expected {
+			t.Errorf("lessValue(%v, %v): expected %v, got %v", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
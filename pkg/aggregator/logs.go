@@ -0,0 +1,90 @@
+package aggregator
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+)
+
+// LogPrinter prints log lines prefixed with [cluster/pod/container]. In
+// chronological mode lines are buffered and released in timestamp order, which
+// requires the lines to carry timestamps.
+type LogPrinter struct {
+	writer io.Writer
+
+	// timestamps prints each line's timestamp after the prefix
+	timestamps bool
+
+	// chronological buffers lines until Flush or Close
+	chronological bool
+	buffered      []executor.LogLine
+}
+
+// NewLogPrinter creates a log printer
+func NewLogPrinter(writer io.Writer, timestamps, chronological bool) *LogPrinter {
+	return &LogPrinter{
+		writer:        writer,
+		timestamps:    timestamps,
+		chronological: chronological,
+	}
+}
+
+// Print prints a log line, or buffers it in chronological mode
+func (p *LogPrinter) Print(line executor.LogLine) error {
+	if p.chronological {
+		p.buffered = append(p.buffered, line)
+		return nil
+	}
+	return p.printLine(line)
+}
+
+// Flush prints, in timestamp order, the buffered lines logged at or before
+// watermark. Lines that arrive later with an older timestamp are printed as
+// soon as possible, so the watermark should trail the newest line by the
+// expected delivery delay.
+func (p *LogPrinter) Flush(watermark time.Time) error {
+	p.sortBuffered()
+
+	n := sort.Search(len(p.buffered), func(i int) bool {
+		return p.buffered[i].Timestamp.After(watermark)
+	})
+	return p.printBuffered(n)
+}
+
+// Close prints every buffered line in timestamp order
+func (p *LogPrinter) Close() error {
+	p.sortBuffered()
+	return p.printBuffered(len(p.buffered))
+}
+
+// sortBuffered orders buffered lines by timestamp, keeping each container's order
+func (p *LogPrinter) sortBuffered() {
+	sort.SliceStable(p.buffered, func(i, j int) bool {
+		return p.buffered[i].Timestamp.Before(p.buffered[j].Timestamp)
+	})
+}
+
+// printBuffered prints and drops the first n buffered lines
+func (p *LogPrinter) printBuffered(n int) error {
+	for _, line := range p.buffered[:n] {
+		if err := p.printLine(line); err != nil {
+			return err
+		}
+	}
+	p.buffered = p.buffered[n:]
+	return nil
+}
+
+// printLine writes a single prefixed line
+func (p *LogPrinter) printLine(line executor.LogLine) error {
+	prefix := fmt.Sprintf("[%s/%s/%s] ", line.Cluster, line.Pod, line.Container)
+	if p.timestamps && !line.Timestamp.IsZero() {
+		prefix += line.Timestamp.Format(time.RFC3339Nano) + " "
+	}
+
+	_, err := fmt.Fprintln(p.writer, prefix+line.Text)
+	return err
+}
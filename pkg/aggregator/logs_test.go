@@ -0,0 +1,68 @@
+package aggregator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+)
+
+func TestLogPrinter_Prefix(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer := NewLogPrinter(buf, false, false)
+
+	line := executor.LogLine{Cluster: "cluster1", Pod: "nginx", Container: "web", Text: "GET / 200"}
+	if err := printer.Print(line); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "[cluster1/nginx/web] GET / 200\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestLogPrinter_Chronological(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer := NewLogPrinter(buf, true, true)
+
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	lines := []executor.LogLine{
+		{Cluster: "eu", Pod: "api", Container: "api", Timestamp: base.Add(2 * time.Second), Text: "third"},
+		{Cluster: "us", Pod: "api", Container: "api", Timestamp: base, Text: "first"},
+		{Cluster: "eu", Pod: "api", Container: "api", Timestamp: base.Add(time.Second), Text: "second"},
+	}
+	for _, line := range lines {
+		if err := printer.Print(line); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("expected lines to be buffered, got %q", buf.String())
+	}
+
+	// Only lines up to the watermark are released
+	if err := printer.Flush(base.Add(time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 2 {
+		t.Errorf("expected 2 lines after flush, got %d: %q", got, buf.String())
+	}
+
+	if err := printer.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		"[us/api/api] 2024-05-01T10:00:00Z first",
+		"[eu/api/api] 2024-05-01T10:00:01Z second",
+		"[eu/api/api] 2024-05-01T10:00:02Z third",
+	}
+	if strings.Join(output, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(expected, "\n"), buf.String())
+	}
+}
@@ -0,0 +1,345 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxListedValues is the number of distinct values a merged cell lists before
+// it only reports that they vary
+const maxListedValues = 3
+
+// allocatedFields are set by each cluster rather than by the user, so they
+// are ignored when checking whether copies of an object diverge
+var allocatedFields = [][]string{
+	{"spec", "clusterIP"},
+	{"spec", "clusterIPs"},
+	{"spec", "healthCheckNodePort"},
+	{"spec", "nodeName"},
+	{"spec", "volumeName"},
+}
+
+// additiveColumns are the columns whose values add up across clusters, such as
+// replica and restart counts. Other numbers, e.g. a ConfigMap's DATA or a
+// PriorityClass's VALUE, describe a single copy and are never summed.
+var additiveColumns = map[string]bool{
+	"READY":       true,
+	"UP-TO-DATE":  true,
+	"AVAILABLE":   true,
+	"DESIRED":     true,
+	"CURRENT":     true,
+	"RESTARTS":    true,
+	"COMPLETIONS": true,
+	"ACTIVE":      true,
+	"REPLICAS":    true,
+}
+
+// mergeNote flags an object that is missing from, or differs on, some clusters
+type mergeNote struct {
+	object   string
+	missing  []string
+	diverged []string
+}
+
+// mergeRows collapses the rows of an object (same namespace and name) found in
+// several clusters into one row. The row's cluster becomes a summary such as
+// "prod-* (12/14)", and cells are aggregated with mergeCells. Rows must be
+// sorted by cluster.
+func (a *TableAggregator) mergeRows(headers []string, rows []kindRow) ([]kindRow, []mergeNote) {
+	var groups [][]kindRow
+	index := make(map[string]int)
+	for _, row := range rows {
+		key := row.Item.GetNamespace() + "/" + row.Item.GetName()
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+
+	merged := make([]kindRow, 0, len(groups))
+	var notes []mergeNote
+	for _, group := range groups {
+		found := make([]string, 0, len(group))
+		for _, row := range group {
+			found = append(found, row.Cluster)
+		}
+
+		representative := group[0]
+		merged = append(merged, kindRow{
+			ItemWithCluster: ItemWithCluster{Item: representative.Item, Cluster: summarizeClusters(found, len(a.clusters))},
+			cells:           mergeCells(headers, group),
+		})
+
+		note := mergeNote{
+			object:   objectDisplayName(representative.Item),
+			missing:  missingClusters(a.clusters, found),
+			diverged: divergedClusters(group),
+		}
+		if len(note.missing) > 0 || len(note.diverged) > 0 {
+			notes = append(notes, note)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return lessNamespacedName(merged[i].Item, merged[j].Item)
+	})
+	sort.SliceStable(notes, func(i, j int) bool {
+		return notes[i].object < notes[j].object
+	})
+	return merged, notes
+}
+
+// printMergeNotes lists the objects that are missing from or diverge on some clusters
+func (a *TableAggregator) printMergeNotes(notes []mergeNote) {
+	if len(notes) == 0 {
+		return
+	}
+
+	fmt.Fprintln(a.writer)
+	fmt.Fprintln(a.writer, "Objects missing or diverging across clusters:")
+	for _, note := range notes {
+		var details []string
+		if len(note.missing) > 0 {
+			details = append(details, "missing on "+strings.Join(note.missing, ", "))
+		}
+		if len(note.diverged) > 0 {
+			details = append(details, "differs on "+strings.Join(note.diverged, ", "))
+		}
+		fmt.Fprintf(a.writer, "  %s: %s\n", note.object, strings.Join(details, "; "))
+	}
+}
+
+// mergeCells aggregates the cells of one object's rows. Counts (e.g. 3) and
+// ratios (e.g. 2/3) of additiveColumns are summed, identical values are kept,
+// AGE shows the oldest copy, and other differing values are listed with the
+// number of clusters reporting each (e.g. "Running:11,CrashLoopBackOff:1").
+func mergeCells(headers []string, rows []kindRow) []string {
+	cells := make([]string, len(headers))
+	for c, header := range headers {
+		values := make([]string, 0, len(rows))
+		for _, row := range rows {
+			if c < len(row.cells) {
+				values = append(values, row.cells[c])
+			}
+		}
+
+		switch {
+		case c == 0 && header == "NAME":
+			cells[c] = rows[0].cells[c]
+		case header == "AGE":
+			cells[c] = oldestCell(rows, c)
+		default:
+			cells[c] = mergeValues(header, values)
+		}
+	}
+	return cells
+}
+
+// mergeValues combines the values of one column across clusters
+func mergeValues(header string, values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+
+	if additiveColumns[header] {
+		if sum, ok := sumCounts(values); ok {
+			return sum
+		}
+		if sum, ok := sumRatios(values); ok {
+			return sum
+		}
+	}
+
+	same := true
+	for _, value := range values[1:] {
+		if value != values[0] {
+			same = false
+			break
+		}
+	}
+	if same {
+		return values[0]
+	}
+
+	counts := make(map[string]int)
+	var distinct []string
+	for _, value := range values {
+		if counts[value] == 0 {
+			distinct = append(distinct, value)
+		}
+		counts[value]++
+	}
+	if len(distinct) > maxListedValues {
+		return "<varies>"
+	}
+
+	sort.SliceStable(distinct, func(i, j int) bool {
+		return counts[distinct[i]] > counts[distinct[j]]
+	})
+	listed := make([]string, 0, len(distinct))
+	for _, value := range distinct {
+		listed = append(listed, fmt.Sprintf("%s:%d", value, counts[value]))
+	}
+	return strings.Join(listed, ",")
+}
+
+// sumCounts sums values that are all integers
+func sumCounts(values []string) (string, bool) {
+	total := int64(0)
+	for _, value := range values {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", false
+		}
+		total += n
+	}
+	return strconv.FormatInt(total, 10), true
+}
+
+// sumRatios sums values that are all ratios such as READY's 2/3
+func sumRatios(values []string) (string, bool) {
+	var num, den int64
+	for _, value := range values {
+		a, b, found := strings.Cut(value, "/")
+		if !found {
+			return "", false
+		}
+		x, errA := strconv.ParseInt(a, 10, 64)
+		y, errB := strconv.ParseInt(b, 10, 64)
+		if errA != nil || errB != nil {
+			return "", false
+		}
+		num += x
+		den += y
+	}
+	return fmt.Sprintf("%d/%d", num, den), true
+}
+
+// oldestCell returns the cell of the row whose object was created first
+func oldestCell(rows []kindRow, c int) string {
+	oldest := rows[0]
+	for _, row := range rows[1:] {
+		created, oldestCreated := row.Item.GetCreationTimestamp(), oldest.Item.GetCreationTimestamp()
+		if created.Before(&oldestCreated) {
+			oldest = row
+		}
+	}
+	if c < len(oldest.cells) {
+		return oldest.cells[c]
+	}
+	return ""
+}
+
+// summarizeClusters describes the clusters an object was found in, e.g. "prod-* (12/14)".
+// Names are listed when few, otherwise abbreviated to their common prefix.
+func summarizeClusters(found []string, total int) string {
+	var names string
+	switch {
+	case len(found) == 1:
+		names = found[0]
+	case len(found) == total:
+		names = "all"
+	default:
+		prefix := found[0]
+		for _, name := range found[1:] {
+			for !strings.HasPrefix(name, prefix) {
+				prefix = prefix[:len(prefix)-1]
+			}
+		}
+		if prefix != "" {
+			names = prefix + "*"
+		} else if len(found) <= maxListedValues {
+			names = strings.Join(found, ",")
+		} else {
+			names = "*"
+		}
+	}
+	return fmt.Sprintf("%s (%d/%d)", names, len(found), total)
+}
+
+// missingClusters returns the clusters in all that are not in found
+func missingClusters(all, found []string) []string {
+	present := make(map[string]bool, len(found))
+	for _, cluster := range found {
+		present[cluster] = true
+	}
+
+	var missing []string
+	for _, cluster := range all {
+		if !present[cluster] {
+			missing = append(missing, cluster)
+		}
+	}
+	return missing
+}
+
+// divergedClusters returns the clusters whose copy of an object differs from
+// the most common one, ignoring metadata, status and allocated fields
+func divergedClusters(rows []kindRow) []string {
+	if len(rows) < 2 {
+		return nil
+	}
+
+	contents := make([]string, len(rows))
+	counts := make(map[string]int)
+	for i, row := range rows {
+		contents[i] = objectContent(row.Item)
+		counts[contents[i]]++
+	}
+
+	// The most common content wins, ties going to the first cluster
+	reference := contents[0]
+	for _, content := range contents {
+		if counts[content] > counts[reference] {
+			reference = content
+		}
+	}
+
+	var diverged []string
+	for i, row := range rows {
+		if contents[i] != reference {
+			diverged = append(diverged, row.Cluster)
+		}
+	}
+	return diverged
+}
+
+// objectContent serializes the user-defined part of an object
+func objectContent(obj unstructured.Unstructured) string {
+	content := obj.DeepCopy().Object
+	delete(content, "metadata")
+	delete(content, "status")
+	for _, path := range allocatedFields {
+		unstructured.RemoveNestedField(content, path...)
+	}
+
+	ports, _, _ := unstructured.NestedSlice(content, "spec", "ports")
+	for _, p := range ports {
+		if port, ok := p.(map[string]interface{}); ok {
+			delete(port, "nodePort")
+		}
+	}
+	if ports != nil {
+		_ = unstructured.SetNestedSlice(content, ports, "spec", "ports")
+	}
+
+	// encoding/json sorts map keys, so equal objects serialize identically
+	data, _ := json.Marshal(content)
+	return string(data)
+}
+
+// objectDisplayName returns namespace/name, or the name of a cluster-scoped object
+func objectDisplayName(obj unstructured.Unstructured) string {
+	if ns := obj.GetNamespace(); ns != "" {
+		return ns + "/" + obj.GetName()
+	}
+	return obj.GetName()
+}
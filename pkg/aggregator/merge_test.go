@@ -0,0 +1,166 @@
+package aggregator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// mergeTestDeployment returns a deployment with the given image and replica status
+func mergeTestDeployment(name, image, created string, ready, replicas int64) unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":              name,
+			"namespace":         "default",
+			"creationTimestamp": created,
+			"uid":               name + "-" + created,
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{"spec": map[string]interface{}{
+				"containers": []interface{}{map[string]interface{}{"name": "app", "image": image}},
+			}},
+		},
+		"status": map[string]interface{}{
+			"replicas":          replicas,
+			"readyReplicas":     ready,
+			"updatedReplicas":   replicas,
+			"availableReplicas": ready,
+		},
+	}}
+}
+
+func TestAggregateGetResults_Merge(t *testing.T) {
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+	if err := agg.SetOptions(TableOptions{Merge: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results := &executor.AggregatedResults{
+		Results: []executor.ClusterResult{
+			{ClusterName: "prod-a", Success: true, Items: []unstructured.Unstructured{
+				mergeTestDeployment("api", "api:1", "2024-01-01T00:00:00Z", 3, 3),
+				mergeTestDeployment("web", "web:1", "2024-01-01T00:00:00Z", 2, 2),
+			}},
+			{ClusterName: "prod-b", Success: true, Items: []unstructured.Unstructured{
+				mergeTestDeployment("api", "api:1", "2023-06-01T00:00:00Z", 2, 3),
+				mergeTestDeployment("web", "web:1", "2024-01-01T00:00:00Z", 2, 2),
+			}},
+			{ClusterName: "prod-c", Success: true, Items: []unstructured.Unstructured{
+				mergeTestDeployment("api", "api:2", "2024-01-01T00:00:00Z", 3, 3),
+			}},
+			{ClusterName: "staging", Success: false},
+		},
+	}
+
+	if err := agg.AggregateGetResults(results, "deployments"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := []string{
+		"CLUSTERS NAMESPACE NAME READY UP-TO-DATE AVAILABLE AGE",
+		"all (3/3) default api 8/9 9 8",
+		"prod-* (2/3) default web 4/4 4 4",
+		"",
+		"Objects missing or diverging across clusters:",
+		"default/api: differs on prod-c",
+		"default/web: missing on prod-c",
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("expected %d lines, got:\n%s", len(expected), buf.String())
+	}
+	for i, want := range expected {
+		if got := strings.Join(strings.Fields(lines[i]), " "); !strings.HasPrefix(got, want) {
+			t.Errorf("line %d: expected %q, got %q", i, want, got)
+		}
+	}
+}
+
+func TestMergeValues(t *testing.T) {
+	tests := []struct {
+		header   string
+		values   []string
+		expected string
+	}{
+		{header: "TYPE", values: []string{"ClusterIP", "ClusterIP"}, expected: "ClusterIP"},
+		{header: "RESTARTS", values: []string{"1", "2", "3"}, expected: "6"},
+		{header: "READY", values: []string{"1/2", "2/2"}, expected: "3/4"},
+		{header: "READY", values: []string{"2/2", "2/2"}, expected: "4/4"},
+		{header: "STATUS", values: []string{"Running", "CrashLoopBackOff", "Running"}, expected: "Running:2,CrashLoopBackOff:1"},
+		{header: "CLUSTER-IP", values: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"}, expected: "<varies>"},
+		{header: "READY", values: []string{"1", "1/2"}, expected: "1:1,1/2:1"},
+		// Per-copy numbers are not additive
+		{header: "DATA", values: []string{"2", "2"}, expected: "2"},
+		{header: "DATA", values: []string{"2", "3", "2"}, expected: "2:2,3:1"},
+		{header: "VALUE", values: []string{"1000", "1000"}, expected: "1000"},
+		{header: "SCORE", values: []string{"1/2", "1/2"}, expected: "1/2"},
+	}
+
+	for _, tt := range tests {
+		if got := mergeValues(tt.header, tt.values); got != tt.expected {
+			t.Errorf("mergeValues(%s, %v): expected %q, got %q", tt.header, tt.values, tt.expected, got)
+		}
+	}
+}
+
+func TestSummarizeClusters(t *testing.T) {
+	tests := []struct {
+		found    []string
+		total    int
+		expected string
+	}{
+		{found: []string{"prod-eu"}, total: 3, expected: "prod-eu (1/3)"},
+		{found: []string{"prod-eu", "prod-us"}, total: 2, expected: "all (2/2)"},
+		{found: []string{"prod-eu", "prod-us"}, total: 14, expected: "prod-* (2/14)"},
+		{found: []string{"east", "west"}, total: 3, expected: "east,west (2/3)"},
+		{found: []string{"a", "b", "c", "d"}, total: 5, expected: "* (4/5)"},
+	}
+
+	for _, tt := range tests {
+		if got := summarizeClusters(tt.found, tt.total); got != tt.expected {
+			t.Errorf("summarizeClusters(%v, %d): expected %q, got %q", tt.found, tt.total, tt.expected, got)
+		}
+	}
+}
+
+func TestDivergedClusters_IgnoresAllocatedFields(t *testing.T) {
+	service := func(cluster, clusterIP string, nodePort int64, selector string) kindRow {
+		return kindRow{
+			ItemWithCluster: ItemWithCluster{Cluster: cluster, Item: unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"name": "api", "uid": cluster},
+				"spec": map[string]interface{}{
+					"clusterIP": clusterIP,
+					"selector":  map[string]interface{}{"app": selector},
+					"ports":     []interface{}{map[string]interface{}{"port": int64(80), "nodePort": nodePort}},
+				},
+			}}},
+		}
+	}
+
+	rows := []kindRow{
+		service("a", "10.0.0.1", 30001, "api"),
+		service("b", "10.0.0.2", 30002, "api"),
+		service("c", "10.0.0.3", 30003, "api-v2"),
+	}
+
+	diverged := divergedClusters(rows)
+	if len(diverged) != 1 || diverged[0] != "c" {
+		t.Errorf("expected only c to diverge, got %v", diverged)
+	}
+}
+
+func TestTableOptions_MergeValidation(t *testing.T) {
+	if err := (TableOptions{Merge: true, GroupBy: GroupByCluster}).Validate(); err == nil {
+		t.Error("expected --merge with --group-by=cluster to be rejected")
+	}
+	if err := (TableOptions{Merge: true, GroupBy: GroupByNamespace}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
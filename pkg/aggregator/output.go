@@ -0,0 +1,258 @@
+package aggregator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/cli-runtime/pkg/printers"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/kubectl/pkg/cmd/get"
+)
+
+// ClusterAnnotation records the cluster an object was retrieved from in JSON and YAML output
+const ClusterAnnotation = "kubectl-mc.k8s.io/cluster"
+
+// clusterField is the pseudo-field holding an object's cluster in jsonpath,
+// go-template and custom-columns output (e.g. {.cluster})
+const clusterField = "cluster"
+
+// Output format names accepted by -o
+const (
+	OutputTable         = ""
+	OutputWide          = "wide"
+	OutputJSON          = "json"
+	OutputYAML          = "yaml"
+	OutputName          = "name"
+	OutputJSONPath      = "jsonpath"
+	OutputGoTemplate    = "go-template"
+	OutputCustomColumns = "custom-columns"
+	OutputJSONLines     = "jsonl"
+)
+
+// OutputFormat is a parsed -o value
+type OutputFormat struct {
+	// Name is one of the Output* constants
+	Name string
+
+	// Template holds the jsonpath, go-template or custom-columns specification
+	Template string
+
+	// fromFile marks templates read with a -file variant; custom columns
+	// files use a header line and a field line instead of the inline spec
+	fromFile bool
+}
+
+// ParseOutputFormat parses a kubectl style -o value such as "json",
+// "jsonpath={.items[*].cluster}" or "custom-columns-file=columns.txt"
+func ParseOutputFormat(value string) (OutputFormat, error) {
+	name, template, hasTemplate := strings.Cut(value, "=")
+
+	// The -file variants read the template from a file
+	if base, ok := strings.CutSuffix(name, "-file"); ok {
+		switch base {
+		case OutputJSONPath, OutputGoTemplate, OutputCustomColumns:
+		default:
+			return OutputFormat{}, fmt.Errorf("unsupported output format: %s", value)
+		}
+		if !hasTemplate || template == "" {
+			return OutputFormat{}, fmt.Errorf("output format %s requires a file name", name)
+		}
+		data, err := os.ReadFile(template)
+		if err != nil {
+			return OutputFormat{}, fmt.Errorf("failed to read template file: %w", err)
+		}
+		return OutputFormat{Name: base, Template: string(data), fromFile: true}, nil
+	}
+
+	switch name {
+	case OutputTable, OutputWide, OutputJSON, OutputYAML, OutputName, OutputJSONLines:
+		if hasTemplate {
+			return OutputFormat{}, fmt.Errorf("output format %s does not take a template", name)
+		}
+		return OutputFormat{Name: name}, nil
+	case OutputJSONPath, OutputGoTemplate, OutputCustomColumns:
+		if !hasTemplate || template == "" {
+			return OutputFormat{}, fmt.Errorf("output format %s requires a template, e.g. -o %s=<template>", name, name)
+		}
+		return OutputFormat{Name: name, Template: template}, nil
+	default:
+		return OutputFormat{}, fmt.Errorf("unsupported output format: %s (allowed: json, jsonl, yaml, name, wide, jsonpath, go-template, custom-columns)", value)
+	}
+}
+
+// IsTable reports whether the format prints a table rather than objects
+func (f OutputFormat) IsTable() bool {
+	return f.Name == OutputTable || f.Name == OutputWide
+}
+
+// ObjectPrinter prints multi-cluster get results as objects, in the same order
+// as the tables: by kind, then cluster, namespace and name
+type ObjectPrinter struct {
+	writer io.Writer
+	format OutputFormat
+
+	// sortBy is a jsonpath expression items are sorted by across clusters
+	sortBy string
+}
+
+// NewObjectPrinter creates a printer for a non-table output format.
+// JSON lines are streamed by a JSONLinesPrinter instead.
+func NewObjectPrinter(writer io.Writer, format OutputFormat) (*ObjectPrinter, error) {
+	if format.IsTable() || format.Name == OutputJSONLines {
+		return nil, fmt.Errorf("output format %q is not printed as a list of objects", format.Name)
+	}
+	return &ObjectPrinter{writer: writer, format: format}, nil
+}
+
+// SetSortBy sorts items by a jsonpath expression instead of cluster, namespace and name
+func (p *ObjectPrinter) SetSortBy(expr string) error {
+	if expr != "" {
+		if _, err := newFieldSorter(expr); err != nil {
+			return err
+		}
+	}
+	p.sortBy = expr
+	return nil
+}
+
+// PrintGetResults prints the items from all successful clusters. JSON, YAML,
+// jsonpath and go-template output also carry an errors field listing the
+// clusters that failed, which is left out when every cluster succeeded. Name
+// and custom-columns output leave the failures to the error records of
+// PrintClusterErrors on stderr, so stdout stays a plain list.
+func (p *ObjectPrinter) PrintGetResults(results *executor.AggregatedResults, resourceType string) error {
+	var allItems []ItemWithCluster
+	for _, result := range results.Results {
+		if !result.Success {
+			continue
+		}
+		for _, item := range result.Items {
+			allItems = append(allItems, ItemWithCluster{Item: item, Cluster: result.ClusterName})
+		}
+	}
+
+	groups := [][]ItemWithCluster{allItems}
+	if isMultiResource(resourceType) {
+		groups = groupByKind(allItems)
+	}
+
+	var ordered []ItemWithCluster
+	for _, group := range groups {
+		sortItems(group)
+		if p.sortBy != "" {
+			if err := sortByField(group, func(item ItemWithCluster) ItemWithCluster { return item }, p.sortBy); err != nil {
+				return err
+			}
+		}
+		ordered = append(ordered, group...)
+	}
+
+	errs := ClusterErrors(results)
+	switch p.format.Name {
+	case OutputName:
+		return p.printNames(ordered)
+	case OutputJSON, OutputYAML:
+		return p.printList(ordered, errs, annotateCluster)
+	default:
+		return p.printList(ordered, errs, withClusterField)
+	}
+}
+
+// printNames prints cluster/kind[.group]/name for every item
+func (p *ObjectPrinter) printNames(items []ItemWithCluster) error {
+	for _, item := range items {
+		kind := strings.ToLower(item.Item.GetKind())
+		if group := item.Item.GroupVersionKind().Group; group != "" {
+			kind += "." + group
+		}
+		if _, err := fmt.Fprintf(p.writer, "%s/%s/%s\n", item.Cluster, kind, item.Item.GetName()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printList prints the items as a v1 List, after decorating each one with its
+// cluster, and the cluster errors if there are any
+func (p *ObjectPrinter) printList(items []ItemWithCluster, errs []ClusterError, decorate func(ItemWithCluster) unstructured.Unstructured) error {
+	list := &unstructured.UnstructuredList{Object: map[string]interface{}{}}
+	list.SetAPIVersion("v1")
+	list.SetKind("List")
+	list.SetResourceVersion("")
+	if len(errs) > 0 {
+		list.Object[errorsField] = errorsSection(errs)
+	}
+	list.Items = make([]unstructured.Unstructured, 0, len(items))
+	for _, item := range items {
+		list.Items = append(list.Items, decorate(item))
+	}
+
+	printer, err := p.printer()
+	if err != nil {
+		return err
+	}
+	return printer.PrintObj(list, p.writer)
+}
+
+// printer returns the cli-runtime printer for the output format
+func (p *ObjectPrinter) printer() (printers.ResourcePrinter, error) {
+	switch p.format.Name {
+	case OutputJSON:
+		return &printers.JSONPrinter{}, nil
+	case OutputYAML:
+		return &printers.YAMLPrinter{}, nil
+	case OutputJSONPath:
+		printer, err := printers.NewJSONPathPrinter(p.format.Template)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing jsonpath %s: %w", p.format.Template, err)
+		}
+		printer.AllowMissingKeys(true)
+		return printer, nil
+	case OutputGoTemplate:
+		printer, err := printers.NewGoTemplatePrinter([]byte(p.format.Template))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing template %s: %w", p.format.Template, err)
+		}
+		return printer, nil
+	case OutputCustomColumns:
+		decoder := scheme.Codecs.UniversalDecoder()
+		var printer *get.CustomColumnsPrinter
+		var err error
+		if p.format.fromFile {
+			printer, err = get.NewCustomColumnsPrinterFromTemplate(strings.NewReader(p.format.Template), decoder)
+		} else {
+			printer, err = get.NewCustomColumnsPrinterFromSpec(p.format.Template, decoder, false)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing custom columns %s: %w", p.format.Template, err)
+		}
+		return printer, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", p.format.Name)
+	}
+}
+
+// annotateCluster returns a copy of the item with the cluster annotation set,
+// which keeps the object valid for kubectl apply and friends
+func annotateCluster(item ItemWithCluster) unstructured.Unstructured {
+	obj := *item.Item.DeepCopy()
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[ClusterAnnotation] = item.Cluster
+	obj.SetAnnotations(annotations)
+	return obj
+}
+
+// withClusterField returns a copy of the item with a top-level cluster field,
+// so templates can refer to .cluster
+func withClusterField(item ItemWithCluster) unstructured.Unstructured {
+	obj := *item.Item.DeepCopy()
+	obj.Object[clusterField] = item.Cluster
+	return obj
+}
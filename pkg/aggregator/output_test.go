@@ -0,0 +1,230 @@
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// outputTestResults returns pods and a deployment from two clusters
+func outputTestResults() *executor.AggregatedResults {
+	pod := func(name, node string) unstructured.Unstructured {
+		return unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+			"spec":       map[string]interface{}{"nodeName": node},
+		}}
+	}
+
+	return &executor.AggregatedResults{
+		Results: []executor.ClusterResult{
+			{ClusterName: "cluster2", Success: true, Items: []unstructured.Unstructured{pod("nginx-2", "node-b")}},
+			{ClusterName: "cluster1", Success: true, Items: []unstructured.Unstructured{pod("nginx-1", "node-a")}},
+			{ClusterName: "cluster3", Success: false},
+		},
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	templateFile := filepath.Join(t.TempDir(), "template.txt")
+	if err := os.WriteFile(templateFile, []byte("{.items[*].cluster}"), 0o600); err != nil {
+		t.Fatalf("failed to write template: %v", err)
+	}
+
+	tests := []struct {
+		value    string
+		expected OutputFormat
+		wantErr  bool
+	}{
+		{value: "", expected: OutputFormat{Name: OutputTable}},
+		{value: "wide", expected: OutputFormat{Name: OutputWide}},
+		{value: "json", expected: OutputFormat{Name: OutputJSON}},
+		{value: "jsonpath={.items[*].cluster}", expected: OutputFormat{Name: OutputJSONPath, Template: "{.items[*].cluster}"}},
+		{value: "custom-columns=A:.cluster,B:.metadata.name", expected: OutputFormat{Name: OutputCustomColumns, Template: "A:.cluster,B:.metadata.name"}},
+		{value: "jsonpath-file=" + templateFile, expected: OutputFormat{Name: OutputJSONPath, Template: "{.items[*].cluster}", fromFile: true}},
+		{value: "jsonpath", wantErr: true},
+		{value: "json=foo", wantErr: true},
+		{value: "table", wantErr: true},
+		{value: "yaml-file=x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseOutputFormat(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseOutputFormat(%q): expected error", tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseOutputFormat(%q): unexpected error: %v", tt.value, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseOutputFormat(%q): expected %+v, got %+v", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestObjectPrinter_JSONList(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer, err := NewObjectPrinter(buf, OutputFormat{Name: OutputJSON})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := printer.PrintGetResults(outputTestResults(), "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	list := unstructured.UnstructuredList{}
+	if err := json.Unmarshal(buf.Bytes(), &list.Object); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+	if list.Object["kind"] != "List" || list.Object["apiVersion"] != "v1" {
+		t.Errorf("expected a v1 List, got %v/%v", list.Object["apiVersion"], list.Object["kind"])
+	}
+
+	items, _, _ := unstructured.NestedSlice(list.Object, "items")
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(items))
+	}
+
+	// Items are sorted by cluster and annotated with it
+	for i, cluster := range []string{"cluster1", "cluster2"} {
+		item := unstructured.Unstructured{Object: items[i].(map[string]interface{})}
+		if got := item.GetAnnotations()[ClusterAnnotation]; got != cluster {
+			t.Errorf("item %d: expected cluster annotation %s, got %q", i, cluster, got)
+		}
+		if _, found := item.Object[clusterField]; found {
+			t.Errorf("item %d: JSON output must not contain the cluster pseudo-field", i)
+		}
+	}
+}
+
+func TestObjectPrinter_YAML(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer, _ := NewObjectPrinter(buf, OutputFormat{Name: OutputYAML})
+
+	if err := printer.PrintGetResults(outputTestResults(), "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var list map[string]interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &list); err != nil {
+		t.Fatalf("output is not valid YAML: %v", err)
+	}
+	if !strings.Contains(buf.String(), ClusterAnnotation+": cluster1") {
+		t.Errorf("expected cluster annotation in YAML output:\n%s", buf.String())
+	}
+}
+
+func TestObjectPrinter_TemplateFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   OutputFormat
+		expected string
+	}{
+		{
+			name:     "name",
+			format:   OutputFormat{Name: OutputName},
+			expected: "cluster1/pod/nginx-1\ncluster2/pod/nginx-2\n",
+		},
+		{
+			name:     "jsonpath",
+			format:   OutputFormat{Name: OutputJSONPath, Template: `{range .items[*]}{.cluster} {.metadata.name}{"\n"}{end}`},
+			expected: "cluster1 nginx-1\ncluster2 nginx-2\n",
+		},
+		{
+			name:     "go-template",
+			format:   OutputFormat{Name: OutputGoTemplate, Template: `{{range .items}}{{.cluster}}/{{.metadata.name}} {{end}}`},
+			expected: "cluster1/nginx-1 cluster2/nginx-2 ",
+		},
+		{
+			name:     "custom-columns",
+			format:   OutputFormat{Name: OutputCustomColumns, Template: "CLUSTER:.cluster,NAME:.metadata.name,NODE:.spec.nodeName"},
+			expected: "CLUSTER    NAME      NODE\ncluster1   nginx-1   node-a\ncluster2   nginx-2   node-b\n",
+		},
+		{
+			name:     "custom-columns file",
+			format:   OutputFormat{Name: OutputCustomColumns, Template: "CLUSTER NAME\n.cluster .metadata.name\n", fromFile: true},
+			expected: "CLUSTER    NAME\ncluster1   nginx-1\ncluster2   nginx-2\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			printer, err := NewObjectPrinter(buf, tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := printer.PrintGetResults(outputTestResults(), "pods"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("expected:\n%q\ngot:\n%q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestAggregateGetResults_Wide(t *testing.T) {
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+	if err := agg.SetOptions(TableOptions{Wide: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := agg.AggregateGetResults(outputTestResults(), "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got:\n%s", buf.String())
+	}
+
+	header := strings.Fields(lines[0])
+	if header[len(header)-2] != "IP" || header[len(header)-1] != "NODE" {
+		t.Errorf("expected wide columns at the end of the header, got %v", header)
+	}
+
+	// Wide columns line up across rows
+	nodeColumn := strings.Index(lines[0], "NODE")
+	if strings.Index(lines[1], "node-a") != nodeColumn || strings.Index(lines[2], "node-b") != nodeColumn {
+		t.Errorf("expected NODE column aligned, got:\n%s", buf.String())
+	}
+}
+
+func TestObjectPrinter_SortBy(t *testing.T) {
+	buf := &bytes.Buffer{}
+	printer, _ := NewObjectPrinter(buf, OutputFormat{Name: OutputName})
+	if err := printer.SetSortBy(".spec.nodeName"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results := outputTestResults()
+	results.Results[0].Items[0].Object["spec"] = map[string]interface{}{"nodeName": "node-0"}
+
+	if err := printer.PrintGetResults(results, "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "cluster2/pod/nginx-2\ncluster1/pod/nginx-1\n"
+	if buf.String() != expected {
+		t.Errorf("expected:\n%q\ngot:\n%q", expected, buf.String())
+	}
+
+	if err := printer.SetSortBy("{.spec"); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
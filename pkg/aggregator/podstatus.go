@@ -0,0 +1,169 @@
+package aggregator
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// nodeUnreachablePodReason is the status reason of pods on a node that stopped reporting
+const nodeUnreachablePodReason = "NodeLost"
+
+// podColumns holds the READY, STATUS and RESTARTS values of a pod
+type podColumns struct {
+	ready    string
+	status   string
+	restarts string
+}
+
+// podStatusColumns computes READY, STATUS and RESTARTS the way kubectl's pod
+// printer does, so crash-looping, initializing and terminating pods show their
+// real state rather than their phase
+func podStatusColumns(obj unstructured.Unstructured, now time.Time) podColumns {
+	pod := &corev1.Pod{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, pod); err != nil {
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		return podColumns{ready: "0/0", status: phase, restarts: "0"}
+	}
+
+	restarts := 0
+	restartableInitContainerRestarts := 0
+	totalContainers := len(pod.Spec.Containers)
+	readyContainers := 0
+	var lastRestartDate, lastRestartableInitContainerRestartDate time.Time
+
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Reason == corev1.PodReasonSchedulingGated {
+			reason = corev1.PodReasonSchedulingGated
+		}
+	}
+
+	// Sidecars (restartable init containers) count as regular containers
+	initContainers := make(map[string]*corev1.Container)
+	for i := range pod.Spec.InitContainers {
+		initContainers[pod.Spec.InitContainers[i].Name] = &pod.Spec.InitContainers[i]
+		if isRestartableInitContainer(&pod.Spec.InitContainers[i]) {
+			totalContainers++
+		}
+	}
+
+	initializing := false
+	for i, container := range pod.Status.InitContainerStatuses {
+		restarts += int(container.RestartCount)
+		lastRestartDate = laterTermination(lastRestartDate, container)
+		restartable := isRestartableInitContainer(initContainers[container.Name])
+		if restartable {
+			restartableInitContainerRestarts += int(container.RestartCount)
+			lastRestartableInitContainerRestartDate = laterTermination(lastRestartableInitContainerRestartDate, container)
+		}
+
+		switch {
+		case container.State.Terminated != nil && container.State.Terminated.ExitCode == 0:
+			continue
+		case restartable && container.Started != nil && *container.Started:
+			if container.Ready {
+				readyContainers++
+			}
+			continue
+		case container.State.Terminated != nil:
+			// Initialization failed
+			switch {
+			case container.State.Terminated.Reason != "":
+				reason = "Init:" + container.State.Terminated.Reason
+			case container.State.Terminated.Signal != 0:
+				reason = fmt.Sprintf("Init:Signal:%d", container.State.Terminated.Signal)
+			default:
+				reason = fmt.Sprintf("Init:ExitCode:%d", container.State.Terminated.ExitCode)
+			}
+		case container.State.Waiting != nil && container.State.Waiting.Reason != "" && container.State.Waiting.Reason != "PodInitializing":
+			reason = "Init:" + container.State.Waiting.Reason
+		default:
+			reason = fmt.Sprintf("Init:%d/%d", i, len(pod.Spec.InitContainers))
+		}
+		initializing = true
+		break
+	}
+
+	if !initializing || podConditionTrue(pod, corev1.PodInitialized) {
+		restarts = restartableInitContainerRestarts
+		lastRestartDate = lastRestartableInitContainerRestartDate
+		hasRunning := false
+		for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+			container := pod.Status.ContainerStatuses[i]
+
+			restarts += int(container.RestartCount)
+			lastRestartDate = laterTermination(lastRestartDate, container)
+
+			switch {
+			case container.State.Waiting != nil && container.State.Waiting.Reason != "":
+				reason = container.State.Waiting.Reason
+			case container.State.Terminated != nil && container.State.Terminated.Reason != "":
+				reason = container.State.Terminated.Reason
+			case container.State.Terminated != nil && container.State.Terminated.Signal != 0:
+				reason = fmt.Sprintf("Signal:%d", container.State.Terminated.Signal)
+			case container.State.Terminated != nil:
+				reason = fmt.Sprintf("ExitCode:%d", container.State.Terminated.ExitCode)
+			case container.Ready && container.State.Running != nil:
+				hasRunning = true
+				readyContainers++
+			}
+		}
+
+		// A pod with a completed container is still running while another one runs
+		if reason == "Completed" && hasRunning {
+			if podConditionTrue(pod, corev1.PodReady) {
+				reason = "Running"
+			} else {
+				reason = "NotReady"
+			}
+		}
+	}
+
+	if pod.DeletionTimestamp != nil && pod.Status.Reason == nodeUnreachablePodReason {
+		reason = "Unknown"
+	} else if pod.DeletionTimestamp != nil && pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+		reason = "Terminating"
+	}
+
+	restartsStr := fmt.Sprint(restarts)
+	if restarts != 0 && !lastRestartDate.IsZero() {
+		restartsStr = fmt.Sprintf("%d (%s ago)", restarts, formatDuration(now.Sub(lastRestartDate)))
+	}
+
+	return podColumns{
+		ready:    fmt.Sprintf("%d/%d", readyContainers, totalContainers),
+		status:   reason,
+		restarts: restartsStr,
+	}
+}
+
+// isRestartableInitContainer reports whether an init container is a sidecar
+func isRestartableInitContainer(container *corev1.Container) bool {
+	return container != nil && container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways
+}
+
+// podConditionTrue reports whether the pod has the condition with status True
+func podConditionTrue(pod *corev1.Pod, conditionType corev1.PodConditionType) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// laterTermination returns the later of last and the container's last termination time
+func laterTermination(last time.Time, container corev1.ContainerStatus) time.Time {
+	if terminated := container.LastTerminationState.Terminated; terminated != nil && terminated.FinishedAt.After(last) {
+		return terminated.FinishedAt.Time
+	}
+	return last
+}
@@ -0,0 +1,195 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestPodStatusColumns(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	always := corev1.ContainerRestartPolicyAlways
+	started := true
+
+	containers := func(names ...string) []corev1.Container {
+		var result []corev1.Container
+		for _, name := range names {
+			result = append(result, corev1.Container{Name: name})
+		}
+		return result
+	}
+	running := corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	waiting := func(reason string) corev1.ContainerState {
+		return corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason}}
+	}
+	terminated := func(reason string, exitCode int32) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: reason, ExitCode: exitCode}}
+	}
+	restartedAt := func(ago time.Duration) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{FinishedAt: metav1.NewTime(now.Add(-ago))}}
+	}
+	deleted := metav1.NewTime(now)
+
+	tests := []struct {
+		name     string
+		pod      corev1.Pod
+		expected podColumns
+	}{
+		{
+			name: "running",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{Containers: containers("app", "proxy")},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", Ready: true, State: running},
+					{Name: "proxy", Ready: true, State: running},
+				}},
+			},
+			expected: podColumns{ready: "2/2", status: "Running", restarts: "0"},
+		},
+		{
+			name: "crash loop with restart age",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{Containers: containers("app")},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", State: waiting("CrashLoopBackOff"), RestartCount: 5, LastTerminationState: restartedAt(2 * time.Minute)},
+				}},
+			},
+			expected: podColumns{ready: "0/1", status: "CrashLoopBackOff", restarts: "5 (2m ago)"},
+		},
+		{
+			name: "image pull backoff",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{Containers: containers("app")},
+				Status: corev1.PodStatus{Phase: corev1.PodPending, ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", State: waiting("ImagePullBackOff")},
+				}},
+			},
+			expected: podColumns{ready: "0/1", status: "ImagePullBackOff", restarts: "0"},
+		},
+		{
+			name: "initializing",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{InitContainers: containers("a", "b", "c"), Containers: containers("app")},
+				Status: corev1.PodStatus{Phase: corev1.PodPending, InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "a", State: terminated("Completed", 0)},
+					{Name: "b", State: running},
+					{Name: "c", State: waiting("PodInitializing")},
+				}},
+			},
+			expected: podColumns{ready: "0/1", status: "Init:1/3", restarts: "0"},
+		},
+		{
+			name: "init container crash loop",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{InitContainers: containers("migrate"), Containers: containers("app")},
+				Status: corev1.PodStatus{Phase: corev1.PodPending, InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "migrate", State: waiting("CrashLoopBackOff"), RestartCount: 3},
+				}},
+			},
+			expected: podColumns{ready: "0/1", status: "Init:CrashLoopBackOff", restarts: "3"},
+		},
+		{
+			name: "init container exit code",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{InitContainers: containers("migrate"), Containers: containers("app")},
+				Status: corev1.PodStatus{Phase: corev1.PodPending, InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: "migrate", State: terminated("", 2)},
+				}},
+			},
+			expected: podColumns{ready: "0/1", status: "Init:ExitCode:2", restarts: "0"},
+		},
+		{
+			name: "sidecar counts as a container",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{{Name: "mesh", RestartPolicy: &always}},
+					Containers:     containers("app"),
+				},
+				Status: corev1.PodStatus{
+					Phase:                 corev1.PodRunning,
+					Conditions:            []corev1.PodCondition{{Type: corev1.PodInitialized, Status: corev1.ConditionTrue}},
+					InitContainerStatuses: []corev1.ContainerStatus{{Name: "mesh", Ready: true, Started: &started, State: running, RestartCount: 1}},
+					ContainerStatuses:     []corev1.ContainerStatus{{Name: "app", Ready: true, State: running}},
+				},
+			},
+			expected: podColumns{ready: "2/2", status: "Running", restarts: "1"},
+		},
+		{
+			name: "completed",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{Containers: containers("job")},
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded, ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "job", State: terminated("Completed", 0)},
+				}},
+			},
+			expected: podColumns{ready: "0/1", status: "Completed", restarts: "0"},
+		},
+		{
+			name: "completed container next to a running one",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{Containers: containers("app", "setup")},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", Ready: true, State: running},
+					{Name: "setup", State: terminated("Completed", 0)},
+				}},
+			},
+			expected: podColumns{ready: "1/2", status: "NotReady", restarts: "0"},
+		},
+		{
+			name: "evicted",
+			pod: corev1.Pod{
+				Spec:   corev1.PodSpec{Containers: containers("app")},
+				Status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"},
+			},
+			expected: podColumns{ready: "0/1", status: "Evicted", restarts: "0"},
+		},
+		{
+			name: "terminating",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted},
+				Spec:       corev1.PodSpec{Containers: containers("app")},
+				Status: corev1.PodStatus{Phase: corev1.PodRunning, ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "app", Ready: true, State: running},
+				}},
+			},
+			expected: podColumns{ready: "1/1", status: "Terminating", restarts: "0"},
+		},
+		{
+			name: "node lost",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionTimestamp: &deleted},
+				Spec:       corev1.PodSpec{Containers: containers("app")},
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning, Reason: "NodeLost"},
+			},
+			expected: podColumns{ready: "0/1", status: "Unknown", restarts: "0"},
+		},
+		{
+			name: "scheduling gated",
+			pod: corev1.Pod{
+				Spec: corev1.PodSpec{Containers: containers("app")},
+				Status: corev1.PodStatus{Phase: corev1.PodPending, Conditions: []corev1.PodCondition{
+					{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonSchedulingGated},
+				}},
+			},
+			expected: podColumns{ready: "0/1", status: "SchedulingGated", restarts: "0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&tt.pod)
+			if err != nil {
+				t.Fatalf("failed to convert pod: %v", err)
+			}
+
+			got := podStatusColumns(unstructured.Unstructured{Object: object}, now)
+			if got != tt.expected {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
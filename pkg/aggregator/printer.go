@@ -0,0 +1,122 @@
+package aggregator
+
+import (
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Printer renders the kind-specific columns of a table. The CLUSTER,
+// NAMESPACE and NAME columns are added by the aggregator.
+type Printer interface {
+	// Headers returns the names of the columns printed after NAME. With wide
+	// set, the columns of -o wide are included.
+	Headers(wide bool) []string
+
+	// Row returns a value per header for an object
+	Row(obj unstructured.Unstructured, wide bool) []string
+}
+
+// ColumnPrinter is a Printer built from column names and functions computing
+// their values. The wide columns are appended after the regular ones.
+type ColumnPrinter struct {
+	Columns     []string
+	Cells       func(obj unstructured.Unstructured) []string
+	WideColumns []string
+	WideCells   func(obj unstructured.Unstructured) []string
+}
+
+// Headers implements Printer
+func (p ColumnPrinter) Headers(wide bool) []string {
+	headers := append([]string{}, p.Columns...)
+	if wide && p.WideCells != nil {
+		headers = append(headers, p.WideColumns...)
+	}
+	return headers
+}
+
+// Row implements Printer
+func (p ColumnPrinter) Row(obj unstructured.Unstructured, wide bool) []string {
+	cells := p.Cells(obj)
+	if wide && p.WideCells != nil {
+		cells = append(cells, p.WideCells(obj)...)
+	}
+	return cells
+}
+
+// PrinterRegistry maps GroupKinds to the printers of their tables
+type PrinterRegistry struct {
+	mu       sync.RWMutex
+	printers map[schema.GroupKind]Printer
+}
+
+// NewPrinterRegistry creates an empty registry
+func NewPrinterRegistry() *PrinterRegistry {
+	return &PrinterRegistry{
+		printers: make(map[schema.GroupKind]Printer),
+	}
+}
+
+// DefaultPrinters holds the built-in printers and is used by every TableAggregator
+// and WatchPrinter unless another registry is set
+var DefaultPrinters = newBuiltinPrinters()
+
+// RegisterPrinter adds a printer to DefaultPrinters, e.g. for a custom resource
+func RegisterPrinter(gk schema.GroupKind, printer Printer) {
+	DefaultPrinters.Register(gk, printer)
+}
+
+// Register sets the printer for a GroupKind, replacing any previous one
+func (r *PrinterRegistry) Register(gk schema.GroupKind, printer Printer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.printers[gk] = printer
+}
+
+// Lookup returns the printer registered for a GroupKind
+func (r *PrinterRegistry) Lookup(gk schema.GroupKind) (Printer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	printer, ok := r.printers[gk]
+	return printer, ok
+}
+
+// printerFor returns the printer for a GroupKind, or the generic one. Objects
+// without a kind are matched by the resource type given on the command line
+// (e.g. "pods" or "pod"), compared with each registered kind.
+func (r *PrinterRegistry) printerFor(gk schema.GroupKind, resourceType string) Printer {
+	if gk.Kind != "" {
+		if printer, ok := r.Lookup(gk); ok {
+			return printer
+		}
+		return genericPrinter
+	}
+
+	// Core kinds win over kinds of the same name in other groups
+	resource := strings.ToLower(resourceType)
+	match := genericPrinter
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for registered, printer := range r.printers {
+		plural, singular := meta.UnsafeGuessKindToResource(registered.WithVersion(""))
+		if resource != plural.Resource && resource != singular.Resource {
+			continue
+		}
+		if registered.Group == "" {
+			return printer
+		}
+		match = printer
+	}
+	return match
+}
+
+// genericPrinter prints the kind and age of any other resource
+var genericPrinter Printer = ColumnPrinter{
+	Columns: []string{"KIND", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		return []string{obj.GetKind(), calculateAge(obj)}
+	},
+}
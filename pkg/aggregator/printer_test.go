@@ -0,0 +1,111 @@
+package aggregator
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// widgetPrinter is a printer for a custom resource
+var widgetPrinter = ColumnPrinter{
+	Columns: []string{"SIZE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		size, _, _ := unstructured.NestedString(obj.Object, "spec", "size")
+		return []string{size}
+	},
+	WideColumns: []string{"COLOR"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		color, _, _ := unstructured.NestedString(obj.Object, "spec", "color")
+		return []string{color}
+	},
+}
+
+func TestPrinterRegistry_PrinterFor(t *testing.T) {
+	registry := NewPrinterRegistry()
+	registry.Register(schema.GroupKind{Kind: "Pod"}, podPrinter)
+	registry.Register(schema.GroupKind{Group: "metrics.example.com", Kind: "Pod"}, widgetPrinter)
+	registry.Register(schema.GroupKind{Group: "apps", Kind: "Deployment"}, deploymentPrinter)
+
+	tests := []struct {
+		name         string
+		gk           schema.GroupKind
+		resourceType string
+		expected     []string
+	}{
+		{name: "by group kind", gk: schema.GroupKind{Group: "apps", Kind: "Deployment"}, expected: deploymentPrinter.Columns},
+		{name: "group matters", gk: schema.GroupKind{Group: "metrics.example.com", Kind: "Pod"}, expected: widgetPrinter.Columns},
+		{name: "unregistered kind", gk: schema.GroupKind{Group: "extensions", Kind: "Deployment"}, expected: []string{"KIND", "AGE"}},
+		{name: "plural resource", resourceType: "deployments", expected: deploymentPrinter.Columns},
+		{name: "singular resource", resourceType: "Deployment", expected: deploymentPrinter.Columns},
+		{name: "core group preferred", resourceType: "pods", expected: podPrinter.Columns},
+		{name: "unknown resource", resourceType: "configmaps", expected: []string{"KIND", "AGE"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := registry.printerFor(tt.gk, tt.resourceType).Headers(false)
+			if strings.Join(got, " ") != strings.Join(tt.expected, " ") {
+				t.Errorf("expected columns %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestColumnPrinter_Wide(t *testing.T) {
+	widget := unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{"size": "large", "color": "blue"},
+	}}
+
+	if got := strings.Join(widgetPrinter.Headers(true), " "); got != "SIZE COLOR" {
+		t.Errorf("expected wide headers SIZE COLOR, got %s", got)
+	}
+	if got := strings.Join(widgetPrinter.Row(widget, false), " "); got != "large" {
+		t.Errorf("expected row large, got %s", got)
+	}
+	if got := strings.Join(widgetPrinter.Row(widget, true), " "); got != "large blue" {
+		t.Errorf("expected wide row large blue, got %s", got)
+	}
+
+	// Printers without wide columns ignore -o wide
+	if got := namespacePrinter.Headers(true); len(got) != 2 {
+		t.Errorf("expected namespace headers unchanged, got %v", got)
+	}
+}
+
+func TestAggregateGetResults_RegisteredPrinter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+
+	registry := NewPrinterRegistry()
+	registry.Register(schema.GroupKind{Group: "example.com", Kind: "Widget"}, widgetPrinter)
+	agg.SetPrinters(registry)
+
+	widget := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "sprocket", "namespace": "default"},
+		"spec":       map[string]interface{}{"size": "large"},
+	}}
+
+	results := &executor.AggregatedResults{
+		Results: []executor.ClusterResult{
+			{ClusterName: "cluster1", Success: true, Items: []unstructured.Unstructured{widget}},
+		},
+	}
+
+	if err := agg.AggregateGetResults(results, "widgets"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if got := strings.Join(strings.Fields(lines[0]), " "); got != "CLUSTER NAMESPACE NAME SIZE" {
+		t.Errorf("expected widget columns, got %q", got)
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "cluster1 default sprocket large" {
+		t.Errorf("unexpected row %q", got)
+	}
+}
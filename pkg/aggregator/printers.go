@@ -0,0 +1,510 @@
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// newBuiltinPrinters returns a registry with the printers of common kinds,
+// matching the columns of kubectl get
+func newBuiltinPrinters() *PrinterRegistry {
+	r := NewPrinterRegistry()
+	r.Register(schema.GroupKind{Kind: "Pod"}, podPrinter)
+	r.Register(schema.GroupKind{Kind: "Service"}, servicePrinter)
+	r.Register(schema.GroupKind{Kind: "Node"}, nodePrinter)
+	r.Register(schema.GroupKind{Kind: "Namespace"}, namespacePrinter)
+	r.Register(schema.GroupKind{Kind: "PersistentVolumeClaim"}, pvcPrinter)
+	r.Register(schema.GroupKind{Kind: "Event"}, eventPrinter)
+	r.Register(schema.GroupKind{Group: "events.k8s.io", Kind: "Event"}, eventPrinter)
+	r.Register(schema.GroupKind{Group: "apps", Kind: "Deployment"}, deploymentPrinter)
+	r.Register(schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, statefulSetPrinter)
+	r.Register(schema.GroupKind{Group: "apps", Kind: "DaemonSet"}, daemonSetPrinter)
+	r.Register(schema.GroupKind{Group: "batch", Kind: "Job"}, jobPrinter)
+	r.Register(schema.GroupKind{Group: "batch", Kind: "CronJob"}, cronJobPrinter)
+	r.Register(schema.GroupKind{Group: "networking.k8s.io", Kind: "Ingress"}, ingressPrinter)
+	return r
+}
+
+var podPrinter = ColumnPrinter{
+	Columns: []string{"READY", "STATUS", "RESTARTS", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		pod := podStatusColumns(obj, time.Now())
+		return []string{pod.ready, pod.status, pod.restarts, calculateAge(obj)}
+	},
+	WideColumns: []string{"IP", "NODE"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		ip, _, _ := unstructured.NestedString(obj.Object, "status", "podIP")
+		node, _, _ := unstructured.NestedString(obj.Object, "spec", "nodeName")
+		return []string{valueOrNone(ip), valueOrNone(node)}
+	},
+}
+
+var servicePrinter = ColumnPrinter{
+	Columns: []string{"TYPE", "CLUSTER-IP", "EXTERNAL-IP", "PORT(S)", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		svcType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+		clusterIP, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP")
+		return []string{svcType, clusterIP, serviceExternalIP(obj), servicePorts(obj), calculateAge(obj)}
+	},
+	WideColumns: []string{"SELECTOR"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		selector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector")
+		return []string{valueOrNone(labels.SelectorFromSet(selector).String())}
+	},
+}
+
+var nodePrinter = ColumnPrinter{
+	Columns: []string{"STATUS", "ROLES", "AGE", "VERSION"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		version, _, _ := unstructured.NestedString(obj.Object, "status", "nodeInfo", "kubeletVersion")
+		return []string{nodeStatus(obj), nodeRoles(obj), calculateAge(obj), version}
+	},
+	WideColumns: []string{"INTERNAL-IP", "EXTERNAL-IP", "OS-IMAGE", "KERNEL-VERSION", "CONTAINER-RUNTIME"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		osImage, _, _ := unstructured.NestedString(obj.Object, "status", "nodeInfo", "osImage")
+		kernel, _, _ := unstructured.NestedString(obj.Object, "status", "nodeInfo", "kernelVersion")
+		containerRuntime, _, _ := unstructured.NestedString(obj.Object, "status", "nodeInfo", "containerRuntimeVersion")
+		return []string{
+			valueOrNone(nodeAddress(obj, "InternalIP")),
+			valueOrNone(nodeAddress(obj, "ExternalIP")),
+			osImage,
+			kernel,
+			containerRuntime,
+		}
+	},
+}
+
+var namespacePrinter = ColumnPrinter{
+	Columns: []string{"STATUS", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		return []string{phase, calculateAge(obj)}
+	},
+}
+
+var pvcPrinter = ColumnPrinter{
+	Columns: []string{"STATUS", "VOLUME", "CAPACITY", "ACCESS MODES", "STORAGECLASS", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		volume, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeName")
+		storageClass, _, _ := unstructured.NestedString(obj.Object, "spec", "storageClassName")
+
+		// Capacity and access modes are only known once the claim is bound
+		var capacity, accessModes string
+		if volume != "" {
+			capacity, _, _ = unstructured.NestedString(obj.Object, "status", "capacity", "storage")
+			modes, _, _ := unstructured.NestedStringSlice(obj.Object, "status", "accessModes")
+			accessModes = formatAccessModes(modes)
+		}
+		return []string{phase, volume, capacity, accessModes, storageClass, calculateAge(obj)}
+	},
+	WideColumns: []string{"VOLUMEMODE"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		mode, _, _ := unstructured.NestedString(obj.Object, "spec", "volumeMode")
+		return []string{valueOrNone(mode)}
+	},
+}
+
+// eventPrinter prints core/v1 and events.k8s.io/v1 events, which name the same fields differently
+var eventPrinter = ColumnPrinter{
+	Columns: []string{"LAST SEEN", "TYPE", "REASON", "OBJECT", "MESSAGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		eventType, _, _ := unstructured.NestedString(obj.Object, "type")
+		reason, _, _ := unstructured.NestedString(obj.Object, "reason")
+
+		message, found, _ := unstructured.NestedString(obj.Object, "message")
+		if !found {
+			message, _, _ = unstructured.NestedString(obj.Object, "note")
+		}
+
+		involved, found, _ := unstructured.NestedMap(obj.Object, "involvedObject")
+		if !found {
+			involved, _, _ = unstructured.NestedMap(obj.Object, "regarding")
+		}
+		kind, _, _ := unstructured.NestedString(involved, "kind")
+		name, _, _ := unstructured.NestedString(involved, "name")
+
+		return []string{
+			eventLastSeen(obj),
+			eventType,
+			reason,
+			strings.ToLower(kind) + "/" + name,
+			strings.TrimSpace(message),
+		}
+	},
+}
+
+var deploymentPrinter = ColumnPrinter{
+	Columns: []string{"READY", "UP-TO-DATE", "AVAILABLE", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		// READY compares with the desired replicas, so a pending scale-up shows
+		replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		readyReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		updatedReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "updatedReplicas")
+		availableReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "availableReplicas")
+		return []string{
+			fmt.Sprintf("%d/%d", readyReplicas, replicas),
+			fmt.Sprint(updatedReplicas),
+			fmt.Sprint(availableReplicas),
+			calculateAge(obj),
+		}
+	},
+	WideColumns: []string{"CONTAINERS", "IMAGES", "SELECTOR"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		return append(podTemplateColumns(obj, "spec", "template"), labelSelector(obj, "spec", "selector"))
+	},
+}
+
+var statefulSetPrinter = ColumnPrinter{
+	Columns: []string{"READY", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+		readyReplicas, _, _ := unstructured.NestedInt64(obj.Object, "status", "readyReplicas")
+		return []string{fmt.Sprintf("%d/%d", readyReplicas, replicas), calculateAge(obj)}
+	},
+	WideColumns: []string{"CONTAINERS", "IMAGES"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		return podTemplateColumns(obj, "spec", "template")
+	},
+}
+
+var daemonSetPrinter = ColumnPrinter{
+	Columns: []string{"DESIRED", "CURRENT", "READY", "UP-TO-DATE", "AVAILABLE", "NODE SELECTOR", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		cells := make([]string, 0, 7)
+		for _, field := range []string{"desiredNumberScheduled", "currentNumberScheduled", "numberReady", "updatedNumberScheduled", "numberAvailable"} {
+			value, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+			cells = append(cells, fmt.Sprint(value))
+		}
+		nodeSelector, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "spec", "nodeSelector")
+		return append(cells, valueOrNone(labels.SelectorFromSet(nodeSelector).String()), calculateAge(obj))
+	},
+	WideColumns: []string{"CONTAINERS", "IMAGES", "SELECTOR"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		return append(podTemplateColumns(obj, "spec", "template"), labelSelector(obj, "spec", "selector"))
+	},
+}
+
+var jobPrinter = ColumnPrinter{
+	Columns: []string{"STATUS", "COMPLETIONS", "DURATION", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		return []string{jobStatus(obj), jobCompletions(obj), jobDuration(obj, time.Now()), calculateAge(obj)}
+	},
+	WideColumns: []string{"CONTAINERS", "IMAGES", "SELECTOR"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		return append(podTemplateColumns(obj, "spec", "template"), labelSelector(obj, "spec", "selector"))
+	},
+}
+
+var cronJobPrinter = ColumnPrinter{
+	Columns: []string{"SCHEDULE", "TIMEZONE", "SUSPEND", "ACTIVE", "LAST SCHEDULE", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		schedule, _, _ := unstructured.NestedString(obj.Object, "spec", "schedule")
+		timeZone, _, _ := unstructured.NestedString(obj.Object, "spec", "timeZone")
+		suspend, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend")
+		active, _, _ := unstructured.NestedSlice(obj.Object, "status", "active")
+		suspended := "False"
+		if suspend {
+			suspended = "True"
+		}
+		return []string{
+			schedule,
+			valueOrNone(timeZone),
+			suspended,
+			fmt.Sprint(len(active)),
+			timeSince(obj, "status", "lastScheduleTime"),
+			calculateAge(obj),
+		}
+	},
+	WideColumns: []string{"CONTAINERS", "IMAGES", "SELECTOR"},
+	WideCells: func(obj unstructured.Unstructured) []string {
+		return append(podTemplateColumns(obj, "spec", "jobTemplate", "spec", "template"),
+			labelSelector(obj, "spec", "jobTemplate", "spec", "selector"))
+	},
+}
+
+var ingressPrinter = ColumnPrinter{
+	Columns: []string{"CLASS", "HOSTS", "ADDRESS", "PORTS", "AGE"},
+	Cells: func(obj unstructured.Unstructured) []string {
+		class, _, _ := unstructured.NestedString(obj.Object, "spec", "ingressClassName")
+
+		var hosts []string
+		rules, _, _ := unstructured.NestedSlice(obj.Object, "spec", "rules")
+		for _, r := range rules {
+			if rule, ok := r.(map[string]interface{}); ok {
+				host, _, _ := unstructured.NestedString(rule, "host")
+				hosts = append(hosts, host)
+			}
+		}
+
+		ports := "80"
+		if tls, _, _ := unstructured.NestedSlice(obj.Object, "spec", "tls"); len(tls) > 0 {
+			ports = "80, 443"
+		}
+
+		return []string{valueOrNone(class), formatHosts(hosts), loadBalancerAddresses(obj), ports, calculateAge(obj)}
+	},
+}
+
+// nodeStatus returns Ready, NotReady or Unknown from the node's Ready condition,
+// followed by SchedulingDisabled for cordoned nodes
+func nodeStatus(obj unstructured.Unstructured) string {
+	status := "Unknown"
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if !ok || condition["type"] != "Ready" {
+			continue
+		}
+		switch condition["status"] {
+		case "True":
+			status = "Ready"
+		case "False":
+			status = "NotReady"
+		}
+	}
+
+	if unschedulable, _, _ := unstructured.NestedBool(obj.Object, "spec", "unschedulable"); unschedulable {
+		status += ",SchedulingDisabled"
+	}
+	return status
+}
+
+// nodeRoles returns the roles from node-role.kubernetes.io/<role> and kubernetes.io/role labels
+func nodeRoles(obj unstructured.Unstructured) string {
+	roles := make(map[string]bool)
+	for key, value := range obj.GetLabels() {
+		switch {
+		case strings.HasPrefix(key, "node-role.kubernetes.io/"):
+			if role := strings.TrimPrefix(key, "node-role.kubernetes.io/"); role != "" {
+				roles[role] = true
+			}
+		case key == "kubernetes.io/role" && value != "":
+			roles[value] = true
+		}
+	}
+
+	names := make([]string, 0, len(roles))
+	for role := range roles {
+		names = append(names, role)
+	}
+	sort.Strings(names)
+	return valueOrNone(strings.Join(names, ","))
+}
+
+// nodeAddress returns the first address of a type (e.g. InternalIP)
+func nodeAddress(obj unstructured.Unstructured, addressType string) string {
+	addresses, _, _ := unstructured.NestedSlice(obj.Object, "status", "addresses")
+	for _, a := range addresses {
+		if address, ok := a.(map[string]interface{}); ok && address["type"] == addressType {
+			value, _, _ := unstructured.NestedString(address, "address")
+			return value
+		}
+	}
+	return ""
+}
+
+// formatAccessModes abbreviates PVC access modes the way kubectl does (e.g. "RWO,ROX")
+func formatAccessModes(modes []string) string {
+	abbreviations := map[string]string{
+		"ReadWriteOnce":    "RWO",
+		"ReadOnlyMany":     "ROX",
+		"ReadWriteMany":    "RWX",
+		"ReadWriteOncePod": "RWOP",
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, mode := range modes {
+		if short, ok := abbreviations[mode]; ok && !seen[short] {
+			seen[short] = true
+			result = append(result, short)
+		}
+	}
+	return strings.Join(result, ",")
+}
+
+// eventLastSeen returns how long ago an event was last observed
+func eventLastSeen(obj unstructured.Unstructured) string {
+	for _, path := range [][]string{
+		{"series", "lastObservedTime"},
+		{"lastTimestamp"},
+		{"deprecatedLastTimestamp"},
+		{"eventTime"},
+	} {
+		if value, found, _ := unstructured.NestedString(obj.Object, path...); found && value != "" {
+			return timeSince(obj, path...)
+		}
+	}
+	return calculateAge(obj)
+}
+
+// jobStatus returns the status of a job from its conditions
+func jobStatus(obj unstructured.Unstructured) string {
+	if obj.GetDeletionTimestamp() != nil {
+		return "Terminating"
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, conditionType := range []string{"Failed", "Complete", "FailureTarget", "SuccessCriteriaMet", "Suspended"} {
+		for _, c := range conditions {
+			if condition, ok := c.(map[string]interface{}); ok && condition["type"] == conditionType && condition["status"] == "True" {
+				return conditionType
+			}
+		}
+	}
+	return "Running"
+}
+
+// jobCompletions returns succeeded pods over the desired completions (e.g. "2/3")
+func jobCompletions(obj unstructured.Unstructured) string {
+	succeeded, _, _ := unstructured.NestedInt64(obj.Object, "status", "succeeded")
+	if completions, found, _ := unstructured.NestedInt64(obj.Object, "spec", "completions"); found {
+		return fmt.Sprintf("%d/%d", succeeded, completions)
+	}
+	if parallelism, _, _ := unstructured.NestedInt64(obj.Object, "spec", "parallelism"); parallelism > 1 {
+		return fmt.Sprintf("%d/1 of %d", succeeded, parallelism)
+	}
+	return fmt.Sprintf("%d/1", succeeded)
+}
+
+// jobDuration returns how long a job ran, or has been running
+func jobDuration(obj unstructured.Unstructured, now time.Time) string {
+	start, ok := nestedTime(obj, "status", "startTime")
+	if !ok {
+		return ""
+	}
+	if completion, ok := nestedTime(obj, "status", "completionTime"); ok {
+		return formatDuration(completion.Sub(start))
+	}
+	return formatDuration(now.Sub(start))
+}
+
+// podTemplateColumns returns the CONTAINERS and IMAGES columns of a pod template
+func podTemplateColumns(obj unstructured.Unstructured, path ...string) []string {
+	var names, images []string
+	containers, _, _ := unstructured.NestedSlice(obj.Object, append(path, "spec", "containers")...)
+	for _, c := range containers {
+		if container, ok := c.(map[string]interface{}); ok {
+			name, _, _ := unstructured.NestedString(container, "name")
+			image, _, _ := unstructured.NestedString(container, "image")
+			names = append(names, name)
+			images = append(images, image)
+		}
+	}
+	return []string{valueOrNone(strings.Join(names, ",")), valueOrNone(strings.Join(images, ","))}
+}
+
+// labelSelector formats a metav1.LabelSelector field, including match expressions
+func labelSelector(obj unstructured.Unstructured, path ...string) string {
+	raw, found, _ := unstructured.NestedMap(obj.Object, path...)
+	if !found {
+		return noneValue
+	}
+
+	selector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, selector); err != nil {
+		return noneValue
+	}
+	parsed, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return noneValue
+	}
+	return valueOrNone(parsed.String())
+}
+
+// formatHosts lists ingress hosts, "*" for rules without one, cut after four like kubectl
+func formatHosts(hosts []string) string {
+	const maxHosts = 4
+
+	var list []string
+	for i, host := range hosts {
+		if i == maxHosts {
+			list = append(list, fmt.Sprintf("+ %d more...", len(hosts)-maxHosts))
+			break
+		}
+		if host == "" {
+			host = "*"
+		}
+		list = append(list, host)
+	}
+	if len(list) == 0 {
+		return "*"
+	}
+	return strings.Join(list, ",")
+}
+
+// loadBalancerAddresses returns the IPs or hostnames in status.loadBalancer.ingress
+func loadBalancerAddresses(obj unstructured.Unstructured) string {
+	var addresses []string
+	ingress, _, _ := unstructured.NestedSlice(obj.Object, "status", "loadBalancer", "ingress")
+	for _, i := range ingress {
+		entry, ok := i.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ip, _, _ := unstructured.NestedString(entry, "ip"); ip != "" {
+			addresses = append(addresses, ip)
+		} else if hostname, _, _ := unstructured.NestedString(entry, "hostname"); hostname != "" {
+			addresses = append(addresses, hostname)
+		}
+	}
+	return strings.Join(addresses, ",")
+}
+
+// serviceExternalIP returns the external addresses of a service by type, like kubectl
+func serviceExternalIP(obj unstructured.Unstructured) string {
+	svcType, _, _ := unstructured.NestedString(obj.Object, "spec", "type")
+	externalIPs, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "externalIPs")
+
+	switch svcType {
+	case "", "ClusterIP", "NodePort":
+		return valueOrNone(strings.Join(externalIPs, ","))
+	case "LoadBalancer":
+		addresses := loadBalancerAddresses(obj)
+		if len(externalIPs) > 0 {
+			if addresses != "" {
+				return addresses + "," + strings.Join(externalIPs, ",")
+			}
+			return strings.Join(externalIPs, ",")
+		}
+		if addresses != "" {
+			return addresses
+		}
+		return "<pending>"
+	case "ExternalName":
+		externalName, _, _ := unstructured.NestedString(obj.Object, "spec", "externalName")
+		return externalName
+	}
+	return "<unknown>"
+}
+
+// nestedTime parses an RFC3339 timestamp field
+func nestedTime(obj unstructured.Unstructured, path ...string) (time.Time, bool) {
+	value, found, _ := unstructured.NestedString(obj.Object, path...)
+	if !found || value == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// timeSince formats how long ago a timestamp field was, or <none> when unset
+func timeSince(obj unstructured.Unstructured, path ...string) string {
+	t, ok := nestedTime(obj, path...)
+	if !ok {
+		return noneValue
+	}
+	return formatDuration(time.Since(t))
+}
@@ -0,0 +1,263 @@
+package aggregator
+
+import (
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestBuiltinPrinters(t *testing.T) {
+	tests := []struct {
+		name     string
+		printer  Printer
+		object   map[string]interface{}
+		wide     bool
+		expected []string
+	}{
+		{
+			name:    "ready cordoned node",
+			printer: nodePrinter,
+			object: map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": map[string]interface{}{
+					"node-role.kubernetes.io/control-plane": "",
+					"node-role.kubernetes.io/etcd":          "",
+				}},
+				"spec": map[string]interface{}{"unschedulable": true},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+					"nodeInfo":   map[string]interface{}{"kubeletVersion": "v1.30.1"},
+					"addresses":  []interface{}{map[string]interface{}{"type": "InternalIP", "address": "10.0.0.1"}},
+				},
+			},
+			wide:     true,
+			expected: []string{"Ready,SchedulingDisabled", "control-plane,etcd", "<none>", "v1.30.1", "10.0.0.1", "<none>", "", "", ""},
+		},
+		{
+			name:    "not ready node",
+			printer: nodePrinter,
+			object: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "False"}},
+				},
+			},
+			expected: []string{"NotReady", "<none>", "<none>", ""},
+		},
+		{
+			name:    "bound pvc",
+			printer: pvcPrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{"volumeName": "pv-1", "storageClassName": "standard"},
+				"status": map[string]interface{}{
+					"phase":       "Bound",
+					"capacity":    map[string]interface{}{"storage": "10Gi"},
+					"accessModes": []interface{}{"ReadWriteOnce", "ReadOnlyMany"},
+				},
+			},
+			expected: []string{"Bound", "pv-1", "10Gi", "RWO,ROX", "standard", "<none>"},
+		},
+		{
+			name:    "statefulset",
+			printer: statefulSetPrinter,
+			object: map[string]interface{}{
+				"spec":   map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{"readyReplicas": int64(2)},
+			},
+			expected: []string{"2/3", "<none>"},
+		},
+		{
+			name:    "daemonset",
+			printer: daemonSetPrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"selector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "agent"}},
+					"template": map[string]interface{}{"spec": map[string]interface{}{
+						"nodeSelector": map[string]interface{}{"kubernetes.io/os": "linux"},
+						"containers":   []interface{}{map[string]interface{}{"name": "agent", "image": "agent:1.0"}},
+					}},
+				},
+				"status": map[string]interface{}{
+					"desiredNumberScheduled": int64(3),
+					"currentNumberScheduled": int64(3),
+					"numberReady":            int64(2),
+					"updatedNumberScheduled": int64(3),
+					"numberAvailable":        int64(2),
+				},
+			},
+			wide:     true,
+			expected: []string{"3", "3", "2", "3", "2", "kubernetes.io/os=linux", "<none>", "agent", "agent:1.0", "app=agent"},
+		},
+		{
+			name:    "completed job",
+			printer: jobPrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{"completions": int64(3)},
+				"status": map[string]interface{}{
+					"succeeded":      int64(3),
+					"startTime":      "2024-01-01T00:00:00Z",
+					"completionTime": "2024-01-01T00:05:00Z",
+					"conditions":     []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}},
+				},
+			},
+			expected: []string{"Complete", "3/3", "5m", "<none>"},
+		},
+		{
+			name:    "parallel job",
+			printer: jobPrinter,
+			object: map[string]interface{}{
+				"spec":   map[string]interface{}{"parallelism": int64(4)},
+				"status": map[string]interface{}{"succeeded": int64(1)},
+			},
+			expected: []string{"Running", "1/1 of 4", "", "<none>"},
+		},
+		{
+			name:    "suspended cronjob",
+			printer: cronJobPrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"schedule": "*/5 * * * *",
+					"suspend":  true,
+				},
+				"status": map[string]interface{}{"active": []interface{}{map[string]interface{}{}}},
+			},
+			expected: []string{"*/5 * * * *", "<none>", "True", "1", "<none>", "<none>"},
+		},
+		{
+			name:    "ingress",
+			printer: ingressPrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"ingressClassName": "nginx",
+					"rules":            []interface{}{map[string]interface{}{"host": "a.example.com"}, map[string]interface{}{}},
+					"tls":              []interface{}{map[string]interface{}{}},
+				},
+				"status": map[string]interface{}{"loadBalancer": map[string]interface{}{
+					"ingress": []interface{}{map[string]interface{}{"ip": "1.2.3.4"}, map[string]interface{}{"hostname": "lb.example.com"}},
+				}},
+			},
+			expected: []string{"nginx", "a.example.com,*", "1.2.3.4,lb.example.com", "80, 443", "<none>"},
+		},
+		{
+			name:    "core event",
+			printer: eventPrinter,
+			object: map[string]interface{}{
+				"type":           "Warning",
+				"reason":         "BackOff",
+				"message":        "Back-off restarting failed container\n",
+				"involvedObject": map[string]interface{}{"kind": "Pod", "name": "nginx"},
+			},
+			expected: []string{"<none>", "Warning", "BackOff", "pod/nginx", "Back-off restarting failed container"},
+		},
+		{
+			name:    "events.k8s.io event",
+			printer: eventPrinter,
+			object: map[string]interface{}{
+				"type":      "Normal",
+				"reason":    "Scheduled",
+				"note":      "Successfully assigned default/nginx",
+				"regarding": map[string]interface{}{"kind": "Pod", "name": "nginx"},
+			},
+			expected: []string{"<none>", "Normal", "Scheduled", "pod/nginx", "Successfully assigned default/nginx"},
+		},
+		{
+			name:    "deployment scaling up",
+			printer: deploymentPrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{"replicas": int64(5)},
+				"status": map[string]interface{}{
+					"replicas":          int64(3),
+					"readyReplicas":     int64(3),
+					"updatedReplicas":   int64(3),
+					"availableReplicas": int64(3),
+				},
+			},
+			expected: []string{"3/5", "3", "3", "<none>"},
+		},
+		{
+			name:    "cluster ip service",
+			printer: servicePrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"type":      "ClusterIP",
+					"clusterIP": "10.96.0.10",
+					"ports":     []interface{}{map[string]interface{}{"port": int64(53), "protocol": "UDP"}},
+				},
+			},
+			expected: []string{"ClusterIP", "10.96.0.10", "<none>", "53/UDP", "<none>"},
+		},
+		{
+			name:    "cluster ip service with external ips",
+			printer: servicePrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"type":        "ClusterIP",
+					"clusterIP":   "10.96.0.11",
+					"externalIPs": []interface{}{"203.0.113.1", "203.0.113.2"},
+				},
+			},
+			expected: []string{"ClusterIP", "10.96.0.11", "203.0.113.1,203.0.113.2", "<none>", "<none>"},
+		},
+		{
+			name:    "load balancer service",
+			printer: servicePrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{
+					"type":        "LoadBalancer",
+					"clusterIP":   "10.96.0.12",
+					"externalIPs": []interface{}{"203.0.113.3"},
+				},
+				"status": map[string]interface{}{"loadBalancer": map[string]interface{}{
+					"ingress": []interface{}{map[string]interface{}{"ip": "198.51.100.1"}, map[string]interface{}{"hostname": "lb.example.com"}},
+				}},
+			},
+			expected: []string{"LoadBalancer", "10.96.0.12", "198.51.100.1,lb.example.com,203.0.113.3", "<none>", "<none>"},
+		},
+		{
+			name:    "pending load balancer service",
+			printer: servicePrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{"type": "LoadBalancer", "clusterIP": "10.96.0.13"},
+			},
+			expected: []string{"LoadBalancer", "10.96.0.13", "<pending>", "<none>", "<none>"},
+		},
+		{
+			name:    "external name service",
+			printer: servicePrinter,
+			object: map[string]interface{}{
+				"spec": map[string]interface{}{"type": "ExternalName", "externalName": "db.example.com"},
+			},
+			expected: []string{"ExternalName", "", "db.example.com", "<none>", "<none>"},
+		},
+		{
+			name:     "namespace",
+			printer:  namespacePrinter,
+			object:   map[string]interface{}{"status": map[string]interface{}{"phase": "Terminating"}},
+			expected: []string{"Terminating", "<none>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := unstructured.Unstructured{Object: tt.object}
+
+			headers := tt.printer.Headers(tt.wide)
+			got := tt.printer.Row(obj, tt.wide)
+			if len(got) != len(headers) {
+				t.Fatalf("expected a cell per header %v, got %q", headers, got)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFormatHosts(t *testing.T) {
+	hosts := []string{"a", "b", "", "d", "e", "f"}
+	if got := formatHosts(hosts); got != "a,b,*,d,+ 2 more..." {
+		t.Errorf("unexpected hosts %q", got)
+	}
+	if got := formatHosts(nil); got != "*" {
+		t.Errorf("expected * without rules, got %q", got)
+	}
+}
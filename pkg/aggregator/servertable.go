@@ -0,0 +1,205 @@
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Tabwriter settings used by kubectl's table printer
+const (
+	tabwriterMinWidth = 6
+	tabwriterWidth    = 4
+	tabwriterPadding  = 3
+)
+
+// mergedTable combines the server-side tables of one kind from every cluster
+type mergedTable struct {
+	columns []metav1.TableColumnDefinition
+	index   map[string]int // upper-cased column name -> position in columns
+	rows    []mergedRow
+}
+
+// mergedRow is a table row with its cells aligned to the merged columns
+type mergedRow struct {
+	ItemWithCluster
+	cells map[int]interface{}
+}
+
+// hasServerTables reports whether any cluster returned server-side tables
+func hasServerTables(results *executor.AggregatedResults) bool {
+	for _, result := range results.Results {
+		if result.Success && len(result.Tables) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// aggregateServerTables prints the server-side tables returned by each cluster,
+// one table per kind. Column definitions from all clusters are merged by name, so
+// clusters running different versions still line up, and CLUSTER is prepended.
+func (a *TableAggregator) aggregateServerTables(results *executor.AggregatedResults) error {
+	var tables []*mergedTable
+	byKind := make(map[schema.GroupKind]*mergedTable)
+
+	tableFor := func(gk schema.GroupKind) *mergedTable {
+		t, ok := byKind[gk]
+		if !ok {
+			t = &mergedTable{index: make(map[string]int)}
+			byKind[gk] = t
+			tables = append(tables, t)
+		}
+		return t
+	}
+
+	for _, result := range results.Results {
+		if !result.Success {
+			continue
+		}
+
+		covered := make(map[schema.GroupKind]bool)
+		for _, resourceTable := range result.Tables {
+			gk := resourceTable.GroupVersionKind.GroupKind()
+			covered[gk] = true
+			tableFor(gk).addTable(result.ClusterName, resourceTable, a.options.Wide)
+		}
+
+		// Resource types the cluster could not render as a table
+		for _, item := range result.Items {
+			gk := item.GroupVersionKind().GroupKind()
+			if !covered[gk] {
+				tableFor(gk).addItem(result.ClusterName, item, a.printers.printerFor(gk, gk.Kind), a.options.Wide)
+			}
+		}
+	}
+
+	printed := 0
+	for _, t := range tables {
+		if len(t.rows) == 0 {
+			continue
+		}
+		if printed > 0 {
+			fmt.Fprintln(a.writer)
+		}
+		if err := a.printMergedTable(t); err != nil {
+			return err
+		}
+		printed++
+	}
+
+	if printed == 0 {
+		fmt.Fprintln(a.writer, "No resources found")
+	}
+
+	return nil
+}
+
+// addColumn adds a column definition unless one with the same name exists
+func (t *mergedTable) addColumn(column metav1.TableColumnDefinition) int {
+	name := strings.ToUpper(column.Name)
+	if i, ok := t.index[name]; ok {
+		return i
+	}
+	t.index[name] = len(t.columns)
+	t.columns = append(t.columns, column)
+	return len(t.columns) - 1
+}
+
+// addTable merges one cluster's table into t. Unless wide is set, only default
+// (priority 0) columns are kept, matching what kubectl get prints without -o wide.
+func (t *mergedTable) addTable(cluster string, resourceTable executor.ResourceTable, wide bool) {
+	positions := make([]int, len(resourceTable.Table.ColumnDefinitions))
+	for i, column := range resourceTable.Table.ColumnDefinitions {
+		positions[i] = -1
+		if wide || column.Priority == 0 {
+			positions[i] = t.addColumn(column)
+		}
+	}
+
+	for _, row := range resourceTable.Table.Rows {
+		item := unstructured.Unstructured{Object: map[string]interface{}{}}
+		if len(row.Object.Raw) > 0 {
+			_ = json.Unmarshal(row.Object.Raw, &item.Object)
+		}
+		item.SetGroupVersionKind(resourceTable.GroupVersionKind)
+
+		cells := make(map[int]interface{})
+		for i, cell := range row.Cells {
+			if i < len(positions) && positions[i] >= 0 {
+				cells[positions[i]] = cell
+			}
+		}
+
+		t.rows = append(t.rows, mergedRow{
+			ItemWithCluster: ItemWithCluster{Item: item, Cluster: cluster},
+			cells:           cells,
+		})
+	}
+}
+
+// addItem adds a row for an item that came without a server-side table, using
+// the kind's printer. Its columns are merged with the server's by name.
+func (t *mergedTable) addItem(cluster string, item unstructured.Unstructured, printer Printer, wide bool) {
+	cells := map[int]interface{}{
+		t.addColumn(metav1.TableColumnDefinition{Name: "Name", Type: "string", Format: "name"}): item.GetName(),
+	}
+
+	values := printer.Row(item, wide)
+	for i, header := range printer.Headers(wide) {
+		if i < len(values) {
+			cells[t.addColumn(metav1.TableColumnDefinition{Name: header, Type: "string"})] = values[i]
+		}
+	}
+
+	t.rows = append(t.rows, mergedRow{
+		ItemWithCluster: ItemWithCluster{Item: item, Cluster: cluster},
+		cells:           cells,
+	})
+}
+
+// printMergedTable writes a merged table with CLUSTER and, for namespaced
+// resources, NAMESPACE columns around the server's columns
+func (a *TableAggregator) printMergedTable(t *mergedTable) error {
+	table := kindTable{}
+	for _, column := range t.columns {
+		table.headers = append(table.headers, strings.ToUpper(column.Name))
+	}
+
+	for _, row := range t.rows {
+		cells := make([]string, 0, len(t.columns))
+		for c, column := range t.columns {
+			if column.Format == "name" || column.Name == "Name" {
+				cells = append(cells, a.displayName(row.Item))
+				continue
+			}
+			cells = append(cells, formatCell(row.cells[c]))
+		}
+		table.rows = append(table.rows, kindRow{ItemWithCluster: row.ItemWithCluster, cells: cells})
+	}
+
+	return a.printTable(table)
+}
+
+// formatCell renders a table cell the way kubectl does: nil cells are empty and
+// strings are cut at the first line break
+func formatCell(cell interface{}) string {
+	if cell == nil {
+		return ""
+	}
+
+	value, ok := cell.(string)
+	if !ok {
+		return fmt.Sprint(cell)
+	}
+
+	if i := strings.IndexAny(value, "\f\n\r"); i >= 0 {
+		return value[:i] + "..."
+	}
+	return strings.ReplaceAll(value, "\t", " ")
+}
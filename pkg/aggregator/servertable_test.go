@@ -0,0 +1,250 @@
+package aggregator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// tableRow builds a server-side table row for a namespaced object
+func tableRow(namespace, name string, cells ...interface{}) metav1.TableRow {
+	object, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "meta.k8s.io/v1",
+		"kind":       "PartialObjectMetadata",
+		"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+	})
+	return metav1.TableRow{
+		Cells:  append([]interface{}{name}, cells...),
+		Object: runtime.RawExtension{Raw: object},
+	}
+}
+
+func TestAggregateGetResults_ServerTables(t *testing.T) {
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+
+	widgetGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+
+	results := &executor.AggregatedResults{
+		Results: []executor.ClusterResult{
+			{
+				ClusterName: "cluster2",
+				Success:     true,
+				Tables: []executor.ResourceTable{{
+					GroupVersionKind: widgetGVK,
+					Table: &metav1.Table{
+						ColumnDefinitions: []metav1.TableColumnDefinition{
+							{Name: "Name", Type: "string", Format: "name"},
+							{Name: "Size", Type: "integer"},
+							{Name: "Color", Type: "string"},
+							{Name: "Debug", Type: "string", Priority: 1},
+						},
+						Rows: []metav1.TableRow{tableRow("default", "gizmo", int64(3), "blue", "hidden-value")},
+					},
+				}},
+			},
+			{
+				ClusterName: "cluster1",
+				Success:     true,
+				Tables: []executor.ResourceTable{{
+					GroupVersionKind: widgetGVK,
+					Table: &metav1.Table{
+						// An older CRD version without the Color column
+						ColumnDefinitions: []metav1.TableColumnDefinition{
+							{Name: "Name", Type: "string", Format: "name"},
+							{Name: "Size", Type: "integer"},
+						},
+						Rows: []metav1.TableRow{tableRow("default", "sprocket", float64(7))},
+					},
+				}},
+			},
+			{
+				ClusterName: "cluster3",
+				Success:     false,
+			},
+		},
+	}
+
+	if err := agg.AggregateGetResults(results, "widgets"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got:\n%s", buf.String())
+	}
+
+	header := strings.Fields(lines[0])
+	expectedHeader := []string{"CLUSTER", "NAMESPACE", "NAME", "SIZE", "COLOR"}
+	if strings.Join(header, " ") != strings.Join(expectedHeader, " ") {
+		t.Errorf("expected header %v, got %v", expectedHeader, header)
+	}
+
+	// Rows are sorted by cluster
+	if fields := strings.Fields(lines[1]); len(fields) != 4 || fields[0] != "cluster1" || fields[2] != "sprocket" || fields[3] != "7" {
+		t.Errorf("unexpected first row: %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); len(fields) != 5 || fields[0] != "cluster2" || fields[4] != "blue" {
+		t.Errorf("unexpected second row: %q", lines[2])
+	}
+
+	if strings.Contains(buf.String(), "hidden-value") {
+		t.Error("expected priority columns to be omitted")
+	}
+}
+
+func TestAggregateGetResults_ServerTablesWithItemFallback(t *testing.T) {
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+
+	podGVK := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+
+	pod := unstructured.Unstructured{}
+	pod.SetGroupVersionKind(podGVK)
+	pod.SetName("legacy-pod")
+	pod.SetNamespace("default")
+
+	results := &executor.AggregatedResults{
+		Results: []executor.ClusterResult{
+			{
+				ClusterName: "cluster1",
+				Success:     true,
+				Tables: []executor.ResourceTable{{
+					GroupVersionKind: podGVK,
+					Table: &metav1.Table{
+						ColumnDefinitions: []metav1.TableColumnDefinition{
+							{Name: "Name", Type: "string", Format: "name"},
+							{Name: "Status", Type: "string"},
+							{Name: "Age", Type: "string"},
+						},
+						Rows: []metav1.TableRow{tableRow("default", "nginx", "Running", "5m")},
+					},
+				}},
+			},
+			{
+				// A cluster that could not serve tables returned plain items
+				ClusterName: "cluster2",
+				Success:     true,
+				Items:       []unstructured.Unstructured{pod},
+			},
+		},
+	}
+
+	if err := agg.AggregateGetResults(results, "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"nginx", "Running", "5m", "cluster2", "legacy-pod"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	if strings.Count(output, "CLUSTER") != 1 {
+		t.Errorf("expected a single merged table, got:\n%s", output)
+	}
+}
+
+func TestFormatCell(t *testing.T) {
+	tests := []struct {
+		cell     interface{}
+		expected string
+	}{
+		{cell: nil, expected: ""},
+		{cell: "Running", expected: "Running"},
+		{cell: float64(3), expected: "3"},
+		{cell: int64(12), expected: "12"},
+		{cell: true, expected: "true"},
+		{cell: "line1\nline2", expected: "line1..."},
+	}
+
+	for _, tt := range tests {
+		if got := formatCell(tt.cell); got != tt.expected {
+			t.Errorf("formatCell(%v): expected %q, got %q", tt.cell, tt.expected, got)
+		}
+	}
+}
+
+func TestAggregateGetResults_ServerTablesWide(t *testing.T) {
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+	if err := agg.SetOptions(TableOptions{Wide: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results := &executor.AggregatedResults{
+		Results: []executor.ClusterResult{{
+			ClusterName: "cluster1",
+			Success:     true,
+			Tables: []executor.ResourceTable{{
+				GroupVersionKind: schema.GroupVersionKind{Version: "v1", Kind: "Pod"},
+				Table: &metav1.Table{
+					ColumnDefinitions: []metav1.TableColumnDefinition{
+						{Name: "Name", Type: "string", Format: "name"},
+						{Name: "Node", Type: "string", Priority: 1},
+					},
+					Rows: []metav1.TableRow{tableRow("default", "nginx", "node-a")},
+				},
+			}},
+		}},
+	}
+
+	if err := agg.AggregateGetResults(results, "pods"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "NODE") || !strings.Contains(buf.String(), "node-a") {
+		t.Errorf("expected priority columns with -o wide, got:\n%s", buf.String())
+	}
+}
+
+func TestAggregateGetResults_ServerTablesMergeConfigMaps(t *testing.T) {
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+	if err := agg.SetOptions(TableOptions{Merge: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	configMapGVK := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	configMapTable := func(data int64) []executor.ResourceTable {
+		return []executor.ResourceTable{{
+			GroupVersionKind: configMapGVK,
+			Table: &metav1.Table{
+				ColumnDefinitions: []metav1.TableColumnDefinition{
+					{Name: "Name", Type: "string", Format: "name"},
+					{Name: "Data", Type: "integer"},
+				},
+				Rows: []metav1.TableRow{tableRow("default", "settings", data)},
+			},
+		}}
+	}
+
+	results := &executor.AggregatedResults{
+		Results: []executor.ClusterResult{
+			{ClusterName: "prod-a", Success: true, Tables: configMapTable(2)},
+			{ClusterName: "prod-b", Success: true, Tables: configMapTable(2)},
+			{ClusterName: "prod-c", Success: true, Tables: configMapTable(3)},
+		},
+	}
+
+	if err := agg.AggregateGetResults(results, "configmaps"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected header and a row, got:\n%s", buf.String())
+	}
+	// Each copy holds its own keys: DATA is listed per value, never summed to 7
+	if fields := strings.Fields(lines[1]); fields[len(fields)-1] != "2:2,3:1" {
+		t.Errorf("expected DATA to list 2:2,3:1, got %q", lines[1])
+	}
+}
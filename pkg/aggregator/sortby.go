@@ -0,0 +1,117 @@
+package aggregator
+
+import (
+	"fmt"
+	"sort"
+
+	"k8s.io/client-go/util/jsonpath"
+	"k8s.io/kubectl/pkg/cmd/get"
+)
+
+// fieldSorter extracts the sort key of an item from a jsonpath expression
+type fieldSorter struct {
+	parser *jsonpath.JSONPath
+}
+
+// newFieldSorter parses a --sort-by expression. Like kubectl, the braces are
+// optional (.metadata.name and {.metadata.name} are equivalent).
+func newFieldSorter(expr string) (*fieldSorter, error) {
+	relaxed, err := get.RelaxedJSONPathExpression(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --sort-by expression %q: %w", expr, err)
+	}
+
+	parser := jsonpath.New("sort-by").AllowMissingKeys(true)
+	if err := parser.Parse(relaxed); err != nil {
+		return nil, fmt.Errorf("invalid --sort-by expression %q: %w", expr, err)
+	}
+	return &fieldSorter{parser: parser}, nil
+}
+
+// key returns the first value the expression selects on the item, or nil.
+// The item's cluster is available as .cluster.
+func (s *fieldSorter) key(item ItemWithCluster) (interface{}, error) {
+	obj := make(map[string]interface{}, len(item.Item.Object)+1)
+	for k, v := range item.Item.Object {
+		obj[k] = v
+	}
+	obj[clusterField] = item.Cluster
+
+	results, err := s.parser.FindResults(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate --sort-by on %s: %w", item.Item.GetName(), err)
+	}
+	if len(results) == 0 || len(results[0]) == 0 {
+		return nil, nil
+	}
+	return results[0][0].Interface(), nil
+}
+
+// sortByField stably sorts elems by the value expr selects on each element's item.
+// Elements missing the field sort first.
+func sortByField[T any](elems []T, item func(T) ItemWithCluster, expr string) error {
+	sorter, err := newFieldSorter(expr)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]interface{}, len(elems))
+	for i, elem := range elems {
+		if keys[i], err = sorter.key(item(elem)); err != nil {
+			return err
+		}
+	}
+
+	order := make([]int, len(elems))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return lessValue(keys[order[i]], keys[order[j]])
+	})
+
+	sorted := make([]T, len(elems))
+	for i, o := range order {
+		sorted[i] = elems[o]
+	}
+	copy(elems, sorted)
+	return nil
+}
+
+// lessValue compares two sort keys. Numbers compare numerically, strings
+// (including RFC3339 timestamps) lexically, and missing values come first.
+func lessValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b != nil
+	}
+
+	numA, okA := toFloat(a)
+	numB, okB := toFloat(b)
+	if okA && okB {
+		return numA < numB
+	}
+
+	strA, okA := a.(string)
+	strB, okB := b.(string)
+	if okA && okB {
+		return strA < strB
+	}
+
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// toFloat converts the numeric types found in unstructured objects
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
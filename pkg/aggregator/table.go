@@ -18,6 +18,18 @@ const (
 // TableAggregator formats multi-cluster results as a kubectl-style table
 type TableAggregator struct {
 	writer io.Writer
+
+	// showKind prefixes names with their kind (e.g. pod/nginx), as kubectl does
+	// when several resource types are listed at once
+	showKind bool
+
+	options TableOptions
+
+	// printers renders the columns of each kind
+	printers *PrinterRegistry
+
+	// clusters lists the clusters that answered, for the merged view
+	clusters []string
 }
 
 // ItemWithCluster represents a Kubernetes resource with its cluster information
@@ -26,50 +38,26 @@ type ItemWithCluster struct {
 	Cluster string
 }
 
-// podColumnWidths holds column widths for pod table
-type podColumnWidths struct {
-	namespace int
-	name      int
-	cluster   int
-	ready     int
-	status    int
-	restarts  int
-}
-
-// deploymentColumnWidths holds column widths for deployment table
-type deploymentColumnWidths struct {
-	namespace int
-	name      int
-	cluster   int
-	ready     int
-	upToDate  int
-	available int
-}
-
-// serviceColumnWidths holds column widths for service table
-type serviceColumnWidths struct {
-	namespace  int
-	name       int
-	cluster    int
-	svcType    int
-	clusterIP  int
-	externalIP int
-	ports      int
+// NewTableAggregator creates a new table aggregator
+func NewTableAggregator(writer io.Writer) *TableAggregator {
+	return &TableAggregator{
+		writer:   writer,
+		printers: DefaultPrinters,
+	}
 }
 
-// genericColumnWidths holds column widths for generic table
-type genericColumnWidths struct {
-	namespace int
-	name      int
-	cluster   int
-	kind      int
+// SetPrinters replaces the registry of kind-specific printers
+func (a *TableAggregator) SetPrinters(printers *PrinterRegistry) {
+	a.printers = printers
 }
 
-// NewTableAggregator creates a new table aggregator
-func NewTableAggregator(writer io.Writer) *TableAggregator {
-	return &TableAggregator{
-		writer: writer,
+// SetOptions sets the sorting, grouping and column options
+func (a *TableAggregator) SetOptions(options TableOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
 	}
+	a.options = options
+	return nil
 }
 
 // AggregateGetResults aggregates and formats get results across clusters
@@ -77,10 +65,12 @@ func (a *TableAggregator) AggregateGetResults(results *executor.AggregatedResult
 	// Collect all items with cluster information
 	var allItems []ItemWithCluster
 
+	a.clusters = nil
 	for _, result := range results.Results {
 		if !result.Success {
 			continue
 		}
+		a.clusters = append(a.clusters, result.ClusterName)
 		for _, item := range result.Items {
 			allItems = append(allItems, ItemWithCluster{
 				Item:    item,
@@ -89,448 +79,152 @@ func (a *TableAggregator) AggregateGetResults(results *executor.AggregatedResult
 		}
 	}
 
+	a.showKind = isMultiResource(resourceType)
+
+	// Prefer the API servers' own table layout when they provided one
+	if hasServerTables(results) {
+		return a.aggregateServerTables(results)
+	}
+
 	if len(allItems) == 0 {
 		fmt.Fprintln(a.writer, "No resources found")
 		return nil
 	}
 
-	// Sort by cluster, then namespace, then name
-	sort.Slice(allItems, func(i, j int) bool {
-		if allItems[i].Cluster != allItems[j].Cluster {
-			return allItems[i].Cluster < allItems[j].Cluster
-		}
-		nsI, _, _ := unstructured.NestedString(allItems[i].Item.Object, "metadata", "namespace")
-		nsJ, _, _ := unstructured.NestedString(allItems[j].Item.Object, "metadata", "namespace")
-		if nsI != nsJ {
-			return nsI < nsJ
-		}
-		nameI, _, _ := unstructured.NestedString(allItems[i].Item.Object, "metadata", "name")
-		nameJ, _, _ := unstructured.NestedString(allItems[j].Item.Object, "metadata", "name")
-		return nameI < nameJ
-	})
-
-	// Format based on resource type
-	switch strings.ToLower(resourceType) {
-	case "pod", "pods":
-		return a.formatPods(allItems)
-	case "deployment", "deployments":
-		return a.formatDeployments(allItems)
-	case "service", "services":
-		return a.formatServices(allItems)
-	default:
-		return a.formatGeneric(allItems)
+	if !isMultiResource(resourceType) {
+		return a.formatItems(allItems, resourceType)
 	}
-}
 
-// formatPods formats pod resources
-func (a *TableAggregator) formatPods(items []ItemWithCluster) error {
-	// Calculate column widths dynamically
-	widths := a.calculatePodColumnWidths(items)
-
-	// Header
-	fmt.Fprintf(a.writer, "%-*s %-*s %-*s %-*s %-*s %-*s %s\n",
-		widths.namespace, "NAMESPACE",
-		widths.name, "NAME",
-		widths.cluster, "CLUSTER",
-		widths.ready, "READY",
-		widths.status, "STATUS",
-		widths.restarts, "RESTARTS",
-		"AGE")
-
-	// Rows
-	for _, item := range items {
-		ns, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "namespace")
-		name, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "name")
-
-		// Get pod status
-		phase, _, _ := unstructured.NestedString(item.Item.Object, "status", "phase")
-
-		// Get container statuses for ready count
-		ready := "0/0"
-		restarts := int64(0)
-		if containerStatuses, found, _ := unstructured.NestedSlice(item.Item.Object, "status", "containerStatuses"); found {
-			total := len(containerStatuses)
-			readyCount := 0
-			for _, cs := range containerStatuses {
-				csMap, ok := cs.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				if isReady, found, _ := unstructured.NestedBool(csMap, "ready"); found && isReady {
-					readyCount++
-				}
-				if count, found, _ := unstructured.NestedInt64(csMap, "restartCount"); found {
-					restarts += count
-				}
-			}
-			ready = fmt.Sprintf("%d/%d", readyCount, total)
+	// Several resource types: one table per kind, like kubectl get pods,svc.
+	// Clusters return items in the requested type order, so grouping keeps the
+	// tables in that order.
+	for i, group := range groupByKind(allItems) {
+		if i > 0 {
+			fmt.Fprintln(a.writer)
+		}
+		if err := a.formatItems(group, group[0].Item.GetKind()); err != nil {
+			return err
 		}
-
-		// Calculate age
-		age := calculateAge(item.Item)
-
-		fmt.Fprintf(a.writer, "%-*s %-*s %-*s %-*s %-*s %-*d %s\n",
-			widths.namespace, ns,
-			widths.name, name,
-			widths.cluster, item.Cluster,
-			widths.ready, ready,
-			widths.status, phase,
-			widths.restarts, restarts,
-			age)
 	}
 
 	return nil
 }
 
-// calculatePodColumnWidths calculates optimal column widths for pod table
-func (a *TableAggregator) calculatePodColumnWidths(items []ItemWithCluster) podColumnWidths {
-	widths := podColumnWidths{
-		namespace: len("NAMESPACE"),
-		name:      len("NAME"),
-		cluster:   len("CLUSTER"),
-		ready:     len("READY"),
-		status:    len("STATUS"),
-		restarts:  len("RESTARTS"),
-	}
-
-	for _, item := range items {
-		ns, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "namespace")
-		name, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "name")
-		phase, _, _ := unstructured.NestedString(item.Item.Object, "status", "phase")
-
-		if len(ns) > widths.namespace {
-			widths.namespace = len(ns)
-		}
-		if len(name) > widths.name {
-			widths.name = len(name)
-		}
-		if len(item.Cluster) > widths.cluster {
-			widths.cluster = len(item.Cluster)
-		}
-
-		// Ready format is "X/Y"
-		if containerStatuses, found, _ := unstructured.NestedSlice(item.Item.Object, "status", "containerStatuses"); found {
-			total := len(containerStatuses)
-			readyCount := 0
-			for _, cs := range containerStatuses {
-				csMap, ok := cs.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				if isReady, found, _ := unstructured.NestedBool(csMap, "ready"); found && isReady {
-					readyCount++
-				}
-			}
-			readyStr := fmt.Sprintf("%d/%d", readyCount, total)
-			if len(readyStr) > widths.ready {
-				widths.ready = len(readyStr)
-			}
-		}
-
-		if len(phase) > widths.status {
-			widths.status = len(phase)
-		}
-	}
-
-	// Add padding
-	widths.namespace += 2
-	widths.name += 2
-	widths.cluster += 2
-	widths.ready += 2
-	widths.status += 2
-	widths.restarts += 2
+// formatItems prints items of a single resource type, identified by resource name or kind
+func (a *TableAggregator) formatItems(items []ItemWithCluster, resourceType string) error {
+	return a.printTable(a.buildTable(items, resourceType))
+}
 
-	return widths
+// sortItems sorts by cluster, then namespace, then name
+func sortItems(items []ItemWithCluster) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return lessItem(items[i], items[j])
+	})
 }
 
-// formatDeployments formats deployment resources
-func (a *TableAggregator) formatDeployments(items []ItemWithCluster) error {
-	// Calculate column widths dynamically
-	widths := a.calculateDeploymentColumnWidths(items)
-
-	// Header
-	fmt.Fprintf(a.writer, "%-*s %-*s %-*s %-*s %-*s %-*s %s\n",
-		widths.namespace, "NAMESPACE",
-		widths.name, "NAME",
-		widths.cluster, "CLUSTER",
-		widths.ready, "READY",
-		widths.upToDate, "UP-TO-DATE",
-		widths.available, "AVAILABLE",
-		"AGE")
-
-	// Rows
-	for _, item := range items {
-		ns, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "namespace")
-		name, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "name")
-
-		replicas, _, _ := unstructured.NestedInt64(item.Item.Object, "status", "replicas")
-		readyReplicas, _, _ := unstructured.NestedInt64(item.Item.Object, "status", "readyReplicas")
-		updatedReplicas, _, _ := unstructured.NestedInt64(item.Item.Object, "status", "updatedReplicas")
-		availableReplicas, _, _ := unstructured.NestedInt64(item.Item.Object, "status", "availableReplicas")
-
-		ready := fmt.Sprintf("%d/%d", readyReplicas, replicas)
-
-		age := calculateAge(item.Item)
-
-		fmt.Fprintf(a.writer, "%-*s %-*s %-*s %-*s %-*d %-*d %s\n",
-			widths.namespace, ns,
-			widths.name, name,
-			widths.cluster, item.Cluster,
-			widths.ready, ready,
-			widths.upToDate, updatedReplicas,
-			widths.available, availableReplicas,
-			age)
+// lessItem orders items by cluster, then namespace, then name
+func lessItem(a, b ItemWithCluster) bool {
+	if a.Cluster != b.Cluster {
+		return a.Cluster < b.Cluster
 	}
-
-	return nil
+	return lessNamespacedName(a.Item, b.Item)
 }
 
-// calculateDeploymentColumnWidths calculates optimal column widths for deployment table
-func (a *TableAggregator) calculateDeploymentColumnWidths(items []ItemWithCluster) deploymentColumnWidths {
-	widths := deploymentColumnWidths{
-		namespace: len("NAMESPACE"),
-		name:      len("NAME"),
-		cluster:   len("CLUSTER"),
-		ready:     len("READY"),
-		upToDate:  len("UP-TO-DATE"),
-		available: len("AVAILABLE"),
+// lessNamespacedName orders objects by namespace, then name
+func lessNamespacedName(a, b unstructured.Unstructured) bool {
+	if a.GetNamespace() != b.GetNamespace() {
+		return a.GetNamespace() < b.GetNamespace()
 	}
+	return a.GetName() < b.GetName()
+}
 
-	for _, item := range items {
-		ns, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "namespace")
-		name, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "name")
-		replicas, _, _ := unstructured.NestedInt64(item.Item.Object, "status", "replicas")
-		readyReplicas, _, _ := unstructured.NestedInt64(item.Item.Object, "status", "readyReplicas")
-
-		if len(ns) > widths.namespace {
-			widths.namespace = len(ns)
-		}
-		if len(name) > widths.name {
-			widths.name = len(name)
-		}
-		if len(item.Cluster) > widths.cluster {
-			widths.cluster = len(item.Cluster)
-		}
-
-		readyStr := fmt.Sprintf("%d/%d", readyReplicas, replicas)
-		if len(readyStr) > widths.ready {
-			widths.ready = len(readyStr)
+// isMultiResource reports whether a resource argument names several resource
+// types, either as a comma-separated list or through the "all" category
+func isMultiResource(resourceType string) bool {
+	for _, resource := range strings.Split(resourceType, ",") {
+		if strings.TrimSpace(strings.ToLower(resource)) == "all" {
+			return true
 		}
 	}
-
-	// Add padding
-	widths.namespace += 2
-	widths.name += 2
-	widths.cluster += 2
-	widths.ready += 2
-	widths.upToDate += 2
-	widths.available += 2
-
-	return widths
+	return strings.Contains(resourceType, ",")
 }
 
-// formatServices formats service resources
-func (a *TableAggregator) formatServices(items []ItemWithCluster) error {
-	// Calculate column widths dynamically
-	widths := a.calculateServiceColumnWidths(items)
-
-	// Header
-	fmt.Fprintf(a.writer, "%-*s %-*s %-*s %-*s %-*s %-*s %-*s %s\n",
-		widths.namespace, "NAMESPACE",
-		widths.name, "NAME",
-		widths.cluster, "CLUSTER",
-		widths.svcType, "TYPE",
-		widths.clusterIP, "CLUSTER-IP",
-		widths.externalIP, "EXTERNAL-IP",
-		widths.ports, "PORT(S)",
-		"AGE")
-
-	// Rows
+// groupByKind splits items by group and kind, keeping groups in order of first
+// appearance and preserving the item order within each group
+func groupByKind(items []ItemWithCluster) [][]ItemWithCluster {
+	var groups [][]ItemWithCluster
+	index := make(map[string]int)
+
 	for _, item := range items {
-		ns, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "namespace")
-		name, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "name")
-
-		svcType, _, _ := unstructured.NestedString(item.Item.Object, "spec", "type")
-		clusterIP, _, _ := unstructured.NestedString(item.Item.Object, "spec", "clusterIP")
-		externalIP := noneValue
-
-		// Get ports
-		ports := noneValue
-		if portsSlice, found, _ := unstructured.NestedSlice(item.Item.Object, "spec", "ports"); found && len(portsSlice) > 0 {
-			var portStrs []string
-			for _, p := range portsSlice {
-				pMap, ok := p.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				port, _, _ := unstructured.NestedInt64(pMap, "port")
-				protocol, _, _ := unstructured.NestedString(pMap, "protocol")
-				portStrs = append(portStrs, fmt.Sprintf("%d/%s", port, protocol))
-			}
-			if len(portStrs) > 0 {
-				ports = strings.Join(portStrs, ",")
-			}
+		key := item.Item.GroupVersionKind().GroupKind().String()
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
 		}
-
-		age := calculateAge(item.Item)
-
-		fmt.Fprintf(a.writer, "%-*s %-*s %-*s %-*s %-*s %-*s %-*s %s\n",
-			widths.namespace, ns,
-			widths.name, name,
-			widths.cluster, item.Cluster,
-			widths.svcType, svcType,
-			widths.clusterIP, clusterIP,
-			widths.externalIP, externalIP,
-			widths.ports, ports,
-			age)
+		groups[i] = append(groups[i], item)
 	}
 
-	return nil
+	return groups
 }
 
-// calculateServiceColumnWidths calculates optimal column widths for service table
-func (a *TableAggregator) calculateServiceColumnWidths(items []ItemWithCluster) serviceColumnWidths {
-	widths := serviceColumnWidths{
-		namespace:  len("NAMESPACE"),
-		name:       len("NAME"),
-		cluster:    len("CLUSTER"),
-		svcType:    len("TYPE"),
-		clusterIP:  len("CLUSTER-IP"),
-		externalIP: len("EXTERNAL-IP"),
-		ports:      len("PORT(S)"),
+// displayName returns the name column for an item, prefixed with its kind
+// (e.g. pod/nginx or deployment.apps/nginx) when several types are listed
+func (a *TableAggregator) displayName(item unstructured.Unstructured) string {
+	name := item.GetName()
+	if !a.showKind || item.GetKind() == "" {
+		return name
 	}
 
-	for _, item := range items {
-		ns, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "namespace")
-		name, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "name")
-		svcType, _, _ := unstructured.NestedString(item.Item.Object, "spec", "type")
-		clusterIP, _, _ := unstructured.NestedString(item.Item.Object, "spec", "clusterIP")
-
-		if len(ns) > widths.namespace {
-			widths.namespace = len(ns)
-		}
-		if len(name) > widths.name {
-			widths.name = len(name)
-		}
-		if len(item.Cluster) > widths.cluster {
-			widths.cluster = len(item.Cluster)
-		}
-		if len(svcType) > widths.svcType {
-			widths.svcType = len(svcType)
-		}
-		if len(clusterIP) > widths.clusterIP {
-			widths.clusterIP = len(clusterIP)
-		}
-
-		// Calculate ports width
-		ports := noneValue
-		if portsSlice, found, _ := unstructured.NestedSlice(item.Item.Object, "spec", "ports"); found && len(portsSlice) > 0 {
-			var portStrs []string
-			for _, p := range portsSlice {
-				pMap, ok := p.(map[string]interface{})
-				if !ok {
-					continue
-				}
-				port, _, _ := unstructured.NestedInt64(pMap, "port")
-				protocol, _, _ := unstructured.NestedString(pMap, "protocol")
-				portStrs = append(portStrs, fmt.Sprintf("%d/%s", port, protocol))
-			}
-			if len(portStrs) > 0 {
-				ports = strings.Join(portStrs, ",")
-			}
-		}
-		if len(ports) > widths.ports {
-			widths.ports = len(ports)
-		}
+	kind := strings.ToLower(item.GetKind())
+	if group := item.GroupVersionKind().Group; group != "" {
+		kind += "." + group
 	}
-
-	// Add padding
-	widths.namespace += 2
-	widths.name += 2
-	widths.cluster += 2
-	widths.svcType += 2
-	widths.clusterIP += 2
-	widths.externalIP += 2
-	widths.ports += 2
-
-	return widths
+	return kind + "/" + name
 }
 
-// formatGeneric formats any resource type in a generic way
-func (a *TableAggregator) formatGeneric(items []ItemWithCluster) error {
-	// Calculate column widths dynamically
-	widths := a.calculateGenericColumnWidths(items)
+// buildTable renders items of one kind into table rows with the kind's printer
+func (a *TableAggregator) buildTable(items []ItemWithCluster, resourceType string) kindTable {
+	printer := a.printers.printerFor(items[0].Item.GroupVersionKind().GroupKind(), resourceType)
 
-	// Header
-	fmt.Fprintf(a.writer, "%-*s %-*s %-*s %-*s %s\n",
-		widths.namespace, "NAMESPACE",
-		widths.name, "NAME",
-		widths.cluster, "CLUSTER",
-		widths.kind, "KIND",
-		"AGE")
-
-	// Rows
+	t := kindTable{headers: append([]string{"NAME"}, printer.Headers(a.options.Wide)...)}
 	for _, item := range items {
-		ns, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "namespace")
-		name, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "name")
-		kind := item.Item.GetKind()
-
-		if ns == "" {
-			ns = noneValue
-		}
-
-		age := calculateAge(item.Item)
-
-		fmt.Fprintf(a.writer, "%-*s %-*s %-*s %-*s %s\n",
-			widths.namespace, ns,
-			widths.name, name,
-			widths.cluster, item.Cluster,
-			widths.kind, kind,
-			age)
+		cells := append([]string{a.displayName(item.Item)}, printer.Row(item.Item, a.options.Wide)...)
+		t.rows = append(t.rows, kindRow{ItemWithCluster: item, cells: cells})
 	}
-
-	return nil
+	return t
 }
 
-// calculateGenericColumnWidths calculates optimal column widths for generic table
-func (a *TableAggregator) calculateGenericColumnWidths(items []ItemWithCluster) genericColumnWidths {
-	widths := genericColumnWidths{
-		namespace: len("NAMESPACE"),
-		name:      len("NAME"),
-		cluster:   len("CLUSTER"),
-		kind:      len("KIND"),
+// valueOrNone returns value, or <none> when it is empty
+func valueOrNone(value string) string {
+	if value == "" {
+		return noneValue
 	}
+	return value
+}
 
-	for _, item := range items {
-		ns, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "namespace")
-		name, _, _ := unstructured.NestedString(item.Item.Object, "metadata", "name")
-		kind := item.Item.GetKind()
-
-		if ns == "" {
-			ns = "<none>"
-		}
+// servicePorts formats a service's ports as kubectl does (e.g. "80/TCP,443/TCP")
+func servicePorts(obj unstructured.Unstructured) string {
+	portsSlice, found, _ := unstructured.NestedSlice(obj.Object, "spec", "ports")
+	if !found || len(portsSlice) == 0 {
+		return noneValue
+	}
 
-		if len(ns) > widths.namespace {
-			widths.namespace = len(ns)
-		}
-		if len(name) > widths.name {
-			widths.name = len(name)
-		}
-		if len(item.Cluster) > widths.cluster {
-			widths.cluster = len(item.Cluster)
-		}
-		if len(kind) > widths.kind {
-			widths.kind = len(kind)
+	var portStrs []string
+	for _, p := range portsSlice {
+		pMap, ok := p.(map[string]interface{})
+		if !ok {
+			continue
 		}
+		port, _, _ := unstructured.NestedInt64(pMap, "port")
+		protocol, _, _ := unstructured.NestedString(pMap, "protocol")
+		portStrs = append(portStrs, fmt.Sprintf("%d/%s", port, protocol))
 	}
-
-	// Add padding
-	widths.namespace += 2
-	widths.name += 2
-	widths.cluster += 2
-	widths.kind += 2
-
-	return widths
+	if len(portStrs) == 0 {
+		return noneValue
+	}
+	return strings.Join(portStrs, ",")
 }
 
 // calculateAge calculates the age of a resource from its creation timestamp
@@ -122,6 +122,7 @@ func TestAggregateGetResults_Deployments(t *testing.T) {
 				"name":      "nginx-deployment",
 				"namespace": "default",
 			},
+			"spec": map[string]interface{}{"replicas": int64(3)},
 			"status": map[string]interface{}{
 				"replicas":          int64(3),
 				"readyReplicas":     int64(3),
@@ -372,3 +373,72 @@ func TestAggregateGetResults_FailedCluster(t *testing.T) {
 		t.Error("missing pod from successful cluster")
 	}
 }
+
+func TestAggregateGetResults_MultipleTypes(t *testing.T) {
+	buf := &bytes.Buffer{}
+	agg := NewTableAggregator(buf)
+
+	pod := unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata": map[string]interface{}{
+				"name":      "nginx-1",
+				"namespace": "default",
+			},
+			"status": map[string]interface{}{
+				"phase": "Running",
+			},
+		},
+	}
+
+	deployment := unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "nginx",
+				"namespace": "default",
+			},
+		},
+	}
+
+	results := &executor.AggregatedResults{
+		Results: []executor.ClusterResult{
+			{
+				ClusterName: "cluster1",
+				Success:     true,
+				Items:       []unstructured.Unstructured{pod, deployment},
+			},
+			{
+				ClusterName: "cluster2",
+				Success:     true,
+				Items:       []unstructured.Unstructured{deployment},
+			},
+		},
+	}
+
+	if err := agg.AggregateGetResults(results, "pods,deploy"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output := buf.String()
+
+	// One table per kind, separated by a blank line
+	tables := strings.Split(strings.TrimSpace(output), "\n\n")
+	if len(tables) != 2 {
+		t.Fatalf("expected 2 tables, got %d:\n%s", len(tables), output)
+	}
+
+	if !strings.Contains(tables[0], "pod/nginx-1") || !strings.Contains(tables[0], "STATUS") {
+		t.Errorf("expected pod table first, got:\n%s", tables[0])
+	}
+
+	if !strings.Contains(tables[1], "deployment.apps/nginx") || !strings.Contains(tables[1], "UP-TO-DATE") {
+		t.Errorf("expected deployment table second, got:\n%s", tables[1])
+	}
+
+	if strings.Count(tables[1], "deployment.apps/nginx") != 2 {
+		t.Errorf("expected deployment from both clusters, got:\n%s", tables[1])
+	}
+}
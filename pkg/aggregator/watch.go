@@ -0,0 +1,103 @@
+package aggregator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+)
+
+// watchColumnPadding separates columns in watch output
+const watchColumnPadding = 3
+
+// WatchPrinter prints a merged multi-cluster watch stream, one row per event.
+// Rows cannot be buffered to size the columns, so each column grows to the
+// widest value seen so far.
+type WatchPrinter struct {
+	writer io.Writer
+	status io.Writer
+
+	// printer renders the kind-specific columns; it is chosen from the first object
+	printers *PrinterRegistry
+	printer  Printer
+	header   []string
+	widths   []int
+	started  bool
+}
+
+// NewWatchPrinter creates a printer writing rows to writer and per-cluster
+// connection status (reconnects and failures) to status
+func NewWatchPrinter(writer, status io.Writer) *WatchPrinter {
+	return &WatchPrinter{
+		writer:   writer,
+		status:   status,
+		printers: DefaultPrinters,
+	}
+}
+
+// SetPrinters replaces the registry of kind-specific printers
+func (p *WatchPrinter) SetPrinters(printers *PrinterRegistry) {
+	p.printers = printers
+}
+
+// PrintEvent prints a single watch event
+func (p *WatchPrinter) PrintEvent(event executor.WatchEvent) error {
+	if event.IsStatus() {
+		return p.printStatus(event)
+	}
+
+	if !p.started {
+		p.printer = p.printers.printerFor(event.Object.GroupVersionKind().GroupKind(), event.Object.GetKind())
+		p.header = append([]string{"EVENT", "CLUSTER", "NAMESPACE", "NAME"}, p.printer.Headers(false)...)
+		p.widths = make([]int, len(p.header))
+	}
+
+	item := *event.Object
+	ns := item.GetNamespace()
+	if ns == "" {
+		ns = noneValue
+	}
+	row := append([]string{string(event.Type), event.Cluster, ns, item.GetName()}, p.printer.Row(item, false)...)
+
+	for i := range p.widths {
+		p.widths[i] = max(p.widths[i], len(p.header[i]), len(row[i]))
+	}
+
+	if !p.started {
+		p.started = true
+		if err := p.printRow(p.header); err != nil {
+			return err
+		}
+	}
+
+	return p.printRow(row)
+}
+
+// printRow writes a row padded to the current column widths
+func (p *WatchPrinter) printRow(fields []string) error {
+	var line strings.Builder
+	for i, field := range fields {
+		if i == len(fields)-1 {
+			line.WriteString(field)
+			break
+		}
+		fmt.Fprintf(&line, "%-*s", p.widths[i]+watchColumnPadding, field)
+	}
+	_, err := fmt.Fprintln(p.writer, line.String())
+	return err
+}
+
+// printStatus reports a change in a cluster's watch connection
+func (p *WatchPrinter) printStatus(event executor.WatchEvent) error {
+	var err error
+	switch event.Status {
+	case executor.WatchStatusReconnecting:
+		_, err = fmt.Fprintf(p.status, "%s: watch interrupted, reconnecting: %v\n", event.Cluster, event.Error)
+	case executor.WatchStatusConnected:
+		_, err = fmt.Fprintf(p.status, "%s: watch resumed\n", event.Cluster)
+	case executor.WatchStatusFailed:
+		_, err = fmt.Fprintf(p.status, "%s: watch failed: %v\n", event.Cluster, event.Error)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package aggregator
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// newWatchPod builds a pod for watch events
+func newWatchPod(name, phase string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": "default",
+			},
+			"status": map[string]interface{}{
+				"phase": phase,
+			},
+		},
+	}
+}
+
+func TestWatchPrinter_Rows(t *testing.T) {
+	out := &bytes.Buffer{}
+	status := &bytes.Buffer{}
+	printer := NewWatchPrinter(out, status)
+
+	events := []executor.WatchEvent{
+		{Cluster: "cluster1", Type: watch.Added, Object: newWatchPod("nginx", "Pending")},
+		{Cluster: "cluster2-with-a-long-name", Type: watch.Modified, Object: newWatchPod("nginx", "Running")},
+		{Cluster: "cluster1", Type: watch.Deleted, Object: newWatchPod("nginx", "Running")},
+	}
+	for _, event := range events {
+		if err := printer.PrintEvent(event); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected header and 3 rows, got:\n%s", out.String())
+	}
+
+	header := strings.Fields(lines[0])
+	expected := []string{"EVENT", "CLUSTER", "NAMESPACE", "NAME", "READY", "STATUS", "RESTARTS", "AGE"}
+	if strings.Join(header, " ") != strings.Join(expected, " ") {
+		t.Errorf("expected header %v, got %v", expected, header)
+	}
+
+	if fields := strings.Fields(lines[2]); fields[0] != "MODIFIED" || fields[1] != "cluster2-with-a-long-name" || fields[5] != "Running" {
+		t.Errorf("unexpected row: %q", lines[2])
+	}
+	if fields := strings.Fields(lines[3]); fields[0] != "DELETED" || fields[1] != "cluster1" {
+		t.Errorf("unexpected row: %q", lines[3])
+	}
+
+	if status.Len() != 0 {
+		t.Errorf("expected no status output, got: %s", status.String())
+	}
+}
+
+func TestWatchPrinter_Status(t *testing.T) {
+	out := &bytes.Buffer{}
+	status := &bytes.Buffer{}
+	printer := NewWatchPrinter(out, status)
+
+	events := []executor.WatchEvent{
+		{Cluster: "cluster1", Status: executor.WatchStatusReconnecting, Error: errors.New("connection reset")},
+		{Cluster: "cluster1", Status: executor.WatchStatusConnected},
+		{Cluster: "cluster2", Status: executor.WatchStatusFailed, Error: errors.New("unauthorized")},
+	}
+	for _, event := range events {
+		if err := printer.PrintEvent(event); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("expected status events to stay out of the table, got: %s", out.String())
+	}
+
+	for _, want := range []string{
+		"cluster1: watch interrupted, reconnecting: connection reset",
+		"cluster1: watch resumed",
+		"cluster2: watch failed: unauthorized",
+	} {
+		if !strings.Contains(status.String(), want) {
+			t.Errorf("expected %q in status output:\n%s", want, status.String())
+		}
+	}
+}
@@ -2,23 +2,53 @@ package client
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/discovery"
+	diskcached "k8s.io/client-go/discovery/cached/disk"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
-// Factory provides Kubernetes clients for a specific context
+// discoveryCacheTTL is how long cached discovery information is used, as in kubectl
+const discoveryCacheTTL = 6 * time.Hour
+
+// unsafeCacheChars are replaced in API server hosts to name their cache directory
+var unsafeCacheChars = regexp.MustCompile(`[^(\w/.)]`)
+
+// Factory provides Kubernetes clients for a specific context.
+//
+// The kubectl flags in configFlags apply as follows. --kubeconfig, --as,
+// --as-group, --as-uid, --request-timeout, --insecure-skip-tls-verify,
+// --disable-compression and --cache-dir apply to every cluster. Flags naming
+// credentials or an endpoint (--context, --cluster, --user, --server, --token,
+// --username, --password, --certificate-authority, --client-certificate,
+// --client-key, --tls-server-name) describe a single cluster and only apply to
+// the hub; member clusters take them from their mapping. A mapping entry's own
+// kubeconfig file and impersonation take precedence over the flags.
 type Factory struct {
 	context     string
 	kubeconfig  string
 	configFlags *genericclioptions.ConfigFlags
+
+	// member is set for member cluster factories, which ignore the hub-only flags
+	member bool
+
+	// mapping holds the per-cluster overrides of the context
+	mapping kubeconfig.ClusterMapping
 }
 
-// NewFactory creates a new client factory for the specified context
+// NewFactory creates a new client factory for the specified context, e.g. the
+// hub's; an empty context falls back to --context and then the current context
 func NewFactory(context string, configFlags *genericclioptions.ConfigFlags) (*Factory, error) {
 	return &Factory{
 		context:     context,
@@ -26,18 +56,141 @@ func NewFactory(context string, configFlags *genericclioptions.ConfigFlags) (*Fa
 	}, nil
 }
 
-// RESTConfig returns a REST config for the specified context
-func (f *Factory) RESTConfig() (*rest.Config, error) {
-	// If context is specified, use it; otherwise use current context
+// NewFactoryForMapping creates a client factory for a mapped cluster. The
+// mapping's kubeconfig file, user, namespace, impersonation, proxy and TLS
+// server name override those of its context.
+func NewFactoryForMapping(mapping kubeconfig.ClusterMapping, configFlags *genericclioptions.ConfigFlags) (*Factory, error) {
+	kubeconfigPath, err := expandHome(mapping.Kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve kubeconfig of cluster %s: %w", mapping.Name, err)
+	}
+
+	return &Factory{
+		context:     mapping.Context,
+		kubeconfig:  kubeconfigPath,
+		configFlags: configFlags,
+		member:      true,
+		mapping:     mapping,
+	}, nil
+}
+
+// clientConfig returns the kubeconfig loader with the flags and the mapping's
+// overrides applied
+func (f *Factory) clientConfig() clientcmd.ClientConfig {
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	configOverrides := &clientcmd.ConfigOverrides{}
+	if f.configFlags != nil {
+		applyFleetFlags(f.configFlags, loadingRules, configOverrides)
+		if !f.member {
+			applyHubFlags(f.configFlags, configOverrides)
+		}
+	}
 
+	if f.kubeconfig != "" {
+		loadingRules.ExplicitPath = f.kubeconfig
+	}
+
+	// If context is specified, use it; otherwise use --context or the current context
 	if f.context != "" {
 		configOverrides.CurrentContext = f.context
 	}
+	if f.mapping.User != "" {
+		configOverrides.Context.AuthInfo = f.mapping.User
+	}
+	if f.mapping.DefaultNamespace != "" {
+		configOverrides.Context.Namespace = f.mapping.DefaultNamespace
+	}
+	if f.mapping.As != "" {
+		configOverrides.AuthInfo.Impersonate = f.mapping.As
+		configOverrides.AuthInfo.ImpersonateGroups = f.mapping.AsGroups
+		configOverrides.AuthInfo.ImpersonateUID = ""
+	}
+	if f.mapping.ProxyURL != "" {
+		configOverrides.ClusterInfo.ProxyURL = f.mapping.ProxyURL
+	}
+	if f.mapping.TLSServerName != "" {
+		configOverrides.ClusterInfo.TLSServerName = f.mapping.TLSServerName
+	}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
+}
+
+// applyFleetFlags applies the kubectl flags that hold for every cluster
+func applyFleetFlags(flags *genericclioptions.ConfigFlags, loadingRules *clientcmd.ClientConfigLoadingRules, overrides *clientcmd.ConfigOverrides) {
+	if flags.KubeConfig != nil {
+		loadingRules.ExplicitPath = *flags.KubeConfig
+	}
+	if flags.Impersonate != nil {
+		overrides.AuthInfo.Impersonate = *flags.Impersonate
+	}
+	if flags.ImpersonateUID != nil {
+		overrides.AuthInfo.ImpersonateUID = *flags.ImpersonateUID
+	}
+	if flags.ImpersonateGroup != nil {
+		overrides.AuthInfo.ImpersonateGroups = *flags.ImpersonateGroup
+	}
+	if flags.Timeout != nil {
+		overrides.Timeout = *flags.Timeout
+	}
+	if flags.Insecure != nil {
+		overrides.ClusterInfo.InsecureSkipTLSVerify = *flags.Insecure
+	}
+	if flags.DisableCompression != nil {
+		overrides.ClusterInfo.DisableCompression = *flags.DisableCompression
+	}
+}
+
+// applyHubFlags applies the kubectl flags that describe a single cluster
+func applyHubFlags(flags *genericclioptions.ConfigFlags, overrides *clientcmd.ConfigOverrides) {
+	if flags.Context != nil {
+		overrides.CurrentContext = *flags.Context
+	}
+	if flags.ClusterName != nil {
+		overrides.Context.Cluster = *flags.ClusterName
+	}
+	if flags.AuthInfoName != nil {
+		overrides.Context.AuthInfo = *flags.AuthInfoName
+	}
+	if flags.APIServer != nil {
+		overrides.ClusterInfo.Server = *flags.APIServer
+	}
+	if flags.TLSServerName != nil {
+		overrides.ClusterInfo.TLSServerName = *flags.TLSServerName
+	}
+	if flags.CAFile != nil {
+		overrides.ClusterInfo.CertificateAuthority = *flags.CAFile
+	}
+	if flags.CertFile != nil {
+		overrides.AuthInfo.ClientCertificate = *flags.CertFile
+	}
+	if flags.KeyFile != nil {
+		overrides.AuthInfo.ClientKey = *flags.KeyFile
+	}
+	if flags.BearerToken != nil {
+		overrides.AuthInfo.Token = *flags.BearerToken
+	}
+	if flags.Username != nil {
+		overrides.AuthInfo.Username = *flags.Username
+	}
+	if flags.Password != nil {
+		overrides.AuthInfo.Password = *flags.Password
+	}
+}
+
+// RESTConfig returns a REST config for the specified context
+func (f *Factory) RESTConfig() (*rest.Config, error) {
+	return f.clientConfig().ClientConfig()
+}
+
+// RawConfig returns the kubeconfig the factory loads its context from
+func (f *Factory) RawConfig() (clientcmdapi.Config, error) {
+	return f.clientConfig().RawConfig()
+}
 
-	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, configOverrides)
-	return clientConfig.ClientConfig()
+// Namespace returns the default namespace of the context, after overrides
+func (f *Factory) Namespace() (string, error) {
+	namespace, _, err := f.clientConfig().Namespace()
+	return namespace, err
 }
 
 // DynamicClient returns a dynamic client
@@ -60,12 +213,36 @@ func (f *Factory) Clientset() (*kubernetes.Clientset, error) {
 	return kubernetes.NewForConfig(config)
 }
 
-// DiscoveryClient returns a discovery client
+// DiscoveryClient returns a discovery client, cached on disk under --cache-dir
+// as kubectl does; an empty --cache-dir disables the cache
 func (f *Factory) DiscoveryClient() (discovery.DiscoveryInterface, error) {
 	config, err := f.RESTConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get REST config: %w", err)
 	}
 
-	return discovery.NewDiscoveryClientForConfig(config)
+	if f.configFlags == nil || f.configFlags.CacheDir == nil || *f.configFlags.CacheDir == "" {
+		return discovery.NewDiscoveryClientForConfig(config)
+	}
+	cacheDir := *f.configFlags.CacheDir
+	return diskcached.NewCachedDiscoveryClientForConfig(config, discoveryCacheDir(cacheDir, config.Host), filepath.Join(cacheDir, "http"), discoveryCacheTTL)
+}
+
+// discoveryCacheDir returns the discovery cache of an API server, laid out
+// like kubectl's so both share it
+func discoveryCacheDir(cacheDir, host string) string {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	return filepath.Join(cacheDir, "discovery", unsafeCacheChars.ReplaceAllString(host, "_"))
+}
+
+// expandHome expands a leading ~ in a path to the user's home directory
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
 }
@@ -1,9 +1,15 @@
 package client
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
 )
 
 func TestNewFactory(t *testing.T) {
@@ -192,3 +198,215 @@ func TestFactoryMultipleContexts(t *testing.T) {
 		}
 	}
 }
+
+// testKubeconfig writes a kubeconfig with one context and two users
+func testKubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	data := `apiVersion: v1
+kind: Config
+clusters:
+- name: prod-eu-1
+  cluster:
+    server: https://10.0.0.1:6443
+contexts:
+- name: admin@prod-eu-1
+  context:
+    cluster: prod-eu-1
+    user: admin
+    namespace: default
+users:
+- name: admin
+  user:
+    token: admin-token
+- name: sso
+  user:
+    token: sso-token
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write kubeconfig: %v", err)
+	}
+	return path
+}
+
+func TestNewFactoryForMapping(t *testing.T) {
+	path := testKubeconfig(t)
+
+	tests := []struct {
+		name          string
+		mapping       kubeconfig.ClusterMapping
+		wantToken     string
+		wantNamespace string
+		wantAs        string
+		wantGroups    []string
+		wantProxy     bool
+		wantTLSName   string
+	}{
+		{
+			name:          "context only",
+			mapping:       kubeconfig.ClusterMapping{Name: "prod-eu-1", Context: "admin@prod-eu-1", Kubeconfig: path},
+			wantToken:     "admin-token",
+			wantNamespace: "default",
+		},
+		{
+			name: "all overrides",
+			mapping: kubeconfig.ClusterMapping{
+				Name:             "prod-eu-1",
+				Context:          "admin@prod-eu-1",
+				Kubeconfig:       path,
+				User:             "sso",
+				DefaultNamespace: "payments",
+				As:               "deployer",
+				AsGroups:         []string{"ops"},
+				ProxyURL:         "socks5://localhost:1080",
+				TLSServerName:    "api.prod-eu-1.internal",
+			},
+			wantToken:     "sso-token",
+			wantNamespace: "payments",
+			wantAs:        "deployer",
+			wantGroups:    []string{"ops"},
+			wantProxy:     true,
+			wantTLSName:   "api.prod-eu-1.internal",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory, err := NewFactoryForMapping(tt.mapping, genericclioptions.NewConfigFlags(true))
+			if err != nil {
+				t.Fatalf("failed to create factory: %v", err)
+			}
+
+			config, err := factory.RESTConfig()
+			if err != nil {
+				t.Fatalf("failed to get REST config: %v", err)
+			}
+			if config.Host != "https://10.0.0.1:6443" {
+				t.Errorf("expected host https://10.0.0.1:6443, got %s", config.Host)
+			}
+			if config.BearerToken != tt.wantToken {
+				t.Errorf("expected token %s, got %s", tt.wantToken, config.BearerToken)
+			}
+			if config.Impersonate.UserName != tt.wantAs {
+				t.Errorf("expected impersonated user %q, got %q", tt.wantAs, config.Impersonate.UserName)
+			}
+			if !reflect.DeepEqual(config.Impersonate.Groups, tt.wantGroups) {
+				t.Errorf("expected impersonated groups %v, got %v", tt.wantGroups, config.Impersonate.Groups)
+			}
+			if (config.Proxy != nil) != tt.wantProxy {
+				t.Errorf("expected proxy %t, got %t", tt.wantProxy, config.Proxy != nil)
+			}
+			if config.TLSClientConfig.ServerName != tt.wantTLSName {
+				t.Errorf("expected TLS server name %q, got %q", tt.wantTLSName, config.TLSClientConfig.ServerName)
+			}
+
+			namespace, err := factory.Namespace()
+			if err != nil {
+				t.Fatalf("failed to get namespace: %v", err)
+			}
+			if namespace != tt.wantNamespace {
+				t.Errorf("expected namespace %s, got %s", tt.wantNamespace, namespace)
+			}
+		})
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+
+	tests := map[string]string{
+		"":                "",
+		"/etc/kubeconfig": "/etc/kubeconfig",
+		"~/.kube/eu.yaml": filepath.Join(home, ".kube/eu.yaml"),
+		"~other/config":   "~other/config",
+	}
+	for input, expected := range tests {
+		got, err := expandHome(input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != expected {
+			t.Errorf("expandHome(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestFactoryFlags(t *testing.T) {
+	path := testKubeconfig(t)
+	configFlags := genericclioptions.NewConfigFlags(true)
+	token, timeout, insecure, as := "flag-token", "5s", true, "auditor"
+	otherContext := "missing"
+	configFlags.KubeConfig = &path
+	configFlags.BearerToken = &token
+	configFlags.Timeout = &timeout
+	configFlags.Insecure = &insecure
+	configFlags.Impersonate = &as
+	configFlags.Context = &otherContext
+
+	// The hub gets every flag; its context overrides --context
+	hub, _ := NewFactory("admin@prod-eu-1", configFlags)
+	config, err := hub.RESTConfig()
+	if err != nil {
+		t.Fatalf("failed to get hub REST config: %v", err)
+	}
+	if config.BearerToken != "flag-token" || config.Timeout != 5*time.Second || !config.Insecure || config.Impersonate.UserName != "auditor" {
+		t.Errorf("expected the hub to honor all flags, got token %q timeout %s insecure %t as %q",
+			config.BearerToken, config.Timeout, config.Insecure, config.Impersonate.UserName)
+	}
+
+	// Members read --kubeconfig and the fleet-wide flags, but keep their own
+	// credentials and context
+	member, _ := NewFactoryForMapping(kubeconfig.ClusterMapping{Name: "prod-eu-1", Context: "admin@prod-eu-1"}, configFlags)
+	config, err = member.RESTConfig()
+	if err != nil {
+		t.Fatalf("failed to get member REST config: %v", err)
+	}
+	if config.BearerToken != "admin-token" || config.Timeout != 5*time.Second || !config.Insecure || config.Impersonate.UserName != "auditor" {
+		t.Errorf("expected the member to honor only fleet-wide flags, got token %q timeout %s insecure %t as %q",
+			config.BearerToken, config.Timeout, config.Insecure, config.Impersonate.UserName)
+	}
+
+	// A mapping's impersonation takes precedence over --as
+	member, _ = NewFactoryForMapping(kubeconfig.ClusterMapping{Name: "prod-eu-1", Context: "admin@prod-eu-1", As: "deployer"}, configFlags)
+	config, err = member.RESTConfig()
+	if err != nil {
+		t.Fatalf("failed to get member REST config: %v", err)
+	}
+	if config.Impersonate.UserName != "deployer" {
+		t.Errorf("expected the mapping's impersonation, got %q", config.Impersonate.UserName)
+	}
+}
+
+func TestFactoryDiscoveryCache(t *testing.T) {
+	path := testKubeconfig(t)
+	configFlags := genericclioptions.NewConfigFlags(true)
+	configFlags.KubeConfig = &path
+
+	cacheDir := t.TempDir()
+	configFlags.CacheDir = &cacheDir
+	factory, _ := NewFactory("admin@prod-eu-1", configFlags)
+	discoveryClient, err := factory.DiscoveryClient()
+	if err != nil {
+		t.Fatalf("failed to get discovery client: %v", err)
+	}
+	if _, ok := discoveryClient.(discovery.CachedDiscoveryInterface); !ok {
+		t.Errorf("expected a cached discovery client with --cache-dir, got %T", discoveryClient)
+	}
+
+	noCache := ""
+	configFlags.CacheDir = &noCache
+	discoveryClient, err = factory.DiscoveryClient()
+	if err != nil {
+		t.Fatalf("failed to get discovery client: %v", err)
+	}
+	if _, ok := discoveryClient.(discovery.CachedDiscoveryInterface); ok {
+		t.Error("expected no cache with an empty --cache-dir")
+	}
+
+	if got := discoveryCacheDir("/cache", "https://10.0.0.1:6443"); got != filepath.Join("/cache", "discovery", "10.0.0.1_6443") {
+		t.Errorf("unexpected discovery cache dir %s", got)
+	}
+}
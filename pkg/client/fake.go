@@ -0,0 +1,122 @@
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	mcdiscovery "github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// FakeClusterClients holds the clients a FakeProvider returns for one cluster.
+// Unset clients cause the corresponding provider method to fail.
+type FakeClusterClients struct {
+	RESTConfig *rest.Config
+	Dynamic    dynamic.Interface
+	Discovery  discovery.DiscoveryInterface
+	Clientset  kubernetes.Interface
+
+	// Namespace is the default namespace of the cluster's context; empty means "default"
+	Namespace string
+}
+
+// FakeProvider is an in-memory ClusterClientProvider for tests.
+// Clusters without registered clients fail like unmapped clusters do.
+type FakeProvider struct {
+	mu      sync.RWMutex
+	clients map[string]FakeClusterClients
+}
+
+// NewFakeProvider creates an empty fake client provider
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		clients: make(map[string]FakeClusterClients),
+	}
+}
+
+// SetClients registers the clients returned for a cluster name.
+// When Discovery is unset, the Clientset's discovery client is used.
+func (p *FakeProvider) SetClients(clusterName string, clients FakeClusterClients) {
+	if clients.Discovery == nil && clients.Clientset != nil {
+		clients.Discovery = clients.Clientset.Discovery()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clients[clusterName] = clients
+}
+
+// lookup returns the registered clients for a cluster
+func (p *FakeProvider) lookup(cluster mcdiscovery.ClusterInfo) (FakeClusterClients, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	clients, ok := p.clients[cluster.Name]
+	if !ok {
+		return FakeClusterClients{}, fmt.Errorf("no clients registered for cluster %s", cluster.Name)
+	}
+	return clients, nil
+}
+
+// RESTConfig returns the registered REST config for the cluster
+func (p *FakeProvider) RESTConfig(cluster mcdiscovery.ClusterInfo) (*rest.Config, error) {
+	clients, err := p.lookup(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if clients.RESTConfig == nil {
+		return nil, fmt.Errorf("no REST config registered for cluster %s", cluster.Name)
+	}
+	return clients.RESTConfig, nil
+}
+
+// DynamicClient returns the registered dynamic client for the cluster
+func (p *FakeProvider) DynamicClient(cluster mcdiscovery.ClusterInfo) (dynamic.Interface, error) {
+	clients, err := p.lookup(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if clients.Dynamic == nil {
+		return nil, fmt.Errorf("no dynamic client registered for cluster %s", cluster.Name)
+	}
+	return clients.Dynamic, nil
+}
+
+// DiscoveryClient returns the registered discovery client for the cluster
+func (p *FakeProvider) DiscoveryClient(cluster mcdiscovery.ClusterInfo) (discovery.DiscoveryInterface, error) {
+	clients, err := p.lookup(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if clients.Discovery == nil {
+		return nil, fmt.Errorf("no discovery client registered for cluster %s", cluster.Name)
+	}
+	return clients.Discovery, nil
+}
+
+// Clientset returns the registered typed client for the cluster
+func (p *FakeProvider) Clientset(cluster mcdiscovery.ClusterInfo) (kubernetes.Interface, error) {
+	clients, err := p.lookup(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if clients.Clientset == nil {
+		return nil, fmt.Errorf("no clientset registered for cluster %s", cluster.Name)
+	}
+	return clients.Clientset, nil
+}
+
+// Namespace returns the registered default namespace for the cluster
+func (p *FakeProvider) Namespace(cluster mcdiscovery.ClusterInfo) (string, error) {
+	clients, err := p.lookup(cluster)
+	if err != nil {
+		return "", err
+	}
+	if clients.Namespace == "" {
+		return "default", nil
+	}
+	return clients.Namespace, nil
+}
@@ -0,0 +1,188 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	mcdiscovery "github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// ClusterIDProperty is the About API ClusterProperty holding the cluster's
+// name within its ClusterSet (KEP-2149)
+const ClusterIDProperty = "cluster.clusterset.k8s.io"
+
+var (
+	// clusterPropertyGVR is the GroupVersionResource for the About API ClusterProperty
+	clusterPropertyGVR = schema.GroupVersionResource{
+		Group:    "about.k8s.io",
+		Version:  "v1alpha1",
+		Resource: "clusterproperties",
+	}
+
+	// namespaceGVR is the GroupVersionResource for namespaces
+	namespaceGVR = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+)
+
+// Identity is what a cluster reports about itself
+type Identity struct {
+	// ClusterID is the value of the cluster.clusterset.k8s.io ClusterProperty,
+	// empty when the About API is not served or not readable
+	ClusterID string
+
+	// KubeSystemUID is the UID of the kube-system namespace, empty when not readable
+	KubeSystemUID string
+}
+
+// IdentityMismatchError reports that a cluster's clients reach a different cluster
+type IdentityMismatchError struct {
+	// Cluster is the discovered cluster the clients were meant to reach
+	Cluster string
+
+	// Reason describes the evidence, e.g. the cluster ID the clients reached
+	Reason string
+}
+
+func (e *IdentityMismatchError) Error() string {
+	return fmt.Sprintf("refusing to use cluster %s: its context reaches a different cluster (%s); fix the mapping and re-check with kubectl mc setup --verify", e.Cluster, e.Reason)
+}
+
+// ReadIdentity reads the About API cluster ID and the kube-system namespace
+// UID. Either is left empty when the cluster does not serve it or the user
+// may not read it; other failures are returned.
+func ReadIdentity(ctx context.Context, dynamicClient dynamic.Interface) (Identity, error) {
+	var identity Identity
+	var err error
+
+	if identity.ClusterID, err = readClusterID(ctx, dynamicClient); err != nil {
+		return identity, err
+	}
+	if identity.KubeSystemUID, err = readKubeSystemUID(ctx, dynamicClient); err != nil {
+		return identity, err
+	}
+	return identity, nil
+}
+
+// readClusterID reads the About API cluster ID, empty when not available
+func readClusterID(ctx context.Context, dynamicClient dynamic.Interface) (string, error) {
+	property, err := dynamicClient.Resource(clusterPropertyGVR).Get(ctx, ClusterIDProperty, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		id, _, _ := unstructured.NestedString(property.Object, "spec", "value")
+		return id, nil
+	case unavailable(err):
+		return "", nil
+	default:
+		return "", fmt.Errorf("failed to read ClusterProperty %s: %w", ClusterIDProperty, err)
+	}
+}
+
+// readKubeSystemUID reads the UID of the kube-system namespace, empty when not available
+func readKubeSystemUID(ctx context.Context, dynamicClient dynamic.Interface) (string, error) {
+	namespace, err := dynamicClient.Resource(namespaceGVR).Get(ctx, "kube-system", metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return string(namespace.GetUID()), nil
+	case unavailable(err):
+		return "", nil
+	default:
+		return "", fmt.Errorf("failed to read the kube-system namespace: %w", err)
+	}
+}
+
+// unavailable reports whether an error means the resource cannot be used for
+// identification, rather than that the cluster could not be reached
+func unavailable(err error) bool {
+	return apierrors.IsNotFound(err) || apierrors.IsForbidden(err) || meta.IsNoMatchError(err)
+}
+
+// IdentityVerifier checks that the clients of a cluster reach that cluster,
+// comparing the About API cluster ID with the cluster name and the kube-system
+// namespace UID with the one recorded in the mapping file
+type IdentityVerifier struct {
+	clients  ClusterClientProvider
+	mappings *kubeconfig.Manager
+}
+
+// NewIdentityVerifier creates a verifier; mappings may be nil when no UIDs are recorded
+func NewIdentityVerifier(clients ClusterClientProvider, mappings *kubeconfig.Manager) *IdentityVerifier {
+	return &IdentityVerifier{
+		clients:  clients,
+		mappings: mappings,
+	}
+}
+
+// Verify reads the identity of the cluster the clients reach and checks it.
+// A cluster that reports neither identity is accepted unverified; the
+// returned bool reports whether any identity was compared.
+func (v *IdentityVerifier) Verify(ctx context.Context, cluster mcdiscovery.ClusterInfo) (Identity, bool, error) {
+	dynamicClient, err := v.clients.DynamicClient(cluster)
+	if err != nil {
+		return Identity{}, false, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	identity, err := ReadIdentity(ctx, dynamicClient)
+	if err != nil {
+		return identity, false, fmt.Errorf("failed to verify cluster identity: %w", err)
+	}
+
+	verified := false
+	if identity.ClusterID != "" {
+		if identity.ClusterID != cluster.Name {
+			return identity, false, &IdentityMismatchError{Cluster: cluster.Name, Reason: fmt.Sprintf("%s is %q", ClusterIDProperty, identity.ClusterID)}
+		}
+		verified = true
+	}
+
+	if v.mappings != nil && identity.KubeSystemUID != "" {
+		if mapping, ok := v.mappings.GetMapping(cluster.Name); ok && mapping.KubeSystemUID != "" {
+			if mapping.KubeSystemUID != identity.KubeSystemUID {
+				return identity, false, &IdentityMismatchError{Cluster: cluster.Name, Reason: fmt.Sprintf("kube-system UID is %s, recorded %s", identity.KubeSystemUID, mapping.KubeSystemUID)}
+			}
+			verified = true
+		}
+	}
+
+	return identity, verified, nil
+}
+
+// VerifyIdentity checks the cluster's identity for the executor with a single
+// read: a cluster whose mapping records a kube-system UID is compared against
+// that UID, any other cluster against its About API cluster ID. A cluster
+// that reports neither is accepted unverified.
+func (v *IdentityVerifier) VerifyIdentity(ctx context.Context, cluster mcdiscovery.ClusterInfo) error {
+	dynamicClient, err := v.clients.DynamicClient(cluster)
+	if err != nil {
+		return fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	if v.mappings != nil {
+		if mapping, ok := v.mappings.GetMapping(cluster.Name); ok && mapping.KubeSystemUID != "" {
+			uid, err := readKubeSystemUID(ctx, dynamicClient)
+			if err != nil {
+				return fmt.Errorf("failed to verify cluster identity: %w", err)
+			}
+			if uid != "" {
+				if uid != mapping.KubeSystemUID {
+					return &IdentityMismatchError{Cluster: cluster.Name, Reason: fmt.Sprintf("kube-system UID is %s, recorded %s", uid, mapping.KubeSystemUID)}
+				}
+				return nil
+			}
+		}
+	}
+
+	id, err := readClusterID(ctx, dynamicClient)
+	if err != nil {
+		return fmt.Errorf("failed to verify cluster identity: %w", err)
+	}
+	if id != "" && id != cluster.Name {
+		return &IdentityMismatchError{Cluster: cluster.Name, Reason: fmt.Sprintf("%s is %q", ClusterIDProperty, id)}
+	}
+	return nil
+}
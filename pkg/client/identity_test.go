@@ -0,0 +1,160 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	mcdiscovery "github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// identityObjects returns a kube-system namespace and, when clusterID is set,
+// the About API ClusterProperty naming the cluster
+func identityObjects(uid, clusterID string) []runtime.Object {
+	objects := []runtime.Object{
+		&unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Namespace",
+			"metadata":   map[string]interface{}{"name": "kube-system", "uid": uid},
+		}},
+	}
+	if clusterID != "" {
+		objects = append(objects, &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "about.k8s.io/v1alpha1",
+			"kind":       "ClusterProperty",
+			"metadata":   map[string]interface{}{"name": ClusterIDProperty},
+			"spec":       map[string]interface{}{"value": clusterID},
+		}})
+	}
+	return objects
+}
+
+func TestReadIdentity(t *testing.T) {
+	client := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), identityObjects("uid-1", "prod-us-1")...)
+	identity, err := ReadIdentity(context.Background(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.ClusterID != "prod-us-1" || identity.KubeSystemUID != "uid-1" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+
+	// Clusters without the About API still report their kube-system UID
+	client = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), identityObjects("uid-2", "")...)
+	identity, err = ReadIdentity(context.Background(), client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if identity.ClusterID != "" || identity.KubeSystemUID != "uid-2" {
+		t.Errorf("unexpected identity %+v", identity)
+	}
+}
+
+func TestIdentityVerifier(t *testing.T) {
+	manager, err := kubeconfig.NewManager(filepath.Join(t.TempDir(), "mappings.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	for _, name := range []string{"prod-us-1", "prod-eu-1", "dev"} {
+		if err := manager.SetMapping(name, name, ""); err != nil {
+			t.Fatalf("failed to set mapping: %v", err)
+		}
+	}
+	if err := manager.SetKubeSystemUID("prod-eu-1", "uid-eu"); err != nil {
+		t.Fatalf("failed to set kube-system UID: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		cluster      string
+		objects      []runtime.Object
+		wantVerified bool
+		wantMismatch bool
+	}{
+		{name: "matching cluster ID", cluster: "prod-us-1", objects: identityObjects("uid-us", "prod-us-1"), wantVerified: true},
+		{name: "other cluster ID", cluster: "prod-us-1", objects: identityObjects("uid-us", "prod-eu-1"), wantMismatch: true},
+		{name: "matching kube-system UID", cluster: "prod-eu-1", objects: identityObjects("uid-eu", ""), wantVerified: true},
+		{name: "other kube-system UID", cluster: "prod-eu-1", objects: identityObjects("uid-us", ""), wantMismatch: true},
+		{name: "nothing to compare", cluster: "dev", objects: identityObjects("uid-dev", "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := NewFakeProvider()
+			provider.SetClients(tt.cluster, FakeClusterClients{
+				Dynamic: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tt.objects...),
+			})
+
+			_, verified, err := NewIdentityVerifier(provider, manager).Verify(context.Background(), mcdiscovery.ClusterInfo{Name: tt.cluster})
+			var mismatch *IdentityMismatchError
+			if errors.As(err, &mismatch) != tt.wantMismatch {
+				t.Fatalf("expected mismatch %t, got error %v", tt.wantMismatch, err)
+			}
+			if !tt.wantMismatch && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if verified != tt.wantVerified {
+				t.Errorf("expected verified %t, got %t", tt.wantVerified, verified)
+			}
+		})
+	}
+}
+
+func TestIdentityVerifier_VerifyIdentity(t *testing.T) {
+	manager, err := kubeconfig.NewManager(filepath.Join(t.TempDir(), "mappings.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	for _, name := range []string{"prod-us-1", "prod-eu-1"} {
+		if err := manager.SetMapping(name, name, ""); err != nil {
+			t.Fatalf("failed to set mapping: %v", err)
+		}
+	}
+	if err := manager.SetKubeSystemUID("prod-eu-1", "uid-eu"); err != nil {
+		t.Fatalf("failed to set kube-system UID: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		cluster      string
+		objects      []runtime.Object
+		wantMismatch bool
+		wantResource string
+	}{
+		{name: "cluster ID without recorded UID", cluster: "prod-us-1", objects: identityObjects("uid-us", "prod-us-1"), wantResource: "clusterproperties"},
+		{name: "other cluster ID", cluster: "prod-us-1", objects: identityObjects("uid-us", "prod-eu-1"), wantMismatch: true, wantResource: "clusterproperties"},
+		{name: "recorded UID", cluster: "prod-eu-1", objects: identityObjects("uid-eu", "prod-us-1"), wantResource: "namespaces"},
+		{name: "other recorded UID", cluster: "prod-eu-1", objects: identityObjects("uid-us", "prod-eu-1"), wantMismatch: true, wantResource: "namespaces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), tt.objects...)
+			provider := NewFakeProvider()
+			provider.SetClients(tt.cluster, FakeClusterClients{Dynamic: dynamicClient})
+
+			err := NewIdentityVerifier(provider, manager).VerifyIdentity(context.Background(), mcdiscovery.ClusterInfo{Name: tt.cluster})
+			var mismatch *IdentityMismatchError
+			if errors.As(err, &mismatch) != tt.wantMismatch {
+				t.Fatalf("expected mismatch %t, got error %v", tt.wantMismatch, err)
+			}
+			if !tt.wantMismatch && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Each check is a single read
+			actions := dynamicClient.Actions()
+			if len(actions) != 1 {
+				t.Fatalf("expected 1 request, got %d", len(actions))
+			}
+			if actions[0].GetResource().Resource != tt.wantResource {
+				t.Errorf("expected a read of %s, got %s", tt.wantResource, actions[0].GetResource().Resource)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"fmt"
+
+	mcdiscovery "github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// ClusterClientProvider returns Kubernetes clients for a discovered cluster.
+// The executor uses it for every per-cluster operation, which allows the
+// multi-cluster logic to be exercised against fake clients.
+type ClusterClientProvider interface {
+	// RESTConfig returns the REST config used to reach the cluster
+	RESTConfig(cluster mcdiscovery.ClusterInfo) (*rest.Config, error)
+
+	// DynamicClient returns a dynamic client for the cluster
+	DynamicClient(cluster mcdiscovery.ClusterInfo) (dynamic.Interface, error)
+
+	// DiscoveryClient returns a discovery client for the cluster
+	DiscoveryClient(cluster mcdiscovery.ClusterInfo) (discovery.DiscoveryInterface, error)
+
+	// Clientset returns a typed Kubernetes client for the cluster
+	Clientset(cluster mcdiscovery.ClusterInfo) (kubernetes.Interface, error)
+
+	// Namespace returns the default namespace of the cluster's context
+	Namespace(cluster mcdiscovery.ClusterInfo) (string, error)
+}
+
+// KubeconfigProvider implements ClusterClientProvider using the cluster-to-context
+// mapping file and the user's kubeconfig
+type KubeconfigProvider struct {
+	mappingManager *kubeconfig.Manager
+	configFlags    *genericclioptions.ConfigFlags
+}
+
+// NewKubeconfigProvider creates a provider that resolves clusters through kubeconfig contexts
+func NewKubeconfigProvider(mappingManager *kubeconfig.Manager, configFlags *genericclioptions.ConfigFlags) *KubeconfigProvider {
+	return &KubeconfigProvider{
+		mappingManager: mappingManager,
+		configFlags:    configFlags,
+	}
+}
+
+// Context returns the kubeconfig context mapped to the cluster
+func (p *KubeconfigProvider) Context(cluster mcdiscovery.ClusterInfo) (string, error) {
+	contextName, err := p.mappingManager.GetContext(cluster.Name)
+	if err != nil {
+		return "", fmt.Errorf("no kubeconfig context mapped for cluster %s", cluster.Name)
+	}
+	return contextName, nil
+}
+
+// factory returns a client factory for the cluster's mapping
+func (p *KubeconfigProvider) factory(cluster mcdiscovery.ClusterInfo) (*Factory, error) {
+	mapping, ok := p.mappingManager.GetMapping(cluster.Name)
+	if !ok {
+		return nil, fmt.Errorf("no kubeconfig context mapped for cluster %s", cluster.Name)
+	}
+
+	factory, err := NewFactoryForMapping(mapping, p.configFlags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client factory: %w", err)
+	}
+
+	return factory, nil
+}
+
+// RESTConfig returns the REST config for the cluster's mapped context
+func (p *KubeconfigProvider) RESTConfig(cluster mcdiscovery.ClusterInfo) (*rest.Config, error) {
+	factory, err := p.factory(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return factory.RESTConfig()
+}
+
+// DynamicClient returns a dynamic client for the cluster's mapped context
+func (p *KubeconfigProvider) DynamicClient(cluster mcdiscovery.ClusterInfo) (dynamic.Interface, error) {
+	factory, err := p.factory(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return factory.DynamicClient()
+}
+
+// DiscoveryClient returns a discovery client for the cluster's mapped context
+func (p *KubeconfigProvider) DiscoveryClient(cluster mcdiscovery.ClusterInfo) (discovery.DiscoveryInterface, error) {
+	factory, err := p.factory(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return factory.DiscoveryClient()
+}
+
+// Clientset returns a typed client for the cluster's mapped context
+func (p *KubeconfigProvider) Clientset(cluster mcdiscovery.ClusterInfo) (kubernetes.Interface, error) {
+	factory, err := p.factory(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := factory.Clientset()
+	if err != nil {
+		return nil, err
+	}
+	return clientset, nil
+}
+
+// Namespace returns the namespace of the cluster's mapped context, or the
+// mapping's defaultNamespace when set; "default" when neither names one
+func (p *KubeconfigProvider) Namespace(cluster mcdiscovery.ClusterInfo) (string, error) {
+	factory, err := p.factory(cluster)
+	if err != nil {
+		return "", err
+	}
+	return factory.Namespace()
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+
+	mcdiscovery "github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+)
+
+func TestKubeconfigProviderContext(t *testing.T) {
+	manager, err := kubeconfig.NewManager(filepath.Join(t.TempDir(), "mappings.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	if err := manager.SetMapping("cluster1", "context1", ""); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+
+	provider := NewKubeconfigProvider(manager, genericclioptions.NewConfigFlags(true))
+
+	contextName, err := provider.Context(mcdiscovery.ClusterInfo{Name: "cluster1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if contextName != "context1" {
+		t.Errorf("expected context1, got %s", contextName)
+	}
+
+	// Unmapped clusters fail before any client is built
+	if _, err := provider.DynamicClient(mcdiscovery.ClusterInfo{Name: "unmapped"}); err == nil {
+		t.Error("expected error for unmapped cluster")
+	}
+	if _, err := provider.Clientset(mcdiscovery.ClusterInfo{Name: "unmapped"}); err == nil {
+		t.Error("expected error for unmapped cluster")
+	}
+}
+
+func TestKubeconfigProviderMappingOverrides(t *testing.T) {
+	manager, err := kubeconfig.NewManager(filepath.Join(t.TempDir(), "mappings.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	mapping := kubeconfig.ClusterMapping{Name: "prod-eu-1", Context: "admin@prod-eu-1", Kubeconfig: testKubeconfig(t), As: "deployer", DefaultNamespace: "payments"}
+	if err := manager.ImportMappings([]kubeconfig.ClusterMapping{mapping}, false); err != nil {
+		t.Fatalf("failed to import mapping: %v", err)
+	}
+
+	// The cluster's own kubeconfig file is read, whatever $KUBECONFIG says
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "missing"))
+	provider := NewKubeconfigProvider(manager, genericclioptions.NewConfigFlags(true))
+	config, err := provider.RESTConfig(mcdiscovery.ClusterInfo{Name: "prod-eu-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Host != "https://10.0.0.1:6443" || config.Impersonate.UserName != "deployer" {
+		t.Errorf("expected the mapping's kubeconfig and impersonation, got host %s as %q", config.Host, config.Impersonate.UserName)
+	}
+
+	namespace, err := provider.Namespace(mcdiscovery.ClusterInfo{Name: "prod-eu-1"})
+	if err != nil || namespace != "payments" {
+		t.Errorf("expected the mapping's default namespace, got %q (err: %v)", namespace, err)
+	}
+}
+
+func TestFakeProvider(t *testing.T) {
+	provider := NewFakeProvider()
+	clientset := kubernetesfake.NewClientset()
+	config := &rest.Config{Host: "https://cluster1.example.com"}
+
+	provider.SetClients("cluster1", FakeClusterClients{
+		RESTConfig: config,
+		Clientset:  clientset,
+	})
+
+	cluster := mcdiscovery.ClusterInfo{Name: "cluster1"}
+
+	gotConfig, err := provider.RESTConfig(cluster)
+	if err != nil || gotConfig != config {
+		t.Errorf("expected registered REST config, got %v (err: %v)", gotConfig, err)
+	}
+
+	gotClientset, err := provider.Clientset(cluster)
+	if err != nil || gotClientset != clientset {
+		t.Errorf("expected registered clientset, got %v (err: %v)", gotClientset, err)
+	}
+
+	// Discovery defaults to the clientset's discovery client
+	if _, err := provider.DiscoveryClient(cluster); err != nil {
+		t.Errorf("expected discovery client from clientset, got error: %v", err)
+	}
+
+	// No dynamic client was registered
+	if _, err := provider.DynamicClient(cluster); err == nil {
+		t.Error("expected error for missing dynamic client")
+	}
+
+	if _, err := provider.Clientset(mcdiscovery.ClusterInfo{Name: "unknown"}); err == nil {
+		t.Error("expected error for unknown cluster")
+	}
+}
@@ -0,0 +1,519 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// APIVersion is the apiVersion of the configuration file
+	APIVersion = "kubectl-mc.k8s.io/v1alpha1"
+
+	// Kind is the kind of the configuration file
+	Kind = "Config"
+
+	// PathEnvVar overrides the default configuration file location
+	PathEnvVar = "KUBECTL_MC_CONFIG"
+)
+
+// DiscoveryClusterProfile discovers clusters from ClusterProfile resources on the hub
+const DiscoveryClusterProfile = "clusterprofile"
+
+// Namespace modes, used when -n is not given
+const (
+	// NamespaceModeSingle queries the same namespace on every cluster
+	NamespaceModeSingle = "single"
+
+	// NamespaceModePerCluster queries the namespace of each cluster's mapped
+	// context, or its mapping's defaultNamespace
+	NamespaceModePerCluster = "per-cluster"
+)
+
+// Cluster column positions, used by output.clusterColumn
+const (
+	ClusterColumnFirst  = "first"
+	ClusterColumnLast   = "last"
+	ClusterColumnHidden = "hidden"
+)
+
+// Default returns the configuration used when no file exists
+func Default() *Config {
+	return &Config{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Discovery: DiscoveryConfig{
+			API:      DiscoveryClusterProfile,
+			CacheTTL: Duration(5 * time.Minute),
+		},
+		Execution: ExecutionConfig{
+			MaxConcurrency:  10,
+			Timeout:         Duration(30 * time.Second),
+			ContinueOnError: true,
+			VerifyIdentity:  true,
+			NamespaceMode:   NamespaceModeSingle,
+		},
+		Output: OutputConfig{
+			Colorize:      true,
+			ClusterColumn: ClusterColumnFirst,
+		},
+	}
+}
+
+// DefaultPath returns the default configuration file location, ~/.kube/kubectl-mc-config.yaml
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".kube", "kubectl-mc-config.yaml"), nil
+}
+
+// Load reads the configuration file at path on top of the defaults. A missing
+// file yields the defaults; unknown fields are ignored so older plugins can
+// read newer files, but invalid values are rejected.
+func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Read parses the configuration file at path on top of the defaults without
+// validating it, so a broken file can still be repaired with Set
+func Read(path string) (*Config, error) {
+	cfg := Default()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// ValidateFile checks the configuration file at path, rejecting unknown fields as well as invalid values
+func ValidateFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	cfg := Default()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return cfg.Validate()
+}
+
+// Save writes the given keys of the configuration, such as execution.timeout
+// or hubs, into the file at path. The rest of the file is kept as it is, so
+// settings the user never set keep following the defaults. The file is
+// replaced atomically: readers see either the old or the new version.
+func (c *Config) Save(path string, keys ...string) error {
+	doc, err := readDocument(path)
+	if err != nil {
+		return err
+	}
+	root := doc.Content[0]
+
+	var current yaml.Node
+	if err := current.Encode(c); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	for _, key := range append([]string{"apiVersion", "kind"}, keys...) {
+		if s, ok := lookupSetting(key); ok {
+			key = s.key
+		}
+		path := strings.Split(key, ".")
+		if value := mappingValue(&current, path); value != nil {
+			setMappingValue(root, path, value)
+		} else {
+			deleteMappingValue(root, path)
+		}
+	}
+
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// readDocument parses the configuration file at path as a YAML document whose
+// root is a mapping; a missing or empty file yields an empty mapping
+func readDocument(path string) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(bytes.TrimSpace(data)) > 0 {
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config file %s: expected a mapping", path)
+	}
+	return doc, nil
+}
+
+// mappingValue returns the node at path in a mapping node, or nil
+func mappingValue(node *yaml.Node, path []string) *yaml.Node {
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// setMappingValue sets the node at path in a mapping node, creating the
+// intermediate mappings
+func setMappingValue(node *yaml.Node, path []string, value *yaml.Node) {
+	for i, key := range path {
+		last := i == len(path)-1
+
+		var next *yaml.Node
+		for j := 0; j+1 < len(node.Content); j += 2 {
+			if node.Content[j].Value == key {
+				if last {
+					node.Content[j+1] = value
+					return
+				}
+				next = node.Content[j+1]
+				break
+			}
+		}
+
+		if next == nil || next.Kind != yaml.MappingNode {
+			child := value
+			if !last {
+				child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			}
+			if next == nil {
+				node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+			} else {
+				*next = *child
+			}
+			if last {
+				return
+			}
+			next = child
+		}
+		node = next
+	}
+}
+
+// deleteMappingValue removes the node at path from a mapping node
+func deleteMappingValue(node *yaml.Node, path []string) {
+	parent := mappingValue(node, path[:len(path)-1])
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return
+	}
+	key := path[len(path)-1]
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// writeFileAtomic replaces the file at path with data through a temporary
+// file renamed over it, keeping the permissions of the existing file
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
+	return nil
+}
+
+// Validate checks that every setting has a supported value. Cluster
+// expressions and context templates are checked by the packages that
+// evaluate them.
+func (c *Config) Validate() error {
+	if c.APIVersion != "" && c.APIVersion != APIVersion {
+		return fmt.Errorf("unsupported apiVersion %q (expected %s)", c.APIVersion, APIVersion)
+	}
+	if c.Kind != "" && c.Kind != Kind {
+		return fmt.Errorf("unsupported kind %q (expected %s)", c.Kind, Kind)
+	}
+
+	if err := validateDiscoveryAPI(c.Discovery.API); err != nil {
+		return fmt.Errorf("invalid discovery.api: %w", err)
+	}
+	if c.Discovery.CacheTTL < 0 {
+		return fmt.Errorf("discovery.cacheTTL must not be negative")
+	}
+
+	if c.Execution.MaxConcurrency < 1 {
+		return fmt.Errorf("execution.maxConcurrency must be at least 1")
+	}
+	if c.Execution.Timeout <= 0 {
+		return fmt.Errorf("execution.timeout must be positive")
+	}
+	switch c.Execution.NamespaceMode {
+	case NamespaceModeSingle, NamespaceModePerCluster:
+	default:
+		return fmt.Errorf("invalid execution.namespaceMode %q (allowed: single, per-cluster)", c.Execution.NamespaceMode)
+	}
+
+	switch c.Output.ClusterColumn {
+	case ClusterColumnFirst, ClusterColumnLast, ClusterColumnHidden:
+	default:
+		return fmt.Errorf("invalid output.clusterColumn %q (allowed: first, last, hidden)", c.Output.ClusterColumn)
+	}
+
+	for name, hub := range c.Hubs {
+		if hub.DiscoveryAPI != "" {
+			if err := validateDiscoveryAPI(hub.DiscoveryAPI); err != nil {
+				return fmt.Errorf("invalid discoveryAPI for hub %q: %w", name, err)
+			}
+		}
+	}
+	if c.CurrentHub != "" {
+		if _, ok := c.Hubs[c.CurrentHub]; !ok {
+			return fmt.Errorf("currentHub %q is not a saved hub profile", c.CurrentHub)
+		}
+	}
+	return nil
+}
+
+// validateDiscoveryAPI checks that clusters can be discovered with the named API
+func validateDiscoveryAPI(api string) error {
+	switch api {
+	case DiscoveryClusterProfile:
+		return nil
+	case "about", "inventory":
+		return fmt.Errorf("%q is not supported yet (supported: %s)", api, DiscoveryClusterProfile)
+	default:
+		return fmt.Errorf("unknown API %q (supported: %s)", api, DiscoveryClusterProfile)
+	}
+}
+
+// ActiveHub returns the hub profile in use, named by CurrentHub, and false when there is none
+func (c *Config) ActiveHub() (string, HubProfile, bool) {
+	if c.CurrentHub == "" {
+		return "", HubProfile{}, false
+	}
+	hub, ok := c.Hubs[c.CurrentHub]
+	return c.CurrentHub, hub, ok
+}
+
+// SetHub saves a hub profile, replacing any profile with the same name
+func (c *Config) SetHub(name string, hub HubProfile) error {
+	if name == "" {
+		return fmt.Errorf("hub profile name must not be empty")
+	}
+	if c.Hubs == nil {
+		c.Hubs = make(map[string]HubProfile)
+	}
+	c.Hubs[name] = hub
+	return c.Validate()
+}
+
+// UseHub makes the named hub profile the current one; an empty name clears it
+func (c *Config) UseHub(name string) error {
+	if name != "" {
+		if _, ok := c.Hubs[name]; !ok {
+			return fmt.Errorf("no hub profile named %q", name)
+		}
+	}
+	c.CurrentHub = name
+	return nil
+}
+
+// setting is a configuration key that can be changed with `config set` or an environment variable
+type setting struct {
+	key    string
+	envVar string
+	set    func(c *Config, value string) error
+}
+
+// settings lists every configurable key, in file order
+var settings = []setting{
+	{key: "discovery.api", envVar: "KUBECTL_MC_DISCOVERY_API", set: func(c *Config, value string) error {
+		c.Discovery.API = value
+		return nil
+	}},
+	{key: "discovery.cacheTTL", envVar: "KUBECTL_MC_CACHE_TTL", set: func(c *Config, value string) error {
+		return setDuration(&c.Discovery.CacheTTL, value)
+	}},
+	{key: "execution.maxConcurrency", envVar: "KUBECTL_MC_MAX_CONCURRENCY", set: func(c *Config, value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", value)
+		}
+		c.Execution.MaxConcurrency = n
+		return nil
+	}},
+	{key: "execution.timeout", envVar: "KUBECTL_MC_TIMEOUT", set: func(c *Config, value string) error {
+		return setDuration(&c.Execution.Timeout, value)
+	}},
+	{key: "execution.continueOnError", envVar: "KUBECTL_MC_CONTINUE_ON_ERROR", set: func(c *Config, value string) error {
+		return setBool(&c.Execution.ContinueOnError, value)
+	}},
+	{key: "execution.verifyIdentity", envVar: "KUBECTL_MC_VERIFY_IDENTITY", set: func(c *Config, value string) error {
+		return setBool(&c.Execution.VerifyIdentity, value)
+	}},
+	{key: "execution.namespaceMode", envVar: "KUBECTL_MC_NAMESPACE_MODE", set: func(c *Config, value string) error {
+		c.Execution.NamespaceMode = value
+		return nil
+	}},
+	{key: "output.colorize", envVar: "KUBECTL_MC_COLORIZE", set: func(c *Config, value string) error {
+		return setBool(&c.Output.Colorize, value)
+	}},
+	{key: "output.clusterColumn", envVar: "KUBECTL_MC_CLUSTER_COLUMN", set: func(c *Config, value string) error {
+		c.Output.ClusterColumn = value
+		return nil
+	}},
+	{key: "currentHub", envVar: "KUBECTL_MC_HUB", set: func(c *Config, value string) error {
+		return c.UseHub(value)
+	}},
+}
+
+// Keys returns the configurable keys, e.g. execution.timeout
+func Keys() []string {
+	keys := make([]string, 0, len(settings))
+	for _, s := range settings {
+		keys = append(keys, s.key)
+	}
+	return keys
+}
+
+// EnvVars returns the environment variable overriding each key
+func EnvVars() map[string]string {
+	envVars := make(map[string]string, len(settings))
+	for _, s := range settings {
+		envVars[s.key] = s.envVar
+	}
+	return envVars
+}
+
+// Set changes the value of a key and validates the result
+func (c *Config) Set(key, value string) error {
+	s, ok := lookupSetting(key)
+	if !ok {
+		return fmt.Errorf("unknown config key %q (allowed: %s)", key, strings.Join(Keys(), ", "))
+	}
+	if err := s.set(c, value); err != nil {
+		return fmt.Errorf("invalid value for %s: %w", s.key, err)
+	}
+	return c.Validate()
+}
+
+// lookupSetting finds a configurable key, ignoring case
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if strings.EqualFold(s.key, key) {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// ApplyEnv overrides settings with the KUBECTL_MC_* environment variables
+// found by lookup, typically os.LookupEnv
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	for _, s := range settings {
+		value, ok := lookup(s.envVar)
+		if !ok || value == "" {
+			continue
+		}
+		if err := s.set(c, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", s.envVar, err)
+		}
+	}
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("invalid KUBECTL_MC_* environment: %w", err)
+	}
+	return nil
+}
+
+// setDuration parses a duration such as 30s or 5m
+func setDuration(d *Duration, value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("expected a duration such as 30s or 5m, got %q", value)
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// setBool parses true or false
+func setBool(b *bool, value string) error {
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("expected true or false, got %q", value)
+	}
+	*b = parsed
+	return nil
+}
@@ -0,0 +1,384 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeConfig writes a configuration file and returns its path
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad_MissingFile(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("expected defaults, got %+v", cfg)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `apiVersion: kubectl-mc.k8s.io/v1alpha1
+kind: Config
+discovery:
+  cacheTTL: 1m
+execution:
+  maxConcurrency: 20
+  continueOnError: false
+  namespaceMode: per-cluster
+output:
+  clusterColumn: last
+  futureSetting: true
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if time.Duration(cfg.Discovery.CacheTTL) != time.Minute {
+		t.Errorf("expected cacheTTL 1m, got %v", time.Duration(cfg.Discovery.CacheTTL))
+	}
+	if cfg.Execution.MaxConcurrency != 20 || cfg.Execution.ContinueOnError || cfg.Execution.NamespaceMode != NamespaceModePerCluster {
+		t.Errorf("unexpected execution settings %+v", cfg.Execution)
+	}
+	if cfg.Output.ClusterColumn != "last" {
+		t.Errorf("expected clusterColumn last, got %q", cfg.Output.ClusterColumn)
+	}
+
+	// Settings missing from the file keep their defaults
+	if time.Duration(cfg.Execution.Timeout) != 30*time.Second || cfg.Discovery.API != DiscoveryClusterProfile || !cfg.Output.Colorize {
+		t.Errorf("expected defaults for unset fields, got %+v", cfg)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "bad duration", content: "execution:\n  timeout: soon\n", wantErr: "invalid duration"},
+		{name: "zero concurrency", content: "execution:\n  maxConcurrency: 0\n", wantErr: "maxConcurrency"},
+		{name: "unsupported api", content: "discovery:\n  api: inventory\n", wantErr: "not supported yet"},
+		{name: "bad cluster column", content: "output:\n  clusterColumn: middle\n", wantErr: "clusterColumn"},
+		{name: "bad namespace mode", content: "execution:\n  namespaceMode: all\n", wantErr: "namespaceMode"},
+		{name: "wrong kind", content: "kind: ClusterMapping\n", wantErr: "unsupported kind"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateFile_UnknownField(t *testing.T) {
+	path := writeConfig(t, "output:\n  showClusterColumn: true\n")
+
+	// Load ignores unknown fields, validate reports them
+	if _, err := Load(path); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateFile(path); err == nil || !strings.Contains(err.Error(), "showClusterColumn") {
+		t.Errorf("expected unknown field error, got %v", err)
+	}
+	if err := ValidateFile(writeConfig(t, "")); err != nil {
+		t.Errorf("expected an empty file to be valid, got %v", err)
+	}
+}
+
+func TestSet(t *testing.T) {
+	cfg := Default()
+
+	if err := cfg.Set("execution.timeout", "1m30s"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if time.Duration(cfg.Execution.Timeout) != 90*time.Second {
+		t.Errorf("expected timeout 1m30s, got %v", time.Duration(cfg.Execution.Timeout))
+	}
+
+	if err := cfg.Set("output.colorize", "false"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Output.Colorize {
+		t.Error("expected colorize to be disabled")
+	}
+
+	for _, tt := range []struct{ key, value string }{
+		{"execution.maxConcurrency", "many"},
+		{"execution.maxConcurrency", "-1"},
+		{"output.clusterColumn", "middle"},
+		{"execution.namespaceMode", "each"},
+		{"output.color", "true"},
+	} {
+		if err := cfg.Set(tt.key, tt.value); err == nil {
+			t.Errorf("Set(%s, %s): expected error", tt.key, tt.value)
+		}
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "config.yaml")
+	cfg := Default()
+	if err := cfg.Set("discovery.cacheTTL", "0s"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cfg.Save(path, "discovery.cacheTTL"); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("expected %+v, got %+v", cfg, loaded)
+	}
+	if err := ValidateFile(path); err != nil {
+		t.Errorf("expected saved file to be valid, got %v", err)
+	}
+}
+
+func TestSave_OnlyGivenKeys(t *testing.T) {
+	path := writeConfig(t, `# managed by hand
+apiVersion: kubectl-mc.k8s.io/v1alpha1
+kind: Config
+execution:
+  maxConcurrency: 4 # keep low
+`)
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if err := cfg.Set("Execution.Timeout", "45s"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cfg.Save(path, "Execution.Timeout"); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{"# managed by hand", "maxConcurrency: 4 # keep low", "timeout: 45s"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected saved config to contain %q, got:\n%s", want, content)
+		}
+	}
+	for _, unwanted := range []string{"cacheTTL", "verifyIdentity", "output:"} {
+		if strings.Contains(content, unwanted) {
+			t.Errorf("expected defaults not to be written, found %q in:\n%s", unwanted, content)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat config: %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("failed to list config directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary files to be left, got %d entries", len(entries))
+	}
+}
+
+func TestSave_RemovesUnsetKey(t *testing.T) {
+	path := writeConfig(t, "apiVersion: kubectl-mc.k8s.io/v1alpha1\nkind: Config\ncurrentHub: prod\nhubs:\n  prod:\n    context: prod-hub\n")
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	cfg.CurrentHub = ""
+	if err := cfg.Save(path, "hubs", "currentHub"); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read saved config: %v", err)
+	}
+	if strings.Contains(string(data), "currentHub") {
+		t.Errorf("expected currentHub to be removed, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "context: prod-hub") {
+		t.Errorf("expected hubs to be kept, got:\n%s", data)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"KUBECTL_MC_MAX_CONCURRENCY": "3",
+		"KUBECTL_MC_CLUSTER_COLUMN":  "hidden",
+		"KUBECTL_MC_TIMEOUT":         "",
+	}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	cfg := Default()
+	if err := cfg.ApplyEnv(lookup); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Execution.MaxConcurrency != 3 || cfg.Output.ClusterColumn != "hidden" {
+		t.Errorf("expected environment to override defaults, got %+v", cfg)
+	}
+	if time.Duration(cfg.Execution.Timeout) != 30*time.Second {
+		t.Errorf("expected empty variables to be ignored, got timeout %v", time.Duration(cfg.Execution.Timeout))
+	}
+
+	env["KUBECTL_MC_CONTINUE_ON_ERROR"] = "maybe"
+	if err := cfg.ApplyEnv(lookup); err == nil || !strings.Contains(err.Error(), "KUBECTL_MC_CONTINUE_ON_ERROR") {
+		t.Errorf("expected error naming the variable, got %v", err)
+	}
+}
+
+func TestLoad_ClusterGroups(t *testing.T) {
+	path := writeConfig(t, `clusterGroups:
+  prod-eu: [prod-fra-*, prod-ams-1]
+  gpu:
+    selector: accel=nvidia
+  prod-gpu: ["@prod-eu&@gpu"]
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.ClusterGroups["prod-eu"].Clusters; len(got) != 2 || got[0] != "prod-fra-*" {
+		t.Errorf("expected the list form to set clusters, got %v", got)
+	}
+	if got := cfg.ClusterGroups["gpu"].Selector; got != "accel=nvidia" {
+		t.Errorf("expected the mapping form to set the selector, got %q", got)
+	}
+}
+
+func TestClusterGroupYAML(t *testing.T) {
+	groups := map[string]ClusterGroup{
+		"prod-eu": {Clusters: []string{"prod-fra-*", "prod-ams-1"}},
+		"gpu":     {Selector: "accel=nvidia"},
+	}
+
+	out, err := yaml.Marshal(groups)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(out), "prod-eu:\n    - prod-fra-*") || !strings.Contains(string(out), "selector: accel=nvidia") {
+		t.Errorf("expected the short list form for pattern groups, got:\n%s", out)
+	}
+
+	var parsed map[string]ClusterGroup
+	if err := yaml.Unmarshal(out, &parsed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, groups) {
+		t.Errorf("expected %v, got %v", groups, parsed)
+	}
+}
+
+func TestLoad_Hubs(t *testing.T) {
+	path := writeConfig(t, `hubs:
+  prod:
+    context: prod-hub
+    namespace: fleet
+    exclude: ["*-canary"]
+  dev:
+    context: kind-hub
+currentHub: prod
+`)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	name, hub, ok := cfg.ActiveHub()
+	if !ok || name != "prod" || hub.Context != "prod-hub" || hub.Namespace != "fleet" {
+		t.Errorf("expected the prod hub to be active, got %q %+v", name, hub)
+	}
+
+	if err := cfg.Set("currentHub", "dev"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if name, _, _ := cfg.ActiveHub(); name != "dev" {
+		t.Errorf("expected the dev hub to be active, got %q", name)
+	}
+	if err := cfg.UseHub("missing"); err == nil {
+		t.Error("expected switching to an unknown hub to fail")
+	}
+	if err := cfg.UseHub(""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, ok := cfg.ActiveHub(); ok {
+		t.Error("expected no active hub after unsetting it")
+	}
+}
+
+func TestLoad_InvalidHubs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "unknown current hub", content: "currentHub: prod\n", wantErr: "not a saved hub profile"},
+		{name: "unsupported api", content: "hubs:\n  prod:\n    discoveryAPI: about\n", wantErr: "not supported yet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSetHub(t *testing.T) {
+	cfg := Default()
+	if err := cfg.SetHub("prod", HubProfile{Context: "prod-hub", Clusters: []string{"prod-*"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Hubs["prod"].Context != "prod-hub" {
+		t.Errorf("expected the profile to be saved, got %+v", cfg.Hubs)
+	}
+	if err := cfg.SetHub("", HubProfile{}); err == nil {
+		t.Error("expected an empty profile name to be rejected")
+	}
+	if err := cfg.SetHub("bad", HubProfile{DiscoveryAPI: "unknown"}); err == nil {
+		t.Error("expected an unknown discovery API to be rejected")
+	}
+}
+
+func TestLoad_ContextTemplates(t *testing.T) {
+	cfg, err := Load(writeConfig(t, "setup:\n  contextTemplates: [\"kind-{{.Name}}\"]\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Setup.ContextTemplates) != 1 || cfg.Setup.ContextTemplates[0] != "kind-{{.Name}}" {
+		t.Errorf("expected one context template, got %v", cfg.Setup.ContextTemplates)
+	}
+}
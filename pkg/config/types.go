@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the kubectl-mc plugin configuration file format
+type Config struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+
+	// Discovery configures how clusters are discovered from the hub
+	Discovery DiscoveryConfig `yaml:"discovery"`
+
+	// Execution configures how commands run against the clusters
+	Execution ExecutionConfig `yaml:"execution"`
+
+	// Output configures how results are printed
+	Output OutputConfig `yaml:"output"`
+
+	// Setup configures how `kubectl mc setup` maps clusters to contexts
+	Setup SetupConfig `yaml:"setup,omitempty"`
+
+	// ClusterGroups are named sets of clusters, referenced as @name in --clusters and --exclude
+	ClusterGroups map[string]ClusterGroup `yaml:"clusterGroups,omitempty"`
+
+	// Hubs are saved hub profiles, switched between with `kubectl mc use-hub`
+	Hubs map[string]HubProfile `yaml:"hubs,omitempty"`
+
+	// CurrentHub is the hub profile commands use when no hub flag is given
+	CurrentHub string `yaml:"currentHub,omitempty"`
+}
+
+// ClusterGroup is a named set of clusters, defined by name patterns, a
+// ClusterProfile label selector, or both. It is written either as a list of
+// patterns (prod-eu: [prod-fra-*, prod-ams-1]) or as a mapping
+// (gpu: {selector: accel=nvidia}).
+type ClusterGroup struct {
+	// Clusters are cluster expressions: names, globs and @group references,
+	// optionally combined with & and !
+	Clusters []string `yaml:"clusters,omitempty"`
+
+	// Selector selects clusters by ClusterProfile labels, using kubectl's -l syntax
+	Selector string `yaml:"selector,omitempty"`
+}
+
+// UnmarshalYAML accepts a list of cluster expressions as well as the mapping form
+func (g *ClusterGroup) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.SequenceNode {
+		return value.Decode(&g.Clusters)
+	}
+
+	// Decode through an alias type to avoid recursing into this method
+	type plain ClusterGroup
+	return value.Decode((*plain)(g))
+}
+
+// MarshalYAML writes groups without a selector in the short list form
+func (g ClusterGroup) MarshalYAML() (interface{}, error) {
+	if g.Selector == "" {
+		return g.Clusters, nil
+	}
+	type plain ClusterGroup
+	return plain(g), nil
+}
+
+// HubProfile is a saved hub cluster and the defaults that go with it
+type HubProfile struct {
+	// Context is the kubeconfig context of the hub; empty uses the current context
+	Context string `yaml:"context,omitempty"`
+
+	// Namespace is where the hub's ClusterProfile resources are located
+	Namespace string `yaml:"namespace,omitempty"`
+
+	// DiscoveryAPI overrides discovery.api for this hub
+	DiscoveryAPI string `yaml:"discoveryAPI,omitempty"`
+
+	// Clusters and Exclude are the default --clusters and --exclude expressions
+	Clusters []string `yaml:"clusters,omitempty"`
+	Exclude  []string `yaml:"exclude,omitempty"`
+}
+
+// DiscoveryConfig configures cluster discovery
+type DiscoveryConfig struct {
+	// API is the sig-multicluster API clusters are discovered with
+	API string `yaml:"api"`
+
+	// CacheTTL is how long discovered clusters are cached; 0 disables the cache
+	CacheTTL Duration `yaml:"cacheTTL"`
+}
+
+// ExecutionConfig configures multi-cluster execution
+type ExecutionConfig struct {
+	// MaxConcurrency is the number of clusters queried at once
+	MaxConcurrency int `yaml:"maxConcurrency"`
+
+	// Timeout bounds the operation on each cluster
+	Timeout Duration `yaml:"timeout"`
+
+	// ContinueOnError keeps querying the remaining clusters after one fails
+	ContinueOnError bool `yaml:"continueOnError"`
+
+	// VerifyIdentity refuses clusters whose context reaches another cluster,
+	// checked through the About API cluster ID or the recorded kube-system UID.
+	// Each check is one GET per cluster, comparing the recorded UID when the
+	// mapping has one. Set false to skip it.
+	VerifyIdentity bool `yaml:"verifyIdentity"`
+
+	// NamespaceMode is the namespace queried when -n is not given: single uses
+	// the same namespace on every cluster, per-cluster each cluster's own
+	NamespaceMode string `yaml:"namespaceMode"`
+}
+
+// OutputConfig configures output formatting
+type OutputConfig struct {
+	// Colorize highlights warnings and errors when writing to a terminal
+	Colorize bool `yaml:"colorize"`
+
+	// ClusterColumn is the default position of the CLUSTER column: first, last or hidden
+	ClusterColumn string `yaml:"clusterColumn"`
+}
+
+// SetupConfig configures cluster-to-context mapping
+type SetupConfig struct {
+	// ContextTemplates render candidate context names from a cluster for
+	// `setup --auto`, e.g. "kind-{{.Name}}" or "arn:aws:eks:*:*:cluster/{{.Name}}"
+	ContextTemplates []string `yaml:"contextTemplates,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as 30s or 5m
+type Duration time.Duration
+
+// MarshalYAML writes the duration as a string
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return time.Duration(d).String(), nil
+}
+
+// UnmarshalYAML parses a duration string
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q at line %d: %w", value.Value, value.Line, err)
+	}
+	*d = Duration(parsed)
+	return nil
+}
@@ -0,0 +1,107 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cacheEntry is the on-disk format of the discovery cache
+type cacheEntry struct {
+	FetchedAt time.Time     `json:"fetchedAt"`
+	Clusters  []ClusterInfo `json:"clusters"`
+}
+
+// CachedDiscovery serves ListClusters from a file cache while it is younger
+// than the TTL, to spare the hub a list on every command
+type CachedDiscovery struct {
+	discovery Discovery
+	path      string
+	ttl       time.Duration
+
+	// refresh ignores the cached clusters, but still updates the cache
+	refresh bool
+
+	now func() time.Time
+}
+
+// NewCachedDiscovery caches the clusters listed by discovery in the file at path for ttl
+func NewCachedDiscovery(discovery Discovery, path string, ttl time.Duration) *CachedDiscovery {
+	return &CachedDiscovery{
+		discovery: discovery,
+		path:      path,
+		ttl:       ttl,
+		now:       time.Now,
+	}
+}
+
+// CachePath returns the cache file for the clusters of a hub context and namespace, under dir
+func CachePath(dir, hubContext, namespace string) string {
+	name := strings.NewReplacer("/", "_", ":", "_", "\\", "_").Replace(hubContext + "_" + namespace)
+	return filepath.Join(dir, name+".json")
+}
+
+// SetRefresh makes ListClusters query the hub even when the cache is fresh
+func (c *CachedDiscovery) SetRefresh(refresh bool) {
+	c.refresh = refresh
+}
+
+// ListClusters returns the cached clusters when fresh, otherwise lists them and updates the cache
+func (c *CachedDiscovery) ListClusters(ctx context.Context) ([]ClusterInfo, error) {
+	if !c.refresh {
+		if clusters, ok := c.read(); ok {
+			return clusters, nil
+		}
+	}
+
+	clusters, err := c.discovery.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// A cache that cannot be written only costs the next command a list
+	_ = c.write(clusters)
+	return clusters, nil
+}
+
+// GetCluster is always answered by the hub
+func (c *CachedDiscovery) GetCluster(ctx context.Context, name string) (*ClusterInfo, error) {
+	return c.discovery.GetCluster(ctx, name)
+}
+
+// read returns the cached clusters if the cache exists and has not expired
+func (c *CachedDiscovery) read() ([]ClusterInfo, bool) {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if c.now().Sub(entry.FetchedAt) >= c.ttl {
+		return nil, false
+	}
+	return entry.Clusters, true
+}
+
+// write stores the clusters in the cache file
+func (c *CachedDiscovery) write(clusters []ClusterInfo) error {
+	data, err := json.Marshal(cacheEntry{FetchedAt: c.now(), Clusters: clusters})
+	if err != nil {
+		return fmt.Errorf("failed to marshal discovery cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create discovery cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write discovery cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingDiscovery is a fake Discovery that counts ListClusters calls
+type countingDiscovery struct {
+	clusters []ClusterInfo
+	err      error
+	calls    int
+}
+
+func (d *countingDiscovery) ListClusters(ctx context.Context) ([]ClusterInfo, error) {
+	d.calls++
+	return d.clusters, d.err
+}
+
+func (d *countingDiscovery) GetCluster(ctx context.Context, name string) (*ClusterInfo, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestCachedDiscovery(t *testing.T) {
+	inner := &countingDiscovery{clusters: []ClusterInfo{{Name: "cluster1", Healthy: true}}}
+	path := CachePath(t.TempDir(), "kind-hub", "open-cluster-management")
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cached := NewCachedDiscovery(inner, path, 5*time.Minute)
+	cached.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	list := func() []ClusterInfo {
+		t.Helper()
+		clusters, err := cached.ListClusters(ctx)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return clusters
+	}
+
+	if clusters := list(); len(clusters) != 1 || inner.calls != 1 {
+		t.Fatalf("expected the first list to query the hub, got %v after %d calls", clusters, inner.calls)
+	}
+
+	// Served from the cache while fresh
+	now = now.Add(4 * time.Minute)
+	if clusters := list(); len(clusters) != 1 || clusters[0].Name != "cluster1" || !clusters[0].Healthy || inner.calls != 1 {
+		t.Errorf("expected cached clusters without a hub call, got %v after %d calls", clusters, inner.calls)
+	}
+
+	// Refresh bypasses the cache
+	cached.SetRefresh(true)
+	list()
+	if inner.calls != 2 {
+		t.Errorf("expected refresh to query the hub, got %d calls", inner.calls)
+	}
+	cached.SetRefresh(false)
+
+	// Expired once the TTL has passed since the last list
+	now = now.Add(5 * time.Minute)
+	list()
+	if inner.calls != 3 {
+		t.Errorf("expected an expired cache to query the hub, got %d calls", inner.calls)
+	}
+}
+
+func TestCachedDiscovery_ErrorNotCached(t *testing.T) {
+	inner := &countingDiscovery{err: errors.New("hub unreachable")}
+	cached := NewCachedDiscovery(inner, filepath.Join(t.TempDir(), "hub.json"), time.Minute)
+
+	if _, err := cached.ListClusters(context.Background()); err == nil {
+		t.Fatal("expected error")
+	}
+
+	inner.err = nil
+	inner.clusters = []ClusterInfo{{Name: "cluster1"}}
+	clusters, err := cached.ListClusters(context.Background())
+	if err != nil || len(clusters) != 1 {
+		t.Errorf("expected the hub to be queried again, got %v, %v", clusters, err)
+	}
+}
+
+func TestCachePath(t *testing.T) {
+	got := CachePath("/cache", "arn:aws:eks:us-west-2:1:cluster/hub", "ocm")
+	if got != "/cache/arn_aws_eks_us-west-2_1_cluster_hub_ocm.json" {
+		t.Errorf("unexpected cache path %q", got)
+	}
+}
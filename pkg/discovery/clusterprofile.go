@@ -2,6 +2,7 @@ package discovery
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -89,6 +90,8 @@ func (d *ClusterProfileDiscovery) parseClusterProfile(obj *unstructured.Unstruct
 	// Determine health from conditions
 	cluster.Healthy = d.isClusterHealthy(obj)
 
+	cluster.AccessProviders = parseAccessProviders(obj)
+
 	return cluster, nil
 }
 
@@ -116,3 +119,37 @@ func (d *ClusterProfileDiscovery) isClusterHealthy(obj *unstructured.Unstructure
 
 	return false
 }
+
+// parseAccessProviders extracts the API server endpoints from
+// status.accessProviders, or the older status.credentialProviders. Each
+// provider carries a kubeconfig-style cluster with server and
+// certificate-authority-data fields.
+func parseAccessProviders(obj *unstructured.Unstructured) []AccessProvider {
+	providers, found, err := unstructured.NestedSlice(obj.Object, "status", "accessProviders")
+	if err != nil || !found {
+		providers, _, _ = unstructured.NestedSlice(obj.Object, "status", "credentialProviders")
+	}
+
+	var result []AccessProvider
+	for _, p := range providers {
+		providerMap, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		name, _, _ := unstructured.NestedString(providerMap, "name")
+		server, _, _ := unstructured.NestedString(providerMap, "cluster", "server")
+		if server == "" {
+			continue
+		}
+
+		provider := AccessProvider{Name: name, Server: server}
+		if caData, _, _ := unstructured.NestedString(providerMap, "cluster", "certificate-authority-data"); caData != "" {
+			if decoded, err := base64.StdEncoding.DecodeString(caData); err == nil {
+				provider.CertificateAuthorityData = decoded
+			}
+		}
+		result = append(result, provider)
+	}
+	return result
+}
@@ -423,3 +423,52 @@ func TestListClusters_Multiple(t *testing.T) {
 		t.Error("cluster2 not found in results")
 	}
 }
+
+func TestParseAccessProviders(t *testing.T) {
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"accessProviders": []interface{}{
+					map[string]interface{}{
+						"name": "open-cluster-management",
+						"cluster": map[string]interface{}{
+							"server":                     "https://prod-1.example.com:6443",
+							"certificate-authority-data": "Y2EtZGF0YQ==",
+						},
+					},
+					map[string]interface{}{
+						"name": "no-server",
+					},
+				},
+			},
+		},
+	}
+
+	providers := parseAccessProviders(obj)
+	if len(providers) != 1 {
+		t.Fatalf("expected 1 access provider, got %d", len(providers))
+	}
+	if providers[0].Name != "open-cluster-management" || providers[0].Server != "https://prod-1.example.com:6443" {
+		t.Errorf("unexpected access provider %+v", providers[0])
+	}
+	if string(providers[0].CertificateAuthorityData) != "ca-data" {
+		t.Errorf("expected decoded CA data, got %q", providers[0].CertificateAuthorityData)
+	}
+
+	// Older ClusterProfiles publish credentialProviders instead
+	legacy := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"credentialProviders": []interface{}{
+					map[string]interface{}{
+						"name":    "secretreader",
+						"cluster": map[string]interface{}{"server": "https://legacy.example.com"},
+					},
+				},
+			},
+		},
+	}
+	if providers := parseAccessProviders(legacy); len(providers) != 1 || providers[0].Server != "https://legacy.example.com" {
+		t.Errorf("expected the credentialProviders endpoint, got %+v", providers)
+	}
+}
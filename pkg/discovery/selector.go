@@ -0,0 +1,255 @@
+package discovery
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// groupPrefix marks a reference to a cluster group, e.g. @prod-eu
+const groupPrefix = "@"
+
+// ClusterGroup is a named set of clusters, defined by name patterns, a
+// ClusterProfile label selector, or both
+type ClusterGroup struct {
+	// Clusters are cluster expressions: names, globs and @group references,
+	// optionally combined with & and ! (see Selector)
+	Clusters []string
+
+	// Selector selects clusters by ClusterProfile labels, using kubectl's -l syntax
+	Selector string
+}
+
+// String describes the group definition, e.g. "prod-fra-*, prod-ams-1" or "selector accel=nvidia"
+func (g ClusterGroup) String() string {
+	var parts []string
+	parts = append(parts, g.Clusters...)
+	if g.Selector != "" {
+		parts = append(parts, "selector "+g.Selector)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Selector chooses clusters from cluster expressions. An expression is a
+// cluster name, a glob (prod-*) or a group reference (@prod-eu); expressions
+// can be intersected with & and negated with !, e.g. "@prod&!@gpu". A list of
+// expressions, as given to --clusters, is their union.
+type Selector struct {
+	groups map[string]ClusterGroup
+}
+
+// NewSelector creates a selector resolving @references against groups
+func NewSelector(groups map[string]ClusterGroup) *Selector {
+	return &Selector{groups: groups}
+}
+
+// Filter returns the clusters matching any include expression (all clusters
+// when include is empty) and no exclude expression
+func (s *Selector) Filter(clusters []ClusterInfo, include, exclude []string) ([]ClusterInfo, error) {
+	// If no filtering specified, return all clusters
+	if len(include) == 0 && len(exclude) == 0 {
+		return clusters, nil
+	}
+
+	// Check every expression up front, so mistakes are reported even when
+	// evaluation would never reach them
+	for _, expr := range append(append([]string{}, include...), exclude...) {
+		if err := s.checkExpression(expr, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	filtered := make([]ClusterInfo, 0, len(clusters))
+	for _, cluster := range clusters {
+		excluded, err := s.matchesAny(cluster, exclude, nil)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			continue
+		}
+
+		included := len(include) == 0
+		if !included {
+			if included, err = s.matchesAny(cluster, include, nil); err != nil {
+				return nil, err
+			}
+		}
+		if included {
+			filtered = append(filtered, cluster)
+		}
+	}
+	return filtered, nil
+}
+
+// Members returns the clusters belonging to a group
+func (s *Selector) Members(group string, clusters []ClusterInfo) ([]ClusterInfo, error) {
+	return s.Filter(clusters, []string{groupPrefix + group}, nil)
+}
+
+// GroupsOf returns the sorted names of the groups a cluster belongs to
+func (s *Selector) GroupsOf(cluster ClusterInfo) ([]string, error) {
+	var names []string
+	for name := range s.groups {
+		member, err := s.matchesGroup(cluster, name, nil)
+		if err != nil {
+			return nil, err
+		}
+		if member {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// matchesAny reports whether the cluster matches one of the expressions.
+// visiting holds the groups being resolved, to detect reference cycles.
+func (s *Selector) matchesAny(cluster ClusterInfo, exprs []string, visiting []string) (bool, error) {
+	for _, expr := range exprs {
+		matched, err := s.matches(cluster, expr, visiting)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// matches reports whether the cluster matches every factor of an expression such as "@prod&!@gpu"
+func (s *Selector) matches(cluster ClusterInfo, expr string, visiting []string) (bool, error) {
+	for _, factor := range strings.Split(expr, "&") {
+		factor = strings.TrimSpace(factor)
+		negated := strings.HasPrefix(factor, "!")
+		factor = strings.TrimSpace(strings.TrimPrefix(factor, "!"))
+		if factor == "" {
+			return false, fmt.Errorf("invalid cluster expression %q", expr)
+		}
+
+		var matched bool
+		if strings.HasPrefix(factor, groupPrefix) {
+			var err error
+			if matched, err = s.matchesGroup(cluster, strings.TrimPrefix(factor, groupPrefix), visiting); err != nil {
+				return false, err
+			}
+		} else {
+			matched = matchesPattern(cluster.Name, factor)
+		}
+
+		if matched == negated {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matchesGroup reports whether the cluster is a member of the named group
+func (s *Selector) matchesGroup(cluster ClusterInfo, name string, visiting []string) (bool, error) {
+	group, visiting, err := s.enter(name, visiting)
+	if err != nil {
+		return false, err
+	}
+
+	matched, err := s.matchesAny(cluster, group.Clusters, visiting)
+	if err != nil || matched {
+		return matched, err
+	}
+
+	if group.Selector != "" {
+		selector, err := labels.Parse(group.Selector)
+		if err != nil {
+			return false, fmt.Errorf("invalid selector for cluster group %q: %w", groupPrefix+name, err)
+		}
+		return selector.Matches(labels.Set(cluster.Labels)), nil
+	}
+	return false, nil
+}
+
+// ValidateGroups checks group names, selectors and references, including reference cycles
+func ValidateGroups(groups map[string]ClusterGroup) error {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := NewSelector(groups)
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, groupPrefix+"&!, ") {
+			return fmt.Errorf("invalid cluster group name %q", name)
+		}
+		group := groups[name]
+		if len(group.Clusters) == 0 && group.Selector == "" {
+			return fmt.Errorf("cluster group %q selects no clusters", name)
+		}
+		if group.Selector != "" {
+			if _, err := labels.Parse(group.Selector); err != nil {
+				return fmt.Errorf("invalid selector for cluster group %q: %w", name, err)
+			}
+		}
+
+		if err := s.checkReferences(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkReferences follows every group the named group references, reporting
+// unknown groups, empty expressions and cycles
+func (s *Selector) checkReferences(name string, visiting []string) error {
+	group, visiting, err := s.enter(name, visiting)
+	if err != nil {
+		return err
+	}
+
+	for _, expr := range group.Clusters {
+		if err := s.checkExpression(expr, visiting); err != nil {
+			return fmt.Errorf("in cluster group %q: %w", groupPrefix+name, err)
+		}
+	}
+	return nil
+}
+
+// checkExpression checks the syntax of an expression and the groups it references
+func (s *Selector) checkExpression(expr string, visiting []string) error {
+	for _, factor := range strings.Split(expr, "&") {
+		factor = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(factor), "!"))
+		if factor == "" {
+			return fmt.Errorf("invalid cluster expression %q", expr)
+		}
+		if strings.HasPrefix(factor, groupPrefix) {
+			if err := s.checkReferences(strings.TrimPrefix(factor, groupPrefix), visiting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// enter looks up a group about to be resolved and adds it to the groups being
+// visited, failing on unknown groups and reference cycles
+func (s *Selector) enter(name string, visiting []string) (ClusterGroup, []string, error) {
+	group, ok := s.groups[name]
+	if !ok {
+		return ClusterGroup{}, nil, fmt.Errorf("unknown cluster group %q", groupPrefix+name)
+	}
+	for _, v := range visiting {
+		if v == name {
+			return ClusterGroup{}, nil, fmt.Errorf("cluster group %q references itself: %s", groupPrefix+name, strings.Join(append(visiting, name), " -> "))
+		}
+	}
+	return group, append(visiting, name), nil
+}
+
+// matchesPattern checks a cluster name against an exact name or a glob.
+// Supports glob patterns: * (any chars), ? (single char), [abc] (char class)
+func matchesPattern(name, pattern string) bool {
+	if name == pattern {
+		return true
+	}
+	matched, err := filepath.Match(pattern, name)
+	return err == nil && matched
+}
@@ -0,0 +1,139 @@
+package discovery
+
+import (
+	"strings"
+	"testing"
+)
+
+// selectorTestClusters returns clusters across two regions, some with GPUs
+func selectorTestClusters() []ClusterInfo {
+	return []ClusterInfo{
+		{Name: "prod-fra-1", Labels: map[string]string{"accel": "nvidia"}},
+		{Name: "prod-fra-2"},
+		{Name: "prod-ams-1", Labels: map[string]string{"accel": "nvidia"}},
+		{Name: "prod-ams-2"},
+		{Name: "prod-us-1", Labels: map[string]string{"accel": "nvidia"}},
+		{Name: "staging-fra-1"},
+	}
+}
+
+// selectorTestGroups returns pattern, selector and composed groups
+func selectorTestGroups() map[string]ClusterGroup {
+	return map[string]ClusterGroup{
+		"prod-eu":     {Clusters: []string{"prod-fra-*", "prod-ams-1"}},
+		"gpu":         {Selector: "accel=nvidia"},
+		"prod-eu-gpu": {Clusters: []string{"@prod-eu&@gpu"}},
+		"fra":         {Clusters: []string{"*-fra-*"}},
+	}
+}
+
+func clusterNames(clusters []ClusterInfo) string {
+	names := make([]string, 0, len(clusters))
+	for _, cluster := range clusters {
+		names = append(names, cluster.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSelectorFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected string
+	}{
+		{name: "no filter", expected: "prod-fra-1,prod-fra-2,prod-ams-1,prod-ams-2,prod-us-1,staging-fra-1"},
+		{name: "names and globs", include: []string{"prod-us-1", "staging-*"}, expected: "prod-us-1,staging-fra-1"},
+		{name: "pattern group", include: []string{"@prod-eu"}, expected: "prod-fra-1,prod-fra-2,prod-ams-1"},
+		{name: "selector group", include: []string{"@gpu"}, expected: "prod-fra-1,prod-ams-1,prod-us-1"},
+		{name: "union", include: []string{"@prod-eu", "@gpu"}, expected: "prod-fra-1,prod-fra-2,prod-ams-1,prod-us-1"},
+		{name: "intersection", include: []string{"@prod-eu&@gpu"}, expected: "prod-fra-1,prod-ams-1"},
+		{name: "difference", include: []string{"@prod-eu&!@gpu"}, expected: "prod-fra-2"},
+		{name: "negation only", include: []string{"!prod-*"}, expected: "staging-fra-1"},
+		{name: "nested group", include: []string{"@prod-eu-gpu"}, expected: "prod-fra-1,prod-ams-1"},
+		{name: "exclude group", exclude: []string{"@fra"}, expected: "prod-ams-1,prod-ams-2,prod-us-1"},
+		{name: "include and exclude", include: []string{"@gpu"}, exclude: []string{"@prod-eu"}, expected: "prod-us-1"},
+	}
+
+	selector := NewSelector(selectorTestGroups())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selector.Filter(selectorTestClusters(), tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if clusterNames(got) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, clusterNames(got))
+			}
+		})
+	}
+}
+
+func TestSelectorFilter_Errors(t *testing.T) {
+	selector := NewSelector(map[string]ClusterGroup{
+		"a": {Clusters: []string{"@b"}},
+		"b": {Clusters: []string{"@a"}},
+	})
+
+	tests := []struct {
+		include []string
+		wantErr string
+	}{
+		{include: []string{"@missing"}, wantErr: "unknown cluster group"},
+		{include: []string{"prod&"}, wantErr: "invalid cluster expression"},
+		{include: []string{"@a"}, wantErr: "references itself"},
+	}
+
+	for _, tt := range tests {
+		if _, err := selector.Filter(selectorTestClusters(), tt.include, nil); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Filter(%v): expected error containing %q, got %v", tt.include, tt.wantErr, err)
+		}
+	}
+}
+
+func TestSelectorGroupsOf(t *testing.T) {
+	selector := NewSelector(selectorTestGroups())
+
+	groups, err := selector.GroupsOf(ClusterInfo{Name: "prod-fra-1", Labels: map[string]string{"accel": "nvidia"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(groups, ","); got != "fra,gpu,prod-eu,prod-eu-gpu" {
+		t.Errorf("expected fra,gpu,prod-eu,prod-eu-gpu, got %s", got)
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	if err := ValidateGroups(selectorTestGroups()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		groups  map[string]ClusterGroup
+		wantErr string
+	}{
+		{name: "unknown reference", groups: map[string]ClusterGroup{"a": {Clusters: []string{"@b"}}}, wantErr: "unknown cluster group"},
+		{name: "cycle", groups: map[string]ClusterGroup{"a": {Clusters: []string{"x", "@a"}}}, wantErr: "references itself"},
+		{name: "bad selector", groups: map[string]ClusterGroup{"a": {Selector: "accel in nvidia"}}, wantErr: "invalid selector"},
+		{name: "empty group", groups: map[string]ClusterGroup{"a": {}}, wantErr: "selects no clusters"},
+		{name: "bad name", groups: map[string]ClusterGroup{"a&b": {Clusters: []string{"x"}}}, wantErr: "invalid cluster group name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateGroups(tt.groups); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestClusterGroupString(t *testing.T) {
+	if got := (ClusterGroup{Clusters: []string{"prod-fra-*", "prod-ams-1"}}).String(); got != "prod-fra-*, prod-ams-1" {
+		t.Errorf("expected the patterns, got %q", got)
+	}
+	if got := (ClusterGroup{Selector: "accel=nvidia"}).String(); got != "selector accel=nvidia" {
+		t.Errorf("expected the selector, got %q", got)
+	}
+}
@@ -23,6 +23,22 @@ type ClusterInfo struct {
 
 	// Labels are the labels from the ClusterProfile
 	Labels map[string]string
+
+	// AccessProviders are the ways to reach the cluster published in the
+	// ClusterProfile status
+	AccessProviders []AccessProvider `json:",omitempty"`
+}
+
+// AccessProvider is an API server endpoint published by a ClusterProfile access provider
+type AccessProvider struct {
+	// Name is the access provider name, e.g. open-cluster-management
+	Name string
+
+	// Server is the URL of the cluster's API server
+	Server string
+
+	// CertificateAuthorityData is the PEM-encoded CA bundle of the API server
+	CertificateAuthorityData []byte `json:",omitempty"`
 }
 
 // Discovery is the interface for discovering clusters
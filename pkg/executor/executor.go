@@ -2,8 +2,8 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -11,288 +11,468 @@ import (
 
 	"github.com/suchpuppet/kubectl-mc/pkg/client"
 	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
-	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
-	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/cli-runtime/pkg/genericclioptions"
-	k8sdiscovery "k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/kubectl/pkg/describe"
 )
 
 // Executor handles multi-cluster command execution
 type Executor struct {
-	mappingManager *kubeconfig.Manager
-	configFlags    *genericclioptions.ConfigFlags
-	config         ExecutorConfig
+	clients client.ClusterClientProvider
+	config  ExecutorConfig
+
+	// verifier checks cluster identities; verified holds the check of each cluster
+	verifier   IdentityVerifier
+	verifiedMu sync.Mutex
+	verified   map[string]*verification
+}
+
+// verification is the identity check of one cluster, shared by every
+// operation on the cluster. Only a conclusive outcome is kept: the cluster
+// was verified, or it reaches another cluster.
+type verification struct {
+	mu   sync.Mutex
+	done bool
+	err  error
 }
 
-// NewExecutor creates a new multi-cluster executor
-func NewExecutor(mappingManager *kubeconfig.Manager, configFlags *genericclioptions.ConfigFlags) *Executor {
+// NewExecutor creates a new multi-cluster executor that obtains per-cluster clients from clients
+func NewExecutor(clients client.ClusterClientProvider) *Executor {
 	return &Executor{
-		mappingManager: mappingManager,
-		configFlags:    configFlags,
-		config:         DefaultConfig(),
+		clients: clients,
+		config:  DefaultConfig(),
 	}
 }
 
-// Get executes a get command across multiple clusters
-func (e *Executor) Get(ctx context.Context, clusters []discovery.ClusterInfo, resource, name, namespace string) (*AggregatedResults, error) {
-	results := NewAggregatedResults(clusters)
-
-	// Create a channel for results
-	resultChan := make(chan ClusterResult, len(clusters))
-
-	// Create semaphore for concurrency control
-	sem := make(chan struct{}, e.config.MaxConcurrency)
-
-	// WaitGroup to wait for all goroutines
-	var wg sync.WaitGroup
-
-	// Execute get on each cluster in parallel
-	for _, cluster := range clusters {
-		wg.Add(1)
-		go func(c discovery.ClusterInfo) {
-			defer wg.Done()
+// SetConfig changes the concurrency, per-cluster timeout and error handling of the executor
+func (e *Executor) SetConfig(config ExecutorConfig) {
+	e.config = config
+}
 
-			// Acquire semaphore
-			sem <- struct{}{}
-			defer func() { <-sem }()
+// SetVerifier makes the executor check each cluster's identity before its
+// first operation, refusing clusters that fail the check. nil disables it.
+func (e *Executor) SetVerifier(verifier IdentityVerifier) {
+	e.verifiedMu.Lock()
+	defer e.verifiedMu.Unlock()
+	e.verifier = verifier
+	e.verified = make(map[string]*verification)
+}
 
-			// Create context with timeout
-			ctx, cancel := context.WithTimeout(ctx, time.Duration(e.config.TimeoutSeconds)*time.Second)
-			defer cancel()
+// VerifyCluster checks the cluster's identity, bounded by the per-cluster
+// timeout. A passed check or an identity mismatch is kept for the life of the
+// executor; other failures, such as a cancelled request or an unreachable
+// cluster, are retried by the next call.
+func (e *Executor) VerifyCluster(ctx context.Context, cluster discovery.ClusterInfo) error {
+	e.verifiedMu.Lock()
+	if e.verifier == nil {
+		e.verifiedMu.Unlock()
+		return nil
+	}
+	v, ok := e.verified[cluster.Name]
+	if !ok {
+		v = &verification{}
+		e.verified[cluster.Name] = v
+	}
+	verifier := e.verifier
+	e.verifiedMu.Unlock()
 
-			// Execute get on this cluster
-			result := e.getFromCluster(ctx, c, resource, name, namespace)
-			resultChan <- result
-		}(cluster)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.done {
+		return v.err
 	}
 
-	// Wait for all goroutines to complete and close the channel
-	go func() {
-		wg.Wait()
-		close(resultChan)
-	}()
+	if e.config.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(e.config.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+	err := verifier.VerifyIdentity(ctx, cluster)
 
-	// Collect results
-	for result := range resultChan {
-		results.AddResult(result)
+	var mismatch *client.IdentityMismatchError
+	if err == nil || errors.As(err, &mismatch) {
+		v.done, v.err = true, err
 	}
+	return err
+}
 
+// Get executes a get command across multiple clusters
+func (e *Executor) Get(ctx context.Context, clusters []discovery.ClusterInfo, query ResourceQuery) (*AggregatedResults, error) {
+	results := e.Run(ctx, clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		return e.getFromCluster(ctx, target.Cluster, query)
+	})
+	return results, nil
+}
+
+// GetStream executes a get command like Get, passing each cluster's result to
+// emit as soon as that cluster has responded
+func (e *Executor) GetStream(ctx context.Context, clusters []discovery.ClusterInfo, query ResourceQuery, emit func(ClusterResult)) (*AggregatedResults, error) {
+	results := e.RunStream(ctx, clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		return e.getFromCluster(ctx, target.Cluster, query)
+	}, emit)
 	return results, nil
 }
 
 // Describe executes a describe command across multiple clusters
-func (e *Executor) Describe(ctx context.Context, clusters []discovery.ClusterInfo, resource, name, namespace string) (*AggregatedResults, error) {
+func (e *Executor) Describe(ctx context.Context, clusters []discovery.ClusterInfo, query ResourceQuery) (*AggregatedResults, error) {
+	results := e.Run(ctx, clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		return e.describeFromCluster(ctx, target.Cluster, query)
+	})
+	return results, nil
+}
+
+// Run executes fn against every cluster in parallel and aggregates the results.
+//
+// Run bounds the number of clusters queried at once by MaxConcurrency, gives each
+// call its own TimeoutSeconds deadline and recovers panics raised by fn, turning
+// them into a failed ClusterResult. Clusters that have not started when ctx is
+// cancelled are reported as failed with the context error. When ContinueOnError
+// is false, the first failure cancels the clusters that are still pending.
+// Results are returned in the same order as clusters, regardless of completion order.
+func (e *Executor) Run(ctx context.Context, clusters []discovery.ClusterInfo, fn ClusterFunc) *AggregatedResults {
+	return e.RunStream(ctx, clusters, fn, nil)
+}
+
+// RunStream is Run, additionally passing each result to emit as soon as its
+// cluster completes. Calls to emit are serialized, in completion order; emit
+// may be nil.
+func (e *Executor) RunStream(ctx context.Context, clusters []discovery.ClusterInfo, fn ClusterFunc, emit func(ClusterResult)) *AggregatedResults {
 	results := NewAggregatedResults(clusters)
 
-	// Create a channel for results
-	resultChan := make(chan ClusterResult, len(clusters))
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Each goroutine writes to its own slot, which preserves the input order
+	ordered := make([]ClusterResult, len(clusters))
 
 	// Create semaphore for concurrency control
-	sem := make(chan struct{}, e.config.MaxConcurrency)
+	concurrency := e.config.MaxConcurrency
+	if concurrency <= 0 || concurrency > len(clusters) {
+		concurrency = len(clusters)
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var emitMu sync.Mutex
+	complete := func(i int, result ClusterResult) {
+		ordered[i] = result
+		if emit != nil {
+			emitMu.Lock()
+			defer emitMu.Unlock()
+			emit(result)
+		}
+	}
 
 	// WaitGroup to wait for all goroutines
 	var wg sync.WaitGroup
 
-	// Execute describe on each cluster in parallel
-	for _, cluster := range clusters {
+	for i, cluster := range clusters {
 		wg.Add(1)
-		go func(c discovery.ClusterInfo) {
+		go func(i int, c discovery.ClusterInfo) {
 			defer wg.Done()
 
-			// Acquire semaphore
-			sem <- struct{}{}
-			defer func() { <-sem }()
+			// Acquire semaphore, giving up if the operation is cancelled first
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				complete(i, ClusterResult{ClusterName: c.Name, Error: ctx.Err()})
+				return
+			}
 
-			// Create context with timeout
-			ctx, cancel := context.WithTimeout(ctx, time.Duration(e.config.TimeoutSeconds)*time.Second)
-			defer cancel()
+			result := e.runOnCluster(ctx, c, fn)
+			if !result.Success && !e.config.ContinueOnError {
+				cancel()
+			}
+			complete(i, result)
+		}(i, cluster)
+	}
 
-			// Execute describe on this cluster
-			result := e.describeFromCluster(ctx, c, resource, name, namespace)
-			resultChan <- result
-		}(cluster)
+	wg.Wait()
+
+	for _, result := range ordered {
+		results.AddResult(result)
 	}
 
-	// Wait for all goroutines to complete and close the channel
-	go func() {
-		wg.Wait()
-		close(resultChan)
+	return results
+}
+
+// runOnCluster invokes fn for a single cluster with the per-cluster timeout applied
+func (e *Executor) runOnCluster(ctx context.Context, cluster discovery.ClusterInfo, fn ClusterFunc) (result ClusterResult) {
+	if err := ctx.Err(); err != nil {
+		return ClusterResult{ClusterName: cluster.Name, Error: err}
+	}
+
+	// Create context with timeout
+	if e.config.TimeoutSeconds > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(e.config.TimeoutSeconds)*time.Second)
+		defer cancel()
+	}
+
+	// A panicking cluster must not take down the whole operation
+	defer func() {
+		if r := recover(); r != nil {
+			result = ClusterResult{
+				ClusterName: cluster.Name,
+				Error:       fmt.Errorf("panic while querying cluster %s: %v", cluster.Name, r),
+			}
+		}
 	}()
 
-	// Collect results
-	for result := range resultChan {
-		results.AddResult(result)
+	if err := e.VerifyCluster(ctx, cluster); err != nil {
+		return ClusterResult{ClusterName: cluster.Name, Error: err}
 	}
 
-	return results, nil
+	result = fn(ctx, ClusterTarget{Cluster: cluster})
+	if result.ClusterName == "" {
+		result.ClusterName = cluster.Name
+	}
+	if !result.Success && result.Error == nil {
+		result.Error = fmt.Errorf("operation failed on cluster %s", cluster.Name)
+	}
+
+	return result
 }
 
 // getFromCluster executes a get command on a single cluster
-func (e *Executor) getFromCluster(ctx context.Context, cluster discovery.ClusterInfo, resource, name, namespace string) ClusterResult {
+func (e *Executor) getFromCluster(ctx context.Context, cluster discovery.ClusterInfo, query ResourceQuery) ClusterResult {
 	result := ClusterResult{
 		ClusterName: cluster.Name,
 		Items:       []unstructured.Unstructured{},
 	}
 
-	// Get the kubeconfig context for this cluster
-	contextName, err := e.mappingManager.GetContext(cluster.Name)
+	// Get dynamic client
+	dynamicClient, err := e.clients.DynamicClient(cluster)
 	if err != nil {
-		result.Error = fmt.Errorf("no kubeconfig context mapped for cluster %s", cluster.Name)
+		result.Error = fmt.Errorf("failed to create dynamic client: %w", err)
 		return result
 	}
 
-	// Create client factory for this cluster's context
-	factory, err := client.NewFactory(contextName, e.configFlags)
+	// Get discovery client to resolve resource types
+	discoveryClient, err := e.clients.DiscoveryClient(cluster)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to create client factory: %w", err)
+		result.Error = fmt.Errorf("failed to create discovery client: %w", err)
 		return result
 	}
 
-	// Get dynamic client
-	dynamicClient, err := factory.DynamicClient()
-	if err != nil {
-		result.Error = fmt.Errorf("failed to create dynamic client: %w", err)
+	// Resolve every requested resource type ("pods,svc" or "all") with one resolver,
+	// so discovery is only queried once per cluster
+	resolver := newResourceResolver(discoveryClient)
+	resources := resolver.expand(query.Resource)
+	if len(resources) == 0 {
+		result.Error = fmt.Errorf("no resource type specified")
 		return result
 	}
 
-	// Get discovery client to resolve resource types
-	discoveryClient, err := factory.DiscoveryClient()
+	namespaces, err := e.queryNamespaces(ctx, cluster, query)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to create discovery client: %w", err)
+		result.Error = err
 		return result
 	}
 
-	// Resolve the GVR for the resource
-	gvr, err := e.resolveGVR(discoveryClient, resource)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to resolve resource type: %w", err)
-		return result
+	// Server-side tables need a REST config; without one, fall back to plain items
+	var restConfig *rest.Config
+	if query.AsTable {
+		restConfig, _ = e.clients.RESTConfig(cluster)
 	}
 
-	// Execute the get operation
-	var resourceInterface dynamic.ResourceInterface
-	if namespace != "" {
-		resourceInterface = dynamicClient.Resource(gvr).Namespace(namespace)
-	} else {
-		resourceInterface = dynamicClient.Resource(gvr)
+	var errs []error
+	failed := 0
+	for _, resource := range resources {
+		mapping, err := resolver.mapping(resource)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to resolve resource type: %w", err))
+			failed++
+			continue
+		}
+
+		ok, err := forEachNamespace(mapping, query, namespaces, func(query ResourceQuery) error {
+			if restConfig != nil {
+				table, items, err := e.fetchTable(ctx, restConfig, mapping, query)
+				if err == nil {
+					result.Tables = append(result.Tables, ResourceTable{GroupVersionKind: mapping.GroupVersionKind, Table: table})
+					result.Items = append(result.Items, items...)
+					return nil
+				}
+				if !errors.Is(err, errTableNotSupported) {
+					return err
+				}
+			}
+
+			items, err := e.fetchItems(ctx, dynamicClient, mapping, query, false)
+			if err != nil {
+				return err
+			}
+
+			// Items from a list may lack type information; the aggregator groups by kind
+			for i := range items {
+				if items[i].GetKind() == "" {
+					items[i].SetGroupVersionKind(mapping.GroupVersionKind)
+				}
+			}
+
+			result.Items = append(result.Items, items...)
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if !ok {
+			failed++
+		}
 	}
 
-	// Check if name contains wildcards
-	hasWildcard := name != "" && (strings.Contains(name, "*") || strings.Contains(name, "?") || strings.Contains(name, "["))
+	// The cluster only fails when no resource type could be retrieved.
+	// Failures of individual types or namespaces are still reported through Error.
+	result.Error = errors.Join(errs...)
+	result.Success = failed < len(resources)
+	return result
+}
+
+// fetchItems retrieves the resources selected by query from a single cluster.
+// Exact names are fetched directly; wildcard names and label selectors are applied
+// to a list. With prefixFallback, an exact name that does not exist matches every
+// resource whose name starts with it, which is how kubectl describe behaves.
+func (e *Executor) fetchItems(ctx context.Context, dynamicClient dynamic.Interface, mapping *meta.RESTMapping, query ResourceQuery, prefixFallback bool) ([]unstructured.Unstructured, error) {
+	resourceInterface := namespacedResource(dynamicClient, mapping, query.Namespace)
+	hasWildcard := isWildcard(query.Name)
 
-	if name != "" && !hasWildcard {
+	if query.Name != "" && !hasWildcard {
 		// Get specific resource by exact name
-		item, err := resourceInterface.Get(ctx, name, metav1.GetOptions{})
-		if err != nil {
-			result.Error = fmt.Errorf("failed to get resource: %w", err)
-			return result
+		item, err := resourceInterface.Get(ctx, query.Name, metav1.GetOptions{})
+		if err == nil {
+			return []unstructured.Unstructured{*item}, nil
 		}
-		result.Items = append(result.Items, *item)
-	} else {
-		// List resources (either no name specified, or name has wildcards)
-		list, err := resourceInterface.List(ctx, metav1.ListOptions{})
-		if err != nil {
-			result.Error = fmt.Errorf("failed to list resources: %w", err)
-			return result
+		if !prefixFallback || !apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get resource: %w", err)
 		}
+	}
+
+	// List resources (no name, wildcard name, or prefix fallback)
+	list, err := resourceInterface.List(ctx, metav1.ListOptions{LabelSelector: query.LabelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources: %w", err)
+	}
 
-		// If wildcard pattern specified, filter results
+	if query.Name == "" {
+		return list.Items, nil
+	}
+
+	items := []unstructured.Unstructured{}
+	for _, item := range list.Items {
 		if hasWildcard {
-			for _, item := range list.Items {
-				matched, err := filepath.Match(name, item.GetName())
-				if err == nil && matched {
-					result.Items = append(result.Items, item)
-				}
+			matched, err := filepath.Match(query.Name, item.GetName())
+			if err == nil && matched {
+				items = append(items, item)
 			}
-		} else {
-			// No filter, return all
-			result.Items = append(result.Items, list.Items...)
+		} else if strings.HasPrefix(item.GetName(), query.Name) {
+			items = append(items, item)
 		}
 	}
 
-	result.Success = true
-	return result
+	if !hasWildcard && len(items) == 0 {
+		return nil, fmt.Errorf("failed to get resource: %w", apierrors.NewNotFound(mapping.Resource.GroupResource(), query.Name))
+	}
+
+	return items, nil
 }
 
-// resolveGVR resolves a resource name to its GroupVersionResource
-func (e *Executor) resolveGVR(_ k8sdiscovery.DiscoveryInterface, resource string) (schema.GroupVersionResource, error) {
-	// This is a simplified implementation.
-	// A production version would use kubectl's resource mapper for better resolution.
-	// discoveryClient parameter reserved for future enhancement.
-
-	// Common resource mappings (simplified)
-	commonResources := map[string]schema.GroupVersionResource{
-		"pods":        {Group: "", Version: "v1", Resource: "pods"},
-		"pod":         {Group: "", Version: "v1", Resource: "pods"},
-		"services":    {Group: "", Version: "v1", Resource: "services"},
-		"service":     {Group: "", Version: "v1", Resource: "services"},
-		"deployments": {Group: "apps", Version: "v1", Resource: "deployments"},
-		"deployment":  {Group: "apps", Version: "v1", Resource: "deployments"},
-		"configmaps":  {Group: "", Version: "v1", Resource: "configmaps"},
-		"configmap":   {Group: "", Version: "v1", Resource: "configmaps"},
-		"secrets":     {Group: "", Version: "v1", Resource: "secrets"},
-		"secret":      {Group: "", Version: "v1", Resource: "secrets"},
-		"namespaces":  {Group: "", Version: "v1", Resource: "namespaces"},
-		"namespace":   {Group: "", Version: "v1", Resource: "namespaces"},
-	}
-
-	gvr, ok := commonResources[resource]
-	if !ok {
-		return schema.GroupVersionResource{}, fmt.Errorf("unknown resource type: %s", resource)
+// namespacedResource returns the dynamic client for a resource type, scoped to
+// namespace unless it is empty or the resource is cluster-scoped
+func namespacedResource(dynamicClient dynamic.Interface, mapping *meta.RESTMapping, namespace string) dynamic.ResourceInterface {
+	if namespace != "" && mapping.Scope.Name() != meta.RESTScopeNameRoot {
+		return dynamicClient.Resource(mapping.Resource).Namespace(namespace)
 	}
+	return dynamicClient.Resource(mapping.Resource)
+}
 
-	return gvr, nil
+// isWildcard reports whether a resource name contains glob characters
+func isWildcard(name string) bool {
+	return strings.ContainsAny(name, "*?[")
 }
 
-// describeFromCluster executes a describe command on a single cluster using kubectl
-func (e *Executor) describeFromCluster(ctx context.Context, cluster discovery.ClusterInfo, resource, name, namespace string) ClusterResult {
+// describeFromCluster describes the selected resources on a single cluster using
+// kubectl's describers, in-process, with the cluster's REST config
+func (e *Executor) describeFromCluster(ctx context.Context, cluster discovery.ClusterInfo, query ResourceQuery) ClusterResult {
 	result := ClusterResult{
 		ClusterName: cluster.Name,
 		Items:       []unstructured.Unstructured{},
 	}
 
-	// Get the kubeconfig context for this cluster
-	contextName, err := e.mappingManager.GetContext(cluster.Name)
+	restConfig, err := e.clients.RESTConfig(cluster)
 	if err != nil {
-		result.Error = fmt.Errorf("no kubeconfig context mapped for cluster %s", cluster.Name)
+		result.Error = fmt.Errorf("failed to get REST config: %w", err)
 		return result
 	}
 
-	// Use kubectl describe directly for perfect formatting
-	output, err := e.executeKubectlDescribe(ctx, contextName, resource, name, namespace)
+	dynamicClient, err := e.clients.DynamicClient(cluster)
 	if err != nil {
-		result.Error = err
+		result.Error = fmt.Errorf("failed to create dynamic client: %w", err)
 		return result
 	}
 
-	result.Output = output
-	result.Success = true
-	return result
-}
-
-// executeKubectlDescribe shells out to kubectl describe for perfect formatting
-func (e *Executor) executeKubectlDescribe(ctx context.Context, contextName, resource, name, namespace string) (string, error) {
-	args := []string{"describe", resource}
+	discoveryClient, err := e.clients.DiscoveryClient(cluster)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to create discovery client: %w", err)
+		return result
+	}
 
-	if name != "" {
-		args = append(args, name)
+	mapping, err := e.resolveMapping(discoveryClient, query.Resource)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to resolve resource type: %w", err)
+		return result
 	}
 
-	if namespace != "" {
-		args = append(args, "-n", namespace)
+	// Built-in kinds get their dedicated describer; CRDs fall back to the generic one
+	describer, ok := describe.DescriberFor(mapping.GroupVersionKind.GroupKind(), restConfig)
+	if !ok {
+		describer, ok = describe.GenericDescriberFor(mapping, restConfig)
+		if !ok {
+			result.Error = fmt.Errorf("no describer available for %s", mapping.GroupVersionKind.Kind)
+			return result
+		}
 	}
 
-	args = append(args, "--context", contextName)
+	namespaces, err := e.queryNamespaces(ctx, cluster, query)
+	if err != nil {
+		result.Error = err
+		return result
+	}
 
-	// Execute kubectl
-	cmd := exec.CommandContext(ctx, "kubectl", args...)
-	output, err := cmd.CombinedOutput()
+	var items []unstructured.Unstructured
+	_, err = forEachNamespace(mapping, query, namespaces, func(query ResourceQuery) error {
+		found, err := e.fetchItems(ctx, dynamicClient, mapping, query, true)
+		items = append(items, found...)
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("kubectl describe failed: %w, output: %s", err, string(output))
+		result.Error = err
+		return result
+	}
+
+	settings := describe.DescriberSettings{ShowEvents: true, ChunkSize: 500}
+	outputs := make([]string, 0, len(items))
+	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			result.Error = err
+			return result
+		}
+
+		output, err := describer.Describe(item.GetNamespace(), item.GetName(), settings)
+		if err != nil {
+			result.Error = fmt.Errorf("failed to describe %s %s: %w", mapping.Resource.Resource, item.GetName(), err)
+			return result
+		}
+		outputs = append(outputs, output)
 	}
 
-	return string(output), nil
+	// Separate multiple objects with a blank line, as kubectl describe does
+	result.Items = append(result.Items, items...)
+	result.Output = strings.Join(outputs, "\n\n")
+	result.Success = true
+	return result
 }
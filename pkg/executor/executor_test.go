@@ -2,12 +2,24 @@ package executor
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
 	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
 	"github.com/suchpuppet/kubectl-mc/pkg/kubeconfig"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	k8sdiscovery "k8s.io/client-go/discovery"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
 )
 
 func TestNewExecutor(t *testing.T) {
@@ -17,18 +29,15 @@ func TestNewExecutor(t *testing.T) {
 		t.Fatalf("failed to create manager: %v", err)
 	}
 
-	executor := NewExecutor(manager, configFlags)
+	provider := client.NewKubeconfigProvider(manager, configFlags)
+	executor := NewExecutor(provider)
 
 	if executor == nil {
 		t.Fatal("expected executor, got nil")
 	}
 
-	if executor.mappingManager != manager {
-		t.Error("mapping manager not set correctly")
-	}
-
-	if executor.configFlags != configFlags {
-		t.Error("config flags not set correctly")
+	if executor.clients != provider {
+		t.Error("client provider not set correctly")
 	}
 
 	// Verify default config is set
@@ -45,10 +54,20 @@ func TestNewExecutor(t *testing.T) {
 	}
 }
 
-func TestResolveGVR(t *testing.T) {
+func TestNewConfig(t *testing.T) {
+	config := NewConfig(10, 1500*time.Millisecond, true)
+	if config.TimeoutSeconds != 2 {
+		t.Errorf("expected partial seconds to round up to 2, got %d", config.TimeoutSeconds)
+	}
+	if config.MaxConcurrency != 10 || !config.ContinueOnError {
+		t.Errorf("unexpected executor config %+v", config)
+	}
+}
+
+func TestResolveMapping_Common(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	tests := []struct {
 		name        string
@@ -130,7 +149,7 @@ func TestResolveGVR(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gvr, err := executor.resolveGVR(nil, tt.resource)
+			mapping, err := executor.resolveMapping(nil, tt.resource)
 
 			if tt.expectError {
 				if err == nil {
@@ -144,6 +163,7 @@ func TestResolveGVR(t *testing.T) {
 				return
 			}
 
+			gvr := mapping.Resource
 			if gvr.Group != tt.expectedGVR.Group {
 				t.Errorf("expected group %s, got %s", tt.expectedGVR.Group, gvr.Group)
 			}
@@ -162,12 +182,12 @@ func TestResolveGVR(t *testing.T) {
 func TestExecutorGet_EmptyClusters(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	ctx := context.Background()
 	clusters := []discovery.ClusterInfo{}
 
-	results, err := executor.Get(ctx, clusters, "pods", "", "default")
+	results, err := executor.Get(ctx, clusters, ResourceQuery{Resource: "pods", Namespace: "default"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -188,7 +208,7 @@ func TestExecutorGet_EmptyClusters(t *testing.T) {
 func TestExecutorGet_SingleCluster_NoMapping(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	ctx := context.Background()
 	clusters := []discovery.ClusterInfo{
@@ -199,7 +219,7 @@ func TestExecutorGet_SingleCluster_NoMapping(t *testing.T) {
 	}
 
 	// This should fail because there's no mapping for "test-cluster"
-	results, err := executor.Get(ctx, clusters, "pods", "", "default")
+	results, err := executor.Get(ctx, clusters, ResourceQuery{Resource: "pods", Namespace: "default"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -225,7 +245,7 @@ func TestExecutorGet_SingleCluster_NoMapping(t *testing.T) {
 func TestExecutorGet_MultipleClusters_NoMappings(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	ctx := context.Background()
 	clusters := []discovery.ClusterInfo{
@@ -234,7 +254,7 @@ func TestExecutorGet_MultipleClusters_NoMappings(t *testing.T) {
 		{Name: "cluster3", Namespace: "ns3"},
 	}
 
-	results, err := executor.Get(ctx, clusters, "pods", "", "default")
+	results, err := executor.Get(ctx, clusters, ResourceQuery{Resource: "pods", Namespace: "default"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -266,7 +286,7 @@ func TestExecutorGet_MultipleClusters_NoMappings(t *testing.T) {
 func TestExecutorGet_ContextCancellation(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	// Create a cancelled context
 	ctx, cancel := context.WithCancel(context.Background())
@@ -276,7 +296,7 @@ func TestExecutorGet_ContextCancellation(t *testing.T) {
 		{Name: "cluster1", Namespace: "ns1"},
 	}
 
-	results, err := executor.Get(ctx, clusters, "pods", "", "default")
+	results, err := executor.Get(ctx, clusters, ResourceQuery{Resource: "pods", Namespace: "default"})
 
 	// Should not error even with cancelled context
 	if err != nil {
@@ -296,7 +316,7 @@ func TestExecutorGet_ContextCancellation(t *testing.T) {
 func TestExecutorGet_DifferentResourceTypes(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	ctx := context.Background()
 	clusters := []discovery.ClusterInfo{
@@ -307,7 +327,7 @@ func TestExecutorGet_DifferentResourceTypes(t *testing.T) {
 
 	for _, resource := range resourceTypes {
 		t.Run(resource, func(t *testing.T) {
-			results, err := executor.Get(ctx, clusters, resource, "", "default")
+			results, err := executor.Get(ctx, clusters, ResourceQuery{Resource: resource, Namespace: "default"})
 			if err != nil {
 				t.Fatalf("unexpected error for %s: %v", resource, err)
 			}
@@ -327,7 +347,7 @@ func TestExecutorGet_DifferentResourceTypes(t *testing.T) {
 func TestExecutorGet_WithSpecificName(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	ctx := context.Background()
 	clusters := []discovery.ClusterInfo{
@@ -335,7 +355,7 @@ func TestExecutorGet_WithSpecificName(t *testing.T) {
 	}
 
 	// Test with specific pod name
-	results, err := executor.Get(ctx, clusters, "pods", "nginx-pod", "default")
+	results, err := executor.Get(ctx, clusters, ResourceQuery{Resource: "pods", Name: "nginx-pod", Namespace: "default"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -353,7 +373,7 @@ func TestExecutorGet_WithSpecificName(t *testing.T) {
 func TestExecutorGet_AllNamespaces(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	ctx := context.Background()
 	clusters := []discovery.ClusterInfo{
@@ -361,7 +381,7 @@ func TestExecutorGet_AllNamespaces(t *testing.T) {
 	}
 
 	// Test with empty namespace (all namespaces)
-	results, err := executor.Get(ctx, clusters, "pods", "", "")
+	results, err := executor.Get(ctx, clusters, ResourceQuery{Resource: "pods"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -379,7 +399,7 @@ func TestExecutorGet_AllNamespaces(t *testing.T) {
 func TestExecutorConfigDefaults(t *testing.T) {
 	configFlags := genericclioptions.NewConfigFlags(true)
 	manager, _ := kubeconfig.NewManager("")
-	executor := NewExecutor(manager, configFlags)
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
 
 	// Verify executor uses default config
 	if executor.config.MaxConcurrency != DefaultConfig().MaxConcurrency {
@@ -397,3 +417,688 @@ func TestExecutorConfigDefaults(t *testing.T) {
 			DefaultConfig().ContinueOnError, executor.config.ContinueOnError)
 	}
 }
+
+func TestExecutorRun_PreservesOrder(t *testing.T) {
+	configFlags := genericclioptions.NewConfigFlags(true)
+	manager, _ := kubeconfig.NewManager("")
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
+
+	clusters := []discovery.ClusterInfo{
+		{Name: "cluster1"},
+		{Name: "cluster2"},
+		{Name: "cluster3"},
+	}
+
+	// Later clusters finish first
+	delays := map[string]time.Duration{
+		"cluster1": 30 * time.Millisecond,
+		"cluster2": 15 * time.Millisecond,
+		"cluster3": 0,
+	}
+
+	results := executor.Run(context.Background(), clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		time.Sleep(delays[target.Cluster.Name])
+		return ClusterResult{Success: true}
+	})
+
+	if len(results.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results.Results))
+	}
+
+	for i, cluster := range clusters {
+		if results.Results[i].ClusterName != cluster.Name {
+			t.Errorf("expected result %d to be %s, got %s", i, cluster.Name, results.Results[i].ClusterName)
+		}
+	}
+
+	if results.Summary.Successful != 3 {
+		t.Errorf("expected 3 successful, got %d", results.Summary.Successful)
+	}
+}
+
+func TestExecutorRunStream_EmitsInCompletionOrder(t *testing.T) {
+	executor := NewExecutor(client.NewFakeProvider())
+
+	clusters := []discovery.ClusterInfo{
+		{Name: "cluster1"},
+		{Name: "cluster2"},
+		{Name: "cluster3"},
+	}
+
+	// Later clusters finish first
+	delays := map[string]time.Duration{
+		"cluster1": 40 * time.Millisecond,
+		"cluster2": 20 * time.Millisecond,
+		"cluster3": 0,
+	}
+
+	var emitted []string
+	results := executor.RunStream(context.Background(), clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		time.Sleep(delays[target.Cluster.Name])
+		return ClusterResult{Success: true}
+	}, func(result ClusterResult) {
+		emitted = append(emitted, result.ClusterName)
+	})
+
+	expected := []string{"cluster3", "cluster2", "cluster1"}
+	if fmt.Sprint(emitted) != fmt.Sprint(expected) {
+		t.Errorf("expected results emitted as %v, got %v", expected, emitted)
+	}
+
+	// The aggregated results keep the input order
+	if results.Results[0].ClusterName != "cluster1" || results.Summary.Successful != 3 {
+		t.Errorf("unexpected aggregated results: %+v", results)
+	}
+}
+
+func TestExecutorRun_ConcurrencyLimit(t *testing.T) {
+	configFlags := genericclioptions.NewConfigFlags(true)
+	manager, _ := kubeconfig.NewManager("")
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
+	executor.config.MaxConcurrency = 2
+
+	clusters := make([]discovery.ClusterInfo, 8)
+	for i := range clusters {
+		clusters[i] = discovery.ClusterInfo{Name: fmt.Sprintf("cluster%d", i)}
+	}
+
+	var running, peak int32
+	executor.Run(context.Background(), clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return ClusterResult{Success: true}
+	})
+
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent calls, got %d", peak)
+	}
+}
+
+func TestExecutorRun_RecoversPanic(t *testing.T) {
+	configFlags := genericclioptions.NewConfigFlags(true)
+	manager, _ := kubeconfig.NewManager("")
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
+
+	clusters := []discovery.ClusterInfo{
+		{Name: "good"},
+		{Name: "bad"},
+	}
+
+	results := executor.Run(context.Background(), clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		if target.Cluster.Name == "bad" {
+			panic("boom")
+		}
+		return ClusterResult{Success: true}
+	})
+
+	if results.Summary.Successful != 1 {
+		t.Errorf("expected 1 successful, got %d", results.Summary.Successful)
+	}
+
+	if results.Summary.Failed != 1 {
+		t.Errorf("expected 1 failed, got %d", results.Summary.Failed)
+	}
+
+	if _, exists := results.Summary.Errors["bad"]; !exists {
+		t.Error("expected error for panicking cluster")
+	}
+}
+
+func TestExecutorRun_Timeout(t *testing.T) {
+	configFlags := genericclioptions.NewConfigFlags(true)
+	manager, _ := kubeconfig.NewManager("")
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
+	executor.config.TimeoutSeconds = 1
+
+	clusters := []discovery.ClusterInfo{{Name: "slow"}}
+
+	results := executor.Run(context.Background(), clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		<-ctx.Done()
+		return ClusterResult{Error: ctx.Err()}
+	})
+
+	if !errors.Is(results.Summary.Errors["slow"], context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", results.Summary.Errors["slow"])
+	}
+}
+
+func TestExecutorRun_CancelledContext(t *testing.T) {
+	configFlags := genericclioptions.NewConfigFlags(true)
+	manager, _ := kubeconfig.NewManager("")
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	clusters := []discovery.ClusterInfo{
+		{Name: "cluster1"},
+		{Name: "cluster2"},
+	}
+
+	var calls int32
+	results := executor.Run(ctx, clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		atomic.AddInt32(&calls, 1)
+		return ClusterResult{Success: true}
+	})
+
+	if calls != 0 {
+		t.Errorf("expected no calls after cancellation, got %d", calls)
+	}
+
+	if results.Summary.Failed != 2 {
+		t.Errorf("expected 2 failed, got %d", results.Summary.Failed)
+	}
+}
+
+func TestExecutorRun_StopOnError(t *testing.T) {
+	configFlags := genericclioptions.NewConfigFlags(true)
+	manager, _ := kubeconfig.NewManager("")
+	executor := NewExecutor(client.NewKubeconfigProvider(manager, configFlags))
+	executor.config.MaxConcurrency = 1
+	executor.config.ContinueOnError = false
+
+	clusters := []discovery.ClusterInfo{
+		{Name: "cluster1"},
+		{Name: "cluster2"},
+		{Name: "cluster3"},
+	}
+
+	var calls int32
+	results := executor.Run(context.Background(), clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+		atomic.AddInt32(&calls, 1)
+		return ClusterResult{Error: fmt.Errorf("failed")}
+	})
+
+	if calls != 1 {
+		t.Errorf("expected 1 call before stopping, got %d", calls)
+	}
+
+	if results.Summary.Failed != 3 {
+		t.Errorf("expected 3 failed, got %d", results.Summary.Failed)
+	}
+}
+
+// newFakePod builds an unstructured pod for fake dynamic clients
+func newFakePod(namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+		},
+	}
+}
+
+// newFakeProvider registers a fake dynamic client per cluster holding the given objects
+func newFakeProvider(objects map[string][]runtime.Object) *client.FakeProvider {
+	provider := client.NewFakeProvider()
+	for cluster, objs := range objects {
+		dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+			runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				{Group: "", Version: "v1", Resource: "pods"}:     "PodList",
+				{Group: "", Version: "v1", Resource: "services"}: "ServiceList",
+			},
+			objs...,
+		)
+		provider.SetClients(cluster, client.FakeClusterClients{
+			Dynamic:   dynamicClient,
+			Discovery: &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}},
+		})
+	}
+	return provider
+}
+
+func TestExecutorGet_FakeClusters(t *testing.T) {
+	provider := newFakeProvider(map[string][]runtime.Object{
+		"cluster1": {newFakePod("default", "nginx-1"), newFakePod("default", "redis-1")},
+		"cluster2": {newFakePod("default", "nginx-2"), newFakePod("other", "nginx-3")},
+	})
+	executor := NewExecutor(provider)
+
+	clusters := []discovery.ClusterInfo{
+		{Name: "cluster1"},
+		{Name: "cluster2"},
+		{Name: "unregistered"},
+	}
+
+	results, err := executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pods", Namespace: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if results.Summary.Successful != 2 {
+		t.Errorf("expected 2 successful, got %d", results.Summary.Successful)
+	}
+
+	if results.Summary.Failed != 1 {
+		t.Errorf("expected 1 failed, got %d", results.Summary.Failed)
+	}
+
+	if len(results.Results[0].Items) != 2 {
+		t.Errorf("expected 2 pods in cluster1, got %d", len(results.Results[0].Items))
+	}
+
+	if len(results.Results[1].Items) != 1 {
+		t.Errorf("expected 1 pod in cluster2 default namespace, got %d", len(results.Results[1].Items))
+	}
+}
+
+func TestExecutorGet_FakeClusters_Wildcard(t *testing.T) {
+	provider := newFakeProvider(map[string][]runtime.Object{
+		"cluster1": {newFakePod("default", "nginx-1"), newFakePod("default", "redis-1")},
+	})
+	executor := NewExecutor(provider)
+
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}}
+
+	results, err := executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pods", Name: "nginx-*"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items := results.Results[0].Items
+	if len(items) != 1 || items[0].GetName() != "nginx-1" {
+		t.Errorf("expected only nginx-1, got %v", items)
+	}
+}
+
+func TestExecutorGet_FakeClusters_ByName(t *testing.T) {
+	provider := newFakeProvider(map[string][]runtime.Object{
+		"cluster1": {newFakePod("default", "nginx-1")},
+		"cluster2": {newFakePod("default", "redis-1")},
+	})
+	executor := NewExecutor(provider)
+
+	clusters := []discovery.ClusterInfo{
+		{Name: "cluster1"},
+		{Name: "cluster2"},
+	}
+
+	results, err := executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pod", Name: "nginx-1", Namespace: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !results.Results[0].Success || len(results.Results[0].Items) != 1 {
+		t.Errorf("expected nginx-1 to be found in cluster1")
+	}
+
+	if results.Results[1].Success {
+		t.Error("expected not found error for cluster2")
+	}
+}
+
+func TestExecutorGet_FakeClusters_LabelSelector(t *testing.T) {
+	labeled := newFakePod("default", "nginx-1")
+	labeled.SetLabels(map[string]string{"app": "nginx"})
+
+	provider := newFakeProvider(map[string][]runtime.Object{
+		"cluster1": {labeled, newFakePod("default", "redis-1")},
+	})
+	executor := NewExecutor(provider)
+
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}}
+
+	results, err := executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pods", LabelSelector: "app=nginx"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items := results.Results[0].Items
+	if len(items) != 1 || items[0].GetName() != "nginx-1" {
+		t.Errorf("expected only nginx-1, got %v", items)
+	}
+}
+
+func TestFetchItems_PrefixFallback(t *testing.T) {
+	provider := newFakeProvider(map[string][]runtime.Object{
+		"cluster1": {newFakePod("default", "nginx-abc"), newFakePod("default", "nginx-def"), newFakePod("default", "redis-1")},
+	})
+	executor := NewExecutor(provider)
+
+	dynamicClient, _ := provider.DynamicClient(discovery.ClusterInfo{Name: "cluster1"})
+	mapping, err := executor.resolveMapping(nil, "pods")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	query := ResourceQuery{Resource: "pods", Name: "nginx", Namespace: "default"}
+
+	// Without the fallback, a missing exact name is an error
+	if _, err := executor.fetchItems(context.Background(), dynamicClient, mapping, query, false); err == nil {
+		t.Error("expected not found error without prefix fallback")
+	}
+
+	// With the fallback, the name matches by prefix like kubectl describe
+	items, err := executor.fetchItems(context.Background(), dynamicClient, mapping, query, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("expected 2 prefix matches, got %d", len(items))
+	}
+
+	query.Name = "missing"
+	if _, err := executor.fetchItems(context.Background(), dynamicClient, mapping, query, true); err == nil {
+		t.Error("expected not found error when nothing matches the prefix")
+	}
+}
+
+func TestResolveMapping_Discovery(t *testing.T) {
+	provider := client.NewFakeProvider()
+	executor := NewExecutor(provider)
+
+	discoveryClient := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "example.com/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", SingularName: "widget", Kind: "Widget", Namespaced: true, ShortNames: []string{"wdg"}, Verbs: metav1.Verbs{"get", "list"}},
+			},
+		},
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "nodes", SingularName: "node", Kind: "Node", Namespaced: false, ShortNames: []string{"no"}, Verbs: metav1.Verbs{"get", "list"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		resource     string
+		expectedKind string
+		namespaced   bool
+	}{
+		{resource: "widgets", expectedKind: "Widget", namespaced: true},
+		{resource: "widget", expectedKind: "Widget", namespaced: true},
+		{resource: "wdg", expectedKind: "Widget", namespaced: true},
+		{resource: "widgets.example.com", expectedKind: "Widget", namespaced: true},
+		{resource: "no", expectedKind: "Node", namespaced: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			mapping, err := executor.resolveMapping(discoveryClient, tt.resource)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if mapping.GroupVersionKind.Kind != tt.expectedKind {
+				t.Errorf("expected kind %s, got %s", tt.expectedKind, mapping.GroupVersionKind.Kind)
+			}
+
+			namespaced := mapping.Scope.Name() == "namespace"
+			if namespaced != tt.namespaced {
+				t.Errorf("expected namespaced %v, got %v", tt.namespaced, namespaced)
+			}
+		})
+	}
+
+	if _, err := executor.resolveMapping(discoveryClient, "gadgets"); err == nil {
+		t.Error("expected error for unknown resource")
+	}
+}
+
+// staleDiscovery serves cached discovery information until it is invalidated
+type staleDiscovery struct {
+	*discoveryfake.FakeDiscovery
+	current     []*metav1.APIResourceList
+	invalidated int
+}
+
+func (d *staleDiscovery) Fresh() bool {
+	return d.invalidated > 0
+}
+
+func (d *staleDiscovery) Invalidate() {
+	d.invalidated++
+	d.Resources = d.current
+}
+
+func TestResolveMapping_InvalidatesStaleCache(t *testing.T) {
+	widgets := &metav1.APIResourceList{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{{Name: "widgets", SingularName: "widget", Kind: "Widget", Namespaced: true, Verbs: metav1.Verbs{"get", "list"}}},
+	}
+	discoveryClient := &staleDiscovery{
+		FakeDiscovery: &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}},
+		current:       []*metav1.APIResourceList{widgets},
+	}
+	resolver := newResourceResolver(discoveryClient)
+
+	// The CRD was installed after the cache was written
+	mapping, err := resolver.mapping("widgets")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mapping.GroupVersionKind.Kind != "Widget" {
+		t.Errorf("expected kind Widget, got %s", mapping.GroupVersionKind.Kind)
+	}
+
+	// Fresh information is not invalidated again
+	if _, err := resolver.mapping("gadgets"); err == nil {
+		t.Error("expected error for unknown resource")
+	}
+	if discoveryClient.invalidated != 1 {
+		t.Errorf("expected 1 invalidation, got %d", discoveryClient.invalidated)
+	}
+}
+
+// newFakeService builds an unstructured service for fake dynamic clients
+func newFakeService(namespace, name string) *unstructured.Unstructured {
+	svc := newFakePod(namespace, name)
+	svc.SetKind("Service")
+	return svc
+}
+
+func TestExecutorGet_FakeClusters_MultipleTypes(t *testing.T) {
+	provider := newFakeProvider(map[string][]runtime.Object{
+		"cluster1": {newFakePod("default", "nginx-1"), newFakeService("default", "nginx")},
+	})
+	executor := NewExecutor(provider)
+
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}}
+
+	results, err := executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pods,services", Namespace: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := results.Results[0]
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+
+	kinds := map[string]int{}
+	for _, item := range result.Items {
+		kinds[item.GetKind()]++
+	}
+	if kinds["Pod"] != 1 || kinds["Service"] != 1 {
+		t.Errorf("expected one pod and one service, got %v", kinds)
+	}
+}
+
+func TestExecutorGet_FakeClusters_PartialTypes(t *testing.T) {
+	provider := newFakeProvider(map[string][]runtime.Object{
+		"cluster1": {newFakePod("default", "nginx-1")},
+	})
+	executor := NewExecutor(provider)
+
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}}
+
+	// "gadgets" cannot be resolved, but pods can
+	results, err := executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pods,gadgets", Namespace: "default"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := results.Results[0]
+	if !result.Success {
+		t.Error("expected partial success")
+	}
+	if result.Error == nil {
+		t.Error("expected error for the unresolvable type")
+	}
+	if len(result.Items) != 1 {
+		t.Errorf("expected 1 item, got %d", len(result.Items))
+	}
+}
+
+func TestResourceResolverExpand(t *testing.T) {
+	discoveryClient := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "pods", Kind: "Pod", Namespaced: true, Categories: []string{"all"}},
+				{Name: "services", Kind: "Service", Namespaced: true, Categories: []string{"all"}},
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			},
+		},
+		{
+			GroupVersion: "apps/v1",
+			APIResources: []metav1.APIResource{
+				{Name: "deployments", Kind: "Deployment", Namespaced: true, Categories: []string{"all"}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		discovery k8sdiscovery.DiscoveryInterface
+		resources string
+		expected  []string
+	}{
+		{
+			name:      "single",
+			resources: "pods",
+			expected:  []string{"pods"},
+		},
+		{
+			name:      "comma separated with duplicates",
+			resources: "pods, svc,pods",
+			expected:  []string{"pods", "svc"},
+		},
+		{
+			name:      "all from discovery",
+			discovery: discoveryClient,
+			resources: "all",
+			expected:  []string{"pods", "services", "deployments.apps"},
+		},
+		{
+			name:      "all plus extra type",
+			discovery: discoveryClient,
+			resources: "all,configmaps",
+			expected:  []string{"pods", "services", "deployments.apps", "configmaps"},
+		},
+		{
+			name:      "all without discovery",
+			resources: "all",
+			expected:  legacyAllResources,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newResourceResolver(tt.discovery).expand(tt.resources)
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// fakeVerifier refuses the listed clusters, fails the unreachable ones
+// and counts the checks
+type fakeVerifier struct {
+	refused     map[string]bool
+	unreachable map[string]bool
+	checks      atomic.Int32
+}
+
+func (v *fakeVerifier) VerifyIdentity(ctx context.Context, cluster discovery.ClusterInfo) error {
+	v.checks.Add(1)
+	if v.refused[cluster.Name] {
+		return &client.IdentityMismatchError{Cluster: cluster.Name, Reason: "cluster ID is \"other\""}
+	}
+	if v.unreachable[cluster.Name] {
+		return fmt.Errorf("failed to verify cluster identity: connection refused")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func TestExecutorRun_VerifiesIdentity(t *testing.T) {
+	executor := NewExecutor(client.NewFakeProvider())
+	verifier := &fakeVerifier{refused: map[string]bool{"cluster2": true}}
+	executor.SetVerifier(verifier)
+
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}, {Name: "cluster2"}}
+	var calls atomic.Int32
+	run := func() *AggregatedResults {
+		return executor.Run(context.Background(), clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+			calls.Add(1)
+			return ClusterResult{Success: true}
+		})
+	}
+
+	results := run()
+	if results.Summary.Successful != 1 || results.Summary.Failed != 1 {
+		t.Errorf("expected 1 successful and 1 failed, got %+v", results.Summary)
+	}
+	if results.Summary.Errors["cluster2"] == nil {
+		t.Error("expected cluster2 to be refused")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected the operation to run only on the verified cluster, ran %d times", calls.Load())
+	}
+
+	// Each cluster is checked once per executor
+	run()
+	if verifier.checks.Load() != 2 {
+		t.Errorf("expected 2 identity checks, got %d", verifier.checks.Load())
+	}
+}
+
+func TestVerifyCluster_RetriesInconclusiveChecks(t *testing.T) {
+	executor := NewExecutor(client.NewFakeProvider())
+	verifier := &fakeVerifier{unreachable: map[string]bool{"cluster2": true}}
+	executor.SetVerifier(verifier)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := executor.VerifyCluster(cancelled, discovery.ClusterInfo{Name: "cluster1"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled check to fail with context.Canceled, got %v", err)
+	}
+	if err := executor.VerifyCluster(context.Background(), discovery.ClusterInfo{Name: "cluster1"}); err != nil {
+		t.Errorf("expected the check to be retried after a cancelled request, got %v", err)
+	}
+	if err := executor.VerifyCluster(context.Background(), discovery.ClusterInfo{Name: "cluster1"}); err != nil {
+		t.Errorf("expected the passed check to be kept, got %v", err)
+	}
+	if verifier.checks.Load() != 2 {
+		t.Errorf("expected 2 identity checks for cluster1, got %d", verifier.checks.Load())
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := executor.VerifyCluster(context.Background(), discovery.ClusterInfo{Name: "cluster2"}); err == nil {
+			t.Error("expected the unreachable cluster to fail the check")
+		}
+	}
+	if verifier.checks.Load() != 4 {
+		t.Errorf("expected the unreachable cluster to be checked on every call, got %d checks in total", verifier.checks.Load())
+	}
+}
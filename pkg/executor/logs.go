@@ -0,0 +1,448 @@
+package executor
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultContainerAnnotation names the container kubectl logs reads by default
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// maxLogLineSize bounds a single log line; longer lines are split into
+// several LogLines
+const maxLogLineSize = 1024 * 1024
+
+// ErrNoPodsFound is reported for a cluster on which the query matched no pods.
+// A pod usually only exists on some clusters, so callers typically only surface
+// it when every cluster reports it.
+var ErrNoPodsFound = errors.New("no pods found")
+
+// LogOptions configures how container logs are retrieved
+type LogOptions struct {
+	// Container selects a single container; empty selects the pod's default container
+	Container string
+
+	// AllContainers reads every container of each pod
+	AllContainers bool
+
+	// Follow keeps the log streams open
+	Follow bool
+
+	// Since only returns logs newer than this duration; zero returns all logs
+	Since time.Duration
+
+	// Tail limits the number of lines per container; negative returns all lines
+	Tail int64
+
+	// Timestamps asks the API server for timestamps, which are parsed into
+	// LogLine.Timestamp rather than left in the text
+	Timestamps bool
+}
+
+// LogLine is a single log line from one container, or a cluster error when Error is set
+type LogLine struct {
+	Cluster   string
+	Namespace string
+	Pod       string
+	Container string
+
+	// Timestamp is set when LogOptions.Timestamps was requested
+	Timestamp time.Time
+	Text      string
+
+	Error error
+}
+
+// Logs streams the logs of the pods selected by query from every cluster into a
+// single channel. query.Resource is either pods, in which case Name may be an
+// exact name or a wildcard and LabelSelector applies, or a workload type such as
+// deployments, whose pods are found through the workload's selector. Lines of a
+// single container are delivered in order. The channel is closed once every
+// stream has ended, or when ctx is cancelled.
+//
+// Without Follow, clusters are read like Run: at most MaxConcurrency at once,
+// each within the per-cluster timeout, stopping at the first failure unless
+// ContinueOnError is set. Followed streams never end, so every cluster is
+// streamed at once and without a timeout.
+func (e *Executor) Logs(ctx context.Context, clusters []discovery.ClusterInfo, query ResourceQuery, opts LogOptions) <-chan LogLine {
+	lines := make(chan LogLine)
+
+	if !opts.Follow {
+		go func() {
+			defer close(lines)
+			e.RunStream(ctx, clusters, func(ctx context.Context, target ClusterTarget) ClusterResult {
+				err := e.logsFromCluster(ctx, target.Cluster, query, opts, lines)
+				// A pod usually only exists on some clusters, which must not stop the others
+				return ClusterResult{ClusterName: target.Cluster.Name, Success: err == nil || errors.Is(err, ErrNoPodsFound), Error: err}
+			}, func(result ClusterResult) {
+				if result.Error != nil {
+					sendLine(ctx, lines, LogLine{Cluster: result.ClusterName, Error: result.Error})
+				}
+			})
+		}()
+		return lines
+	}
+
+	var wg sync.WaitGroup
+	for _, cluster := range clusters {
+		wg.Add(1)
+		go func(c discovery.ClusterInfo) {
+			defer wg.Done()
+			if err := e.logsFromCluster(ctx, c, query, opts, lines); err != nil {
+				sendLine(ctx, lines, LogLine{Cluster: c.Name, Error: err})
+			}
+		}(cluster)
+	}
+
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	return lines
+}
+
+// logsFromCluster streams the logs of the selected pods on a single cluster.
+// Without Follow, containers are read one after another so their lines stay
+// grouped; with Follow, every container is streamed at once.
+func (e *Executor) logsFromCluster(ctx context.Context, cluster discovery.ClusterInfo, query ResourceQuery, opts LogOptions, lines chan<- LogLine) error {
+	if err := e.VerifyCluster(ctx, cluster); err != nil {
+		return err
+	}
+
+	clientset, err := e.clients.Clientset(cluster)
+	if err != nil {
+		return fmt.Errorf("failed to create clientset: %w", err)
+	}
+
+	namespaces, err := e.queryNamespaces(ctx, cluster, query)
+	if err != nil {
+		return err
+	}
+
+	var pods []corev1.Pod
+	for _, namespace := range namespaces {
+		query.Namespace = namespace
+		selected, err := e.selectPods(ctx, cluster, clientset, query)
+		if errors.Is(err, ErrNoPodsFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		pods = append(pods, selected...)
+	}
+	if len(pods) == 0 {
+		return ErrNoPodsFound
+	}
+
+	var errs []error
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := range pods {
+		pod := &pods[i]
+		containers, err := logContainers(pod, opts)
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+			continue
+		}
+
+		for _, container := range containers {
+			stream := func() {
+				if err := streamContainerLogs(ctx, clientset, cluster.Name, pod, container, opts, lines); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}
+
+			if !opts.Follow {
+				stream()
+				continue
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stream()
+			}()
+		}
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// selectPods returns the pods selected by query on a single cluster
+func (e *Executor) selectPods(ctx context.Context, cluster discovery.ClusterInfo, clientset kubernetes.Interface, query ResourceQuery) ([]corev1.Pod, error) {
+	pods := clientset.CoreV1().Pods(query.Namespace)
+
+	resource := strings.ToLower(query.Resource)
+	if resource == "" || resource == "pod" || resource == "pods" || resource == "po" {
+		if query.Name != "" && !isWildcard(query.Name) {
+			pod, err := pods.Get(ctx, query.Name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get pod: %w", err)
+			}
+			return []corev1.Pod{*pod}, nil
+		}
+
+		list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: query.LabelSelector})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
+		}
+
+		var selected []corev1.Pod
+		for _, pod := range list.Items {
+			if query.Name != "" {
+				if matched, err := filepath.Match(query.Name, pod.Name); err != nil || !matched {
+					continue
+				}
+			}
+			selected = append(selected, pod)
+		}
+		return selected, nil
+	}
+
+	// A workload (e.g. deploy/nginx): select its pods through the workload's selector
+	selector, err := e.workloadSelector(ctx, cluster, query)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := pods.List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return list.Items, nil
+}
+
+// workloadSelector returns the pod selector of the workload named by query
+func (e *Executor) workloadSelector(ctx context.Context, cluster discovery.ClusterInfo, query ResourceQuery) (labels.Selector, error) {
+	if query.Name == "" {
+		return nil, fmt.Errorf("a name is required to select pods through %s", query.Resource)
+	}
+
+	dynamicClient, err := e.clients.DynamicClient(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	discoveryClient, err := e.clients.DiscoveryClient(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discovery client: %w", err)
+	}
+
+	mapping, err := e.resolveMapping(discoveryClient, query.Resource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve resource type: %w", err)
+	}
+
+	workload, err := namespacedResource(dynamicClient, mapping, query.Namespace).Get(ctx, query.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrNoPodsFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", query.Resource, err)
+	}
+
+	return podSelector(workload)
+}
+
+// podSelector extracts the pod selector from a workload. Services use a plain
+// label map; every other workload uses a LabelSelector in spec.selector.
+func podSelector(workload *unstructured.Unstructured) (labels.Selector, error) {
+	kind := workload.GetKind()
+	raw, found, _ := unstructured.NestedMap(workload.Object, "spec", "selector")
+	if !found || len(raw) == 0 {
+		return nil, fmt.Errorf("%s %s has no pod selector", strings.ToLower(kind), workload.GetName())
+	}
+
+	if kind == "Service" || kind == "ReplicationController" {
+		set := labels.Set{}
+		for key, value := range raw {
+			set[key] = fmt.Sprint(value)
+		}
+		return labels.SelectorFromSet(set), nil
+	}
+
+	labelSelector := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, labelSelector); err != nil {
+		return nil, fmt.Errorf("failed to parse selector of %s %s: %w", strings.ToLower(kind), workload.GetName(), err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse selector of %s %s: %w", strings.ToLower(kind), workload.GetName(), err)
+	}
+	return selector, nil
+}
+
+// logContainers returns the containers whose logs are read for a pod
+func logContainers(pod *corev1.Pod, opts LogOptions) ([]string, error) {
+	if opts.Container != "" {
+		for _, container := range pod.Spec.Containers {
+			if container.Name == opts.Container {
+				return []string{opts.Container}, nil
+			}
+		}
+		for _, container := range pod.Spec.InitContainers {
+			if container.Name == opts.Container {
+				return []string{opts.Container}, nil
+			}
+		}
+		return nil, fmt.Errorf("container %s is not valid for pod %s", opts.Container, pod.Name)
+	}
+
+	if len(pod.Spec.Containers) == 0 {
+		return nil, fmt.Errorf("pod %s has no containers", pod.Name)
+	}
+
+	if opts.AllContainers {
+		containers := make([]string, 0, len(pod.Spec.Containers))
+		for _, container := range pod.Spec.Containers {
+			containers = append(containers, container.Name)
+		}
+		return containers, nil
+	}
+
+	// Same default as kubectl: the annotated default container, else the first one
+	if name := pod.Annotations[defaultContainerAnnotation]; name != "" {
+		for _, container := range pod.Spec.Containers {
+			if container.Name == name {
+				return []string{name}, nil
+			}
+		}
+	}
+	return []string{pod.Spec.Containers[0].Name}, nil
+}
+
+// streamContainerLogs sends the log lines of one container
+func streamContainerLogs(ctx context.Context, clientset kubernetes.Interface, cluster string, pod *corev1.Pod, container string, opts LogOptions, lines chan<- LogLine) error {
+	logOptions := &corev1.PodLogOptions{
+		Container:  container,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+	}
+	if opts.Since > 0 {
+		seconds := int64(opts.Since.Seconds())
+		logOptions.SinceSeconds = &seconds
+	}
+	if opts.Tail >= 0 {
+		tail := opts.Tail
+		logOptions.TailLines = &tail
+	}
+
+	stream, err := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, logOptions).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to stream logs of %s/%s: %w", pod.Name, container, err)
+	}
+	defer stream.Close()
+
+	var timestamp time.Time
+	err = readLogLines(stream, func(text string, continued bool) bool {
+		line := LogLine{
+			Cluster:   cluster,
+			Namespace: pod.Namespace,
+			Pod:       pod.Name,
+			Container: container,
+			Text:      text,
+		}
+		if opts.Timestamps {
+			// Only the first part of a split line carries the timestamp
+			if continued {
+				line.Timestamp = timestamp
+			} else {
+				line.Timestamp, line.Text = splitLogTimestamp(text)
+				timestamp = line.Timestamp
+			}
+		}
+		return sendLine(ctx, lines, line)
+	})
+
+	// A stream cut short by a timeout or cancellation is incomplete
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("failed to read logs of %s/%s: %w", pod.Name, container, ctxErr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read logs of %s/%s: %w", pod.Name, container, err)
+	}
+	return nil
+}
+
+// readLogLines calls emit with each line of r until r ends or emit returns
+// false. Lines longer than maxLogLineSize are passed in several parts, the
+// later ones with continued set.
+func readLogLines(r io.Reader, emit func(text string, continued bool) bool) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+
+	var line []byte
+	continued := false
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		line = append(line, chunk...)
+		if isPrefix && len(line) < maxLogLineSize {
+			continue
+		}
+
+		if !emit(string(line), continued) {
+			return nil
+		}
+		line = line[:0]
+		continued = isPrefix
+	}
+}
+
+// splitLogTimestamp separates the RFC3339 timestamp the API server prefixes
+// lines with when timestamps are requested
+func splitLogTimestamp(text string) (time.Time, string) {
+	prefix, rest, found := strings.Cut(text, " ")
+	if !found {
+		prefix, rest = text, ""
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, prefix)
+	if err != nil {
+		return time.Time{}, text
+	}
+	return timestamp, rest
+}
+
+// sendLine delivers a log line unless ctx is cancelled first
+func sendLine(ctx context.Context, lines chan<- LogLine, line LogLine) bool {
+	select {
+	case lines <- line:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,355 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	kubernetesfake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// newLogPod builds a typed pod with the given containers
+func newLogPod(name string, podLabels map[string]string, containers ...string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: podLabels},
+	}
+	for _, container := range containers {
+		pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: container})
+	}
+	return pod
+}
+
+// collectLogs drains a log channel, sorted by cluster, pod and container
+func collectLogs(t *testing.T, lines <-chan LogLine) []LogLine {
+	t.Helper()
+
+	var collected []LogLine
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				sort.Slice(collected, func(i, j int) bool {
+					a, b := collected[i], collected[j]
+					if a.Cluster != b.Cluster {
+						return a.Cluster < b.Cluster
+					}
+					if a.Pod != b.Pod {
+						return a.Pod < b.Pod
+					}
+					return a.Container < b.Container
+				})
+				return collected
+			}
+			collected = append(collected, line)
+		case <-timeout:
+			t.Fatal("timed out waiting for logs")
+		}
+	}
+}
+
+func TestExecutorLogs_PodsAcrossClusters(t *testing.T) {
+	provider := client.NewFakeProvider()
+	provider.SetClients("cluster1", client.FakeClusterClients{
+		Clientset: kubernetesfake.NewClientset(
+			newLogPod("nginx-1", nil, "nginx", "sidecar"),
+			newLogPod("redis-1", nil, "redis"),
+		),
+	})
+	provider.SetClients("cluster2", client.FakeClusterClients{
+		Clientset: kubernetesfake.NewClientset(newLogPod("nginx-2", nil, "nginx")),
+	})
+
+	exec := NewExecutor(provider)
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}, {Name: "cluster2"}}
+
+	lines := collectLogs(t, exec.Logs(context.Background(), clusters,
+		ResourceQuery{Resource: "pods", Name: "nginx-*", Namespace: "default"},
+		LogOptions{Tail: -1}))
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d: %+v", len(lines), lines)
+	}
+
+	// Only the default (first) container is read
+	expected := []LogLine{
+		{Cluster: "cluster1", Namespace: "default", Pod: "nginx-1", Container: "nginx", Text: "fake logs"},
+		{Cluster: "cluster2", Namespace: "default", Pod: "nginx-2", Container: "nginx", Text: "fake logs"},
+	}
+	for i, want := range expected {
+		if lines[i] != want {
+			t.Errorf("expected line %+v, got %+v", want, lines[i])
+		}
+	}
+}
+
+func TestExecutorLogs_AllContainersAndSelector(t *testing.T) {
+	provider := client.NewFakeProvider()
+	provider.SetClients("cluster1", client.FakeClusterClients{
+		Clientset: kubernetesfake.NewClientset(
+			newLogPod("web-1", map[string]string{"app": "web"}, "web", "sidecar"),
+			newLogPod("db-1", map[string]string{"app": "db"}, "db"),
+		),
+	})
+
+	exec := NewExecutor(provider)
+
+	lines := collectLogs(t, exec.Logs(context.Background(), []discovery.ClusterInfo{{Name: "cluster1"}},
+		ResourceQuery{Resource: "pods", Namespace: "default", LabelSelector: "app=web"},
+		LogOptions{AllContainers: true, Follow: true, Tail: -1}))
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d: %+v", len(lines), lines)
+	}
+	if lines[0].Container != "sidecar" || lines[1].Container != "web" {
+		t.Errorf("expected lines from both containers, got %+v", lines)
+	}
+}
+
+func TestExecutorLogs_Deployment(t *testing.T) {
+	deployment := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "default"},
+		"spec": map[string]interface{}{
+			"selector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": "web"},
+			},
+		},
+	}}
+
+	provider := client.NewFakeProvider()
+	provider.SetClients("cluster1", client.FakeClusterClients{
+		Clientset: kubernetesfake.NewClientset(
+			newLogPod("web-abc", map[string]string{"app": "web"}, "web"),
+			newLogPod("other", map[string]string{"app": "other"}, "other"),
+		),
+		Dynamic:   dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), deployment),
+		Discovery: &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}},
+	})
+
+	exec := NewExecutor(provider)
+
+	lines := collectLogs(t, exec.Logs(context.Background(), []discovery.ClusterInfo{{Name: "cluster1"}},
+		ResourceQuery{Resource: "deployment", Name: "web", Namespace: "default"},
+		LogOptions{Tail: 10}))
+
+	if len(lines) != 1 || lines[0].Pod != "web-abc" {
+		t.Errorf("expected logs from web-abc only, got %+v", lines)
+	}
+}
+
+func TestExecutorLogs_Errors(t *testing.T) {
+	provider := client.NewFakeProvider()
+	provider.SetClients("cluster1", client.FakeClusterClients{
+		Clientset: kubernetesfake.NewClientset(newLogPod("nginx", nil, "nginx")),
+	})
+	provider.SetClients("cluster2", client.FakeClusterClients{
+		Clientset: kubernetesfake.NewClientset(),
+	})
+
+	exec := NewExecutor(provider)
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}, {Name: "cluster2"}, {Name: "cluster3"}}
+
+	lines := collectLogs(t, exec.Logs(context.Background(), clusters,
+		ResourceQuery{Resource: "pods", Name: "nginx", Namespace: "default"},
+		LogOptions{Container: "missing", Tail: -1}))
+
+	if len(lines) != 3 {
+		t.Fatalf("expected one error per cluster, got %+v", lines)
+	}
+	for _, line := range lines {
+		if line.Error == nil {
+			t.Errorf("expected error for %s, got %+v", line.Cluster, line)
+		}
+	}
+
+	// A pod that only exists elsewhere is reported as ErrNoPodsFound
+	if !errors.Is(lines[1].Error, ErrNoPodsFound) {
+		t.Errorf("expected ErrNoPodsFound for cluster2, got %v", lines[1].Error)
+	}
+
+	// Wildcards that match nothing report ErrNoPodsFound
+	lines = collectLogs(t, exec.Logs(context.Background(), clusters[1:2],
+		ResourceQuery{Resource: "pods", Name: "nginx-*", Namespace: "default"},
+		LogOptions{Tail: -1}))
+	if len(lines) != 1 || !errors.Is(lines[0].Error, ErrNoPodsFound) {
+		t.Errorf("expected ErrNoPodsFound, got %+v", lines)
+	}
+}
+
+func TestLogContainers_DefaultAnnotation(t *testing.T) {
+	pod := newLogPod("nginx", nil, "istio-proxy", "nginx")
+	pod.Annotations = map[string]string{defaultContainerAnnotation: "nginx"}
+
+	containers, err := logContainers(pod, LogOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(containers) != 1 || containers[0] != "nginx" {
+		t.Errorf("expected annotated default container, got %v", containers)
+	}
+}
+
+func TestPodSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		selector map[string]interface{}
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "match labels",
+			kind:     "Deployment",
+			selector: map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+			expected: "app=web",
+		},
+		{
+			name: "match expressions",
+			kind: "StatefulSet",
+			selector: map[string]interface{}{"matchExpressions": []interface{}{
+				map[string]interface{}{"key": "tier", "operator": "In", "values": []interface{}{"db"}},
+			}},
+			expected: "tier in (db)",
+		},
+		{
+			name:     "service label map",
+			kind:     "Service",
+			selector: map[string]interface{}{"app": "web"},
+			expected: "app=web",
+		},
+		{
+			name:    "no selector",
+			kind:    "Deployment",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workload := &unstructured.Unstructured{Object: map[string]interface{}{"kind": tt.kind}}
+			workload.SetName("web")
+			if tt.selector != nil {
+				_ = unstructured.SetNestedMap(workload.Object, tt.selector, "spec", "selector")
+			}
+
+			selector, err := podSelector(workload)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if selector.String() != tt.expected {
+				t.Errorf("expected selector %q, got %q", tt.expected, selector.String())
+			}
+		})
+	}
+}
+
+func TestSplitLogTimestamp(t *testing.T) {
+	timestamp, text := splitLogTimestamp("2024-05-01T10:00:00.123456789Z GET /healthz 200")
+	if text != "GET /healthz 200" {
+		t.Errorf("expected text without timestamp, got %q", text)
+	}
+	if want := time.Date(2024, 5, 1, 10, 0, 0, 123456789, time.UTC); !timestamp.Equal(want) {
+		t.Errorf("expected %v, got %v", want, timestamp)
+	}
+
+	// Lines without a timestamp are left untouched
+	timestamp, text = splitLogTimestamp("plain line")
+	if !timestamp.IsZero() || text != "plain line" {
+		t.Errorf("expected untouched line, got %v %q", timestamp, text)
+	}
+}
+
+func TestExecutorLogs_MaxConcurrency(t *testing.T) {
+	var active, peak atomic.Int32
+	provider := client.NewFakeProvider()
+	var clusters []discovery.ClusterInfo
+	for _, name := range []string{"cluster1", "cluster2", "cluster3"} {
+		clientset := kubernetesfake.NewClientset(newLogPod("nginx", nil, "nginx"))
+		clientset.PrependReactor("get", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return false, nil, nil
+		})
+		provider.SetClients(name, client.FakeClusterClients{Clientset: clientset})
+		clusters = append(clusters, discovery.ClusterInfo{Name: name})
+	}
+
+	exec := NewExecutor(provider)
+	exec.SetConfig(ExecutorConfig{MaxConcurrency: 1, TimeoutSeconds: 30, ContinueOnError: true})
+
+	lines := collectLogs(t, exec.Logs(context.Background(), clusters,
+		ResourceQuery{Resource: "pods", Name: "nginx", Namespace: "default"},
+		LogOptions{Tail: -1}))
+
+	if len(lines) != 3 {
+		t.Fatalf("expected a line per cluster, got %+v", lines)
+	}
+	if peak.Load() != 1 {
+		t.Errorf("expected at most 1 cluster read at once, got %d", peak.Load())
+	}
+}
+
+func TestReadLogLines(t *testing.T) {
+	long := strings.Repeat("x", maxLogLineSize*2+10)
+
+	tests := []struct {
+		name          string
+		input         string
+		wantLengths   []int
+		wantContinued []bool
+	}{
+		{name: "lines", input: "one\r\ntwo\nthree", wantLengths: []int{3, 3, 5}, wantContinued: []bool{false, false, false}},
+		{name: "empty lines", input: "\n\n", wantLengths: []int{0, 0}, wantContinued: []bool{false, false}},
+		{name: "long line", input: long + "\nnext\n", wantLengths: []int{maxLogLineSize, maxLogLineSize, 10, 4}, wantContinued: []bool{false, true, true, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lengths []int
+			var continued []bool
+			err := readLogLines(strings.NewReader(tt.input), func(text string, c bool) bool {
+				lengths = append(lengths, len(text))
+				continued = append(continued, c)
+				return true
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(lengths, tt.wantLengths) {
+				t.Errorf("expected line lengths %v, got %v", tt.wantLengths, lengths)
+			}
+			if !reflect.DeepEqual(continued, tt.wantContinued) {
+				t.Errorf("expected continued %v, got %v", tt.wantContinued, continued)
+			}
+		})
+	}
+}
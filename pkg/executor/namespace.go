@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// queryNamespaces returns the namespaces query selects on a cluster. A single
+// empty namespace selects all namespaces; glob patterns that match nothing
+// select none.
+func (e *Executor) queryNamespaces(ctx context.Context, cluster discovery.ClusterInfo, query ResourceQuery) ([]string, error) {
+	if query.PerClusterNamespace {
+		namespace, err := e.clients.Namespace(cluster)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the default namespace: %w", err)
+		}
+		return []string{namespace}, nil
+	}
+	if len(query.Namespaces) == 0 {
+		return []string{query.Namespace}, nil
+	}
+
+	namespaces := []string{}
+	seen := make(map[string]bool)
+	add := func(namespace string) {
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	var existing []string
+	for _, pattern := range query.Namespaces {
+		if !isWildcard(pattern) {
+			add(pattern)
+			continue
+		}
+
+		// Patterns are matched against the cluster's namespaces, listed once
+		if existing == nil {
+			var err error
+			if existing, err = e.listNamespaces(ctx, cluster); err != nil {
+				return nil, err
+			}
+		}
+		for _, namespace := range existing {
+			if matched, err := filepath.Match(pattern, namespace); err == nil && matched {
+				add(namespace)
+			}
+		}
+	}
+	return namespaces, nil
+}
+
+// listNamespaces returns the sorted names of a cluster's namespaces
+func (e *Executor) listNamespaces(ctx context.Context, cluster discovery.ClusterInfo) ([]string, error) {
+	dynamicClient, err := e.clients.DynamicClient(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dynamic client: %w", err)
+	}
+
+	list, err := dynamicClient.Resource(commonResources["namespaces"].gvr).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	names := make([]string, 0, len(list.Items))
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// forEachNamespace runs fetch with query scoped to each namespace, or once for
+// cluster-scoped resource types. With several namespaces, an exact name that
+// is missing from some of them is only an error when it is missing from all.
+// The returned bool reports whether any fetch succeeded.
+func forEachNamespace(mapping *meta.RESTMapping, query ResourceQuery, namespaces []string, fetch func(ResourceQuery) error) (bool, error) {
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		query.Namespace = ""
+		err := fetch(query)
+		return err == nil, err
+	}
+	if len(namespaces) == 1 {
+		query.Namespace = namespaces[0]
+		err := fetch(query)
+		return err == nil, err
+	}
+
+	var errs []error
+	succeeded, notFound := 0, 0
+	for _, namespace := range namespaces {
+		query.Namespace = namespace
+		err := fetch(query)
+		switch {
+		case err == nil:
+			succeeded++
+		case apierrors.IsNotFound(err):
+			notFound++
+		default:
+			errs = append(errs, fmt.Errorf("namespace %s: %w", namespace, err))
+		}
+	}
+	if notFound > 0 && notFound == len(namespaces) {
+		errs = append(errs, fmt.Errorf("failed to get resource: %s %q not found in namespaces %s", mapping.Resource.Resource, query.Name, strings.Join(namespaces, ",")))
+	}
+	return succeeded > 0 || len(namespaces) == 0, errors.Join(errs...)
+}
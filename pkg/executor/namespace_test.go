@@ -0,0 +1,139 @@
+package executor
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// newFakeNamespace builds an unstructured namespace for fake dynamic clients
+func newFakeNamespace(name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata":   map[string]interface{}{"name": name},
+	}}
+}
+
+// newNamespacedProvider registers clusters whose namespaces and pods can be
+// listed, with the given default namespace per cluster
+func newNamespacedProvider(objects map[string][]runtime.Object, defaults map[string]string) *client.FakeProvider {
+	provider := client.NewFakeProvider()
+	for cluster, objs := range objects {
+		dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+			runtime.NewScheme(),
+			map[schema.GroupVersionResource]string{
+				{Group: "", Version: "v1", Resource: "pods"}:       "PodList",
+				{Group: "", Version: "v1", Resource: "namespaces"}: "NamespaceList",
+			},
+			objs...,
+		)
+		provider.SetClients(cluster, client.FakeClusterClients{
+			Dynamic:   dynamicClient,
+			Discovery: &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}},
+			Namespace: defaults[cluster],
+		})
+	}
+	return provider
+}
+
+func TestExecutorGet_Namespaces(t *testing.T) {
+	provider := newNamespacedProvider(map[string][]runtime.Object{
+		"cluster1": {
+			newFakeNamespace("team-a"), newFakeNamespace("team-b"), newFakeNamespace("payments"),
+			newFakePod("team-a", "api-1"), newFakePod("team-b", "api-2"), newFakePod("payments", "api-3"),
+		},
+		"cluster2": {
+			newFakeNamespace("team-a"), newFakeNamespace("shop"),
+			newFakePod("team-a", "api-4"), newFakePod("shop", "api-5"),
+		},
+	}, map[string]string{"cluster1": "payments", "cluster2": "shop"})
+	executor := NewExecutor(provider)
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}, {Name: "cluster2"}}
+
+	tests := []struct {
+		name     string
+		query    ResourceQuery
+		expected string
+	}{
+		{
+			name:     "several namespaces",
+			query:    ResourceQuery{Resource: "pods", Namespaces: []string{"team-a", "team-b"}},
+			expected: "cluster1/team-a/api-1,cluster1/team-b/api-2,cluster2/team-a/api-4",
+		},
+		{
+			name:     "namespace pattern",
+			query:    ResourceQuery{Resource: "pods", Namespaces: []string{"team-*"}},
+			expected: "cluster1/team-a/api-1,cluster1/team-b/api-2,cluster2/team-a/api-4",
+		},
+		{
+			name:     "pattern matching nothing",
+			query:    ResourceQuery{Resource: "pods", Namespaces: []string{"ops-*"}},
+			expected: "",
+		},
+		{
+			name:     "per-cluster namespace",
+			query:    ResourceQuery{Resource: "pods", PerClusterNamespace: true},
+			expected: "cluster1/payments/api-3,cluster2/shop/api-5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := executor.Get(context.Background(), clusters, tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var found []string
+			for _, result := range results.Results {
+				if !result.Success {
+					t.Fatalf("%s: expected success, got error: %v", result.ClusterName, result.Error)
+				}
+				for _, item := range result.Items {
+					found = append(found, result.ClusterName+"/"+item.GetNamespace()+"/"+item.GetName())
+				}
+			}
+			sort.Strings(found)
+			if got := strings.Join(found, ","); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestExecutorGet_NameInSeveralNamespaces(t *testing.T) {
+	provider := newNamespacedProvider(map[string][]runtime.Object{
+		"cluster1": {newFakePod("team-a", "api"), newFakePod("team-b", "worker")},
+	}, nil)
+	executor := NewExecutor(provider)
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}}
+
+	// A name missing from some of the namespaces is not an error
+	results, err := executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pods", Name: "api", Namespaces: []string{"team-a", "team-b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := results.Results[0]; !result.Success || result.Error != nil || len(result.Items) != 1 {
+		t.Errorf("expected api from team-a only, got %d items (error: %v)", len(result.Items), result.Error)
+	}
+
+	// Missing from all of them is
+	results, err = executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pods", Name: "db", Namespaces: []string{"team-a", "team-b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result := results.Results[0]; result.Success || result.Error == nil || !strings.Contains(result.Error.Error(), "team-a,team-b") {
+		t.Errorf("expected a not found error naming the namespaces, got %v", result.Error)
+	}
+}
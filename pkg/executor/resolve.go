@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8sdiscovery "k8s.io/client-go/discovery"
+	"k8s.io/client-go/restmapper"
+)
+
+// allCategory is the resource category expanded by "kubectl get all"
+const allCategory = "all"
+
+// commonResource is a statically known resource type
+type commonResource struct {
+	gvr        schema.GroupVersionResource
+	kind       string
+	namespaced bool
+}
+
+// commonResources resolves the most frequently used resource types without a
+// discovery round trip. Everything else, including CRDs, goes through discovery.
+var commonResources = map[string]commonResource{
+	"pods":        {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}, kind: "Pod", namespaced: true},
+	"pod":         {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "pods"}, kind: "Pod", namespaced: true},
+	"services":    {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services"}, kind: "Service", namespaced: true},
+	"service":     {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "services"}, kind: "Service", namespaced: true},
+	"deployments": {gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, kind: "Deployment", namespaced: true},
+	"deployment":  {gvr: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, kind: "Deployment", namespaced: true},
+	"configmaps":  {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}, kind: "ConfigMap", namespaced: true},
+	"configmap":   {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "configmaps"}, kind: "ConfigMap", namespaced: true},
+	"secrets":     {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "secrets"}, kind: "Secret", namespaced: true},
+	"secret":      {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "secrets"}, kind: "Secret", namespaced: true},
+	"namespaces":  {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}, kind: "Namespace", namespaced: false},
+	"namespace":   {gvr: schema.GroupVersionResource{Group: "", Version: "v1", Resource: "namespaces"}, kind: "Namespace", namespaced: false},
+}
+
+// legacyAllResources is what "all" expands to when the cluster's discovery
+// information is unavailable. It mirrors kubectl's built-in expansion.
+var legacyAllResources = []string{
+	"pods",
+	"replicationcontrollers",
+	"services",
+	"daemonsets.apps",
+	"deployments.apps",
+	"replicasets.apps",
+	"statefulsets.apps",
+	"horizontalpodautoscalers.autoscaling",
+	"jobs.batch",
+	"cronjobs.batch",
+}
+
+// resourceResolver resolves resource names for a single cluster. The discovery
+// based REST mapper is built lazily and shared across lookups, so resolving
+// several resource types costs one round of discovery.
+type resourceResolver struct {
+	discovery k8sdiscovery.DiscoveryInterface
+	mapper    meta.RESTMapper
+}
+
+// newResourceResolver creates a resolver backed by the given discovery client, which may be nil
+func newResourceResolver(discoveryClient k8sdiscovery.DiscoveryInterface) *resourceResolver {
+	return &resourceResolver{discovery: discoveryClient}
+}
+
+// resolveMapping resolves a single resource name to its REST mapping
+func (e *Executor) resolveMapping(discoveryClient k8sdiscovery.DiscoveryInterface, resource string) (*meta.RESTMapping, error) {
+	return newResourceResolver(discoveryClient).mapping(resource)
+}
+
+// expand splits a comma-separated resource argument (e.g. "pods,svc,deploy") into
+// individual resource names, expanding "all" into the resource types of that category
+func (r *resourceResolver) expand(resources string) []string {
+	var expanded []string
+	seen := make(map[string]bool)
+
+	add := func(resource string) {
+		if resource != "" && !seen[resource] {
+			seen[resource] = true
+			expanded = append(expanded, resource)
+		}
+	}
+
+	for _, resource := range strings.Split(resources, ",") {
+		resource = strings.ToLower(strings.TrimSpace(resource))
+		if resource != allCategory {
+			add(resource)
+			continue
+		}
+
+		for _, all := range r.expandAll() {
+			add(all)
+		}
+	}
+
+	return expanded
+}
+
+// expandAll returns the resource types in the "all" category
+func (r *resourceResolver) expandAll() []string {
+	if r.discovery != nil {
+		groupResources, ok := restmapper.NewDiscoveryCategoryExpander(r.discovery).Expand(allCategory)
+		if ok && len(groupResources) > 0 {
+			resources := make([]string, 0, len(groupResources))
+			for _, gr := range groupResources {
+				resources = append(resources, gr.String())
+			}
+			return resources
+		}
+	}
+	return legacyAllResources
+}
+
+// mapping resolves a resource name as typed on the command line (plural, singular,
+// short name or resource.group) to its REST mapping. Common types are resolved
+// statically; anything else is looked up through the cluster's discovery API.
+func (r *resourceResolver) mapping(resource string) (*meta.RESTMapping, error) {
+	mapping, err := r.lookup(strings.ToLower(resource))
+	if err != nil && r.invalidate() {
+		// The type may be newer than the cached discovery information, e.g. a new CRD
+		mapping, err = r.lookup(strings.ToLower(resource))
+	}
+	return mapping, err
+}
+
+// invalidate drops discovery information served from a cache, reporting
+// whether a lookup is worth retrying
+func (r *resourceResolver) invalidate() bool {
+	cached, ok := r.discovery.(k8sdiscovery.CachedDiscoveryInterface)
+	if !ok || cached.Fresh() {
+		return false
+	}
+	cached.Invalidate()
+	r.mapper = nil
+	return true
+}
+
+// lookup resolves a lower-case resource name to its REST mapping
+func (r *resourceResolver) lookup(resource string) (*meta.RESTMapping, error) {
+	if common, ok := commonResources[resource]; ok {
+		scope := meta.RESTScopeNamespace
+		if !common.namespaced {
+			scope = meta.RESTScopeRoot
+		}
+		return &meta.RESTMapping{
+			Resource:         common.gvr,
+			GroupVersionKind: common.gvr.GroupVersion().WithKind(common.kind),
+			Scope:            scope,
+		}, nil
+	}
+
+	if resource == "" || r.discovery == nil {
+		return nil, fmt.Errorf("unknown resource type: %s", resource)
+	}
+
+	if r.mapper == nil {
+		// Discovery of individual API groups may fail (e.g. an unavailable
+		// aggregated API); the groups that were discovered are still usable
+		groupResources, err := restmapper.GetAPIGroupResources(r.discovery)
+		if err != nil && len(groupResources) == 0 {
+			return nil, fmt.Errorf("failed to discover resource types: %w", err)
+		}
+		r.mapper = restmapper.NewShortcutExpander(restmapper.NewDiscoveryRESTMapper(groupResources), r.discovery, nil)
+	}
+
+	// Accept both "resource.version.group" and "resource.group" forms, as kubectl does
+	var gvk schema.GroupVersionKind
+	fullySpecifiedGVR, groupResource := schema.ParseResourceArg(resource)
+	if fullySpecifiedGVR != nil {
+		gvk, _ = r.mapper.KindFor(*fullySpecifiedGVR)
+	}
+	if gvk.Empty() {
+		var err error
+		gvk, err = r.mapper.KindFor(groupResource.WithVersion(""))
+		if err != nil {
+			return nil, fmt.Errorf("unknown resource type: %s: %w", resource, err)
+		}
+	}
+
+	mapping, err := r.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map resource type %s: %w", resource, err)
+	}
+
+	return mapping, nil
+}
@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+)
+
+// tableAcceptHeader asks the API server to render resources as a Table, falling
+// back to plain JSON for servers or resources that do not support it
+const tableAcceptHeader = "application/json;as=Table;v=1;g=meta.k8s.io,application/json"
+
+// errTableNotSupported is returned when the server answers a table request with a plain object
+var errTableNotSupported = errors.New("server did not return a table")
+
+// fetchTable retrieves the resources selected by query as a server-side rendered
+// Table. Rows carry the object metadata (or the full object with TableObjects),
+// which is returned as items so callers can sort and filter without a second
+// request. Wildcard names filter the rows.
+func (e *Executor) fetchTable(ctx context.Context, restConfig *rest.Config, mapping *meta.RESTMapping, query ResourceQuery) (*metav1.Table, []unstructured.Unstructured, error) {
+	restClient, err := newTableRESTClient(restConfig, mapping.Resource.GroupVersion())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create REST client: %w", err)
+	}
+
+	namespaced := mapping.Scope.Name() != meta.RESTScopeNameRoot
+	hasWildcard := isWildcard(query.Name)
+
+	req := restClient.Get().
+		NamespaceIfScoped(query.Namespace, namespaced && query.Namespace != "").
+		Resource(mapping.Resource.Resource).
+		SetHeader("Accept", tableAcceptHeader)
+	if query.TableObjects {
+		req = req.Param("includeObject", string(metav1.IncludeObject))
+	}
+
+	if query.Name != "" && !hasWildcard {
+		req = req.Name(query.Name)
+	} else {
+		req = req.VersionedParams(&metav1.ListOptions{LabelSelector: query.LabelSelector}, metav1.ParameterCodec)
+	}
+
+	raw, err := req.Do(ctx).Raw()
+	if err != nil {
+		if query.Name != "" && !hasWildcard {
+			return nil, nil, fmt.Errorf("failed to get resource: %w", err)
+		}
+		return nil, nil, fmt.Errorf("failed to list resources: %w", err)
+	}
+
+	table := &metav1.Table{}
+	if err := json.Unmarshal(raw, table); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode table: %w", err)
+	}
+	if table.Kind != "Table" {
+		return nil, nil, errTableNotSupported
+	}
+
+	rows := table.Rows[:0]
+	items := make([]unstructured.Unstructured, 0, len(table.Rows))
+	for _, row := range table.Rows {
+		item := tableRowObject(row, mapping.GroupVersionKind)
+		if hasWildcard {
+			if matched, err := filepath.Match(query.Name, item.GetName()); err != nil || !matched {
+				continue
+			}
+		}
+		rows = append(rows, row)
+		items = append(items, item)
+	}
+	table.Rows = rows
+
+	return table, items, nil
+}
+
+// tableRowObject decodes the object metadata embedded in a table row
+func tableRowObject(row metav1.TableRow, gvk schema.GroupVersionKind) unstructured.Unstructured {
+	item := unstructured.Unstructured{Object: map[string]interface{}{}}
+	if len(row.Object.Raw) > 0 {
+		_ = json.Unmarshal(row.Object.Raw, &item.Object)
+	}
+
+	// Rows embed PartialObjectMetadata; report the kind of the listed resource instead
+	item.SetGroupVersionKind(gvk)
+	return item
+}
+
+// newTableRESTClient creates a REST client for a resource's group version
+func newTableRESTClient(restConfig *rest.Config, gv schema.GroupVersion) (*rest.RESTClient, error) {
+	config := rest.CopyConfig(restConfig)
+	config.GroupVersion = &gv
+	if gv.Group == "" {
+		config.APIPath = "/api"
+	} else {
+		config.APIPath = "/apis"
+	}
+	config.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	return rest.RESTClientFor(config)
+}
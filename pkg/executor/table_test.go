@@ -0,0 +1,103 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+)
+
+// newTableServer serves a pod table for /api/v1/namespaces/default/pods and
+// records the Accept header of the last request
+func newTableServer(t *testing.T, accept *string) *httptest.Server {
+	t.Helper()
+
+	row := func(name, status string) metav1.TableRow {
+		object, _ := json.Marshal(map[string]interface{}{
+			"apiVersion": "meta.k8s.io/v1",
+			"kind":       "PartialObjectMetadata",
+			"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		})
+		return metav1.TableRow{
+			Cells:  []interface{}{name, "1/1", status},
+			Object: runtime.RawExtension{Raw: object},
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*accept = r.Header.Get("Accept")
+		if r.URL.Path != "/api/v1/namespaces/default/pods" {
+			http.NotFound(w, r)
+			return
+		}
+
+		table := metav1.Table{
+			TypeMeta: metav1.TypeMeta{APIVersion: "meta.k8s.io/v1", Kind: "Table"},
+			ColumnDefinitions: []metav1.TableColumnDefinition{
+				{Name: "Name", Type: "string", Format: "name"},
+				{Name: "Ready", Type: "string"},
+				{Name: "Status", Type: "string"},
+			},
+			Rows: []metav1.TableRow{row("nginx-1", "Running"), row("redis-1", "CrashLoopBackOff")},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(table)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestExecutorGet_ServerTable(t *testing.T) {
+	var accept string
+	server := newTableServer(t, &accept)
+
+	provider := newFakeProvider(map[string][]runtime.Object{"cluster1": nil})
+	clients := client.FakeClusterClients{RESTConfig: &rest.Config{Host: server.URL}}
+	clients.Dynamic, _ = provider.DynamicClient(discovery.ClusterInfo{Name: "cluster1"})
+	clients.Discovery, _ = provider.DiscoveryClient(discovery.ClusterInfo{Name: "cluster1"})
+	provider.SetClients("cluster1", clients)
+	executor := NewExecutor(provider)
+
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}}
+
+	results, err := executor.Get(context.Background(), clusters, ResourceQuery{Resource: "pods", Name: "nginx-*", Namespace: "default", AsTable: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := results.Results[0]
+	if !result.Success {
+		t.Fatalf("expected success, got error: %v", result.Error)
+	}
+
+	if !strings.Contains(accept, "as=Table") {
+		t.Errorf("expected table Accept header, got %q", accept)
+	}
+
+	if len(result.Tables) != 1 {
+		t.Fatalf("expected 1 table, got %d", len(result.Tables))
+	}
+
+	table := result.Tables[0]
+	if table.GroupVersionKind.Kind != "Pod" {
+		t.Errorf("expected Pod table, got %s", table.GroupVersionKind.Kind)
+	}
+
+	// The wildcard filters the table rows
+	if len(table.Table.Rows) != 1 {
+		t.Errorf("expected 1 row after wildcard filtering, got %d", len(table.Table.Rows))
+	}
+
+	if len(result.Items) != 1 || result.Items[0].GetName() != "nginx-1" || result.Items[0].GetKind() != "Pod" {
+		t.Errorf("expected nginx-1 pod metadata, got %v", result.Items)
+	}
+}
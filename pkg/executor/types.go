@@ -1,8 +1,14 @@
 package executor
 
 import (
+	"context"
+	"math"
+	"time"
+
 	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 // ClusterResult represents the result from a single cluster
@@ -10,10 +16,71 @@ type ClusterResult struct {
 	ClusterName string
 	Success     bool
 	Items       []unstructured.Unstructured
-	Output      string // Raw text output (for describe, logs, etc.)
-	Error       error
+	Output      string          // Raw text output (for describe, logs, etc.)
+	Tables      []ResourceTable // Server-side tables (for get with AsTable)
+
+	// Error explains a failure. It may also be set on a successful result when
+	// only part of the operation failed, e.g. one of several resource types.
+	Error error
+}
+
+// ResourceQuery selects the resources an operation targets on each cluster
+type ResourceQuery struct {
+	// Resource is the resource type as typed by the user (e.g. pods, deploy, widgets.example.com)
+	Resource string
+
+	// Name is an exact resource name or a glob pattern; empty selects all resources
+	Name string
+
+	// Namespace to query; empty queries all namespaces
+	Namespace string
+
+	// Namespaces are several namespaces to query instead of Namespace. Entries
+	// may be glob patterns (e.g. team-*), matched against each cluster's namespaces.
+	Namespaces []string
+
+	// PerClusterNamespace queries each cluster's default namespace, that of its
+	// mapped context or the mapping's defaultNamespace, instead of Namespace
+	PerClusterNamespace bool
+
+	// LabelSelector filters resources by label, using kubectl's -l syntax
+	LabelSelector string
+
+	// AsTable asks each API server to render the resources as a Table. Items then
+	// only carry object metadata. Clusters that cannot serve tables return full items.
+	AsTable bool
+
+	// TableObjects asks for full objects in the rows of server-side tables, so
+	// items can be sorted by any field rather than only by metadata
+	TableObjects bool
 }
 
+// ResourceTable is a server-side rendered table for one resource type
+type ResourceTable struct {
+	// GroupVersionKind identifies the resource type the table lists
+	GroupVersionKind schema.GroupVersionKind
+
+	// Table holds the column definitions and rows returned by the API server
+	Table *metav1.Table
+}
+
+// ClusterTarget identifies the cluster a ClusterFunc operates on
+type ClusterTarget struct {
+	// Cluster is the discovered cluster information
+	Cluster discovery.ClusterInfo
+}
+
+// IdentityVerifier checks that the clients of a cluster reach that cluster
+// and not another one, e.g. because of a wrong context mapping
+type IdentityVerifier interface {
+	// VerifyIdentity returns an error when the cluster cannot be trusted
+	VerifyIdentity(ctx context.Context, cluster discovery.ClusterInfo) error
+}
+
+// ClusterFunc performs an operation against a single cluster.
+// The context carries the per-cluster timeout and must be honored.
+type ClusterFunc func(ctx context.Context, target ClusterTarget) ClusterResult
+
 // AggregatedResults contains results from all clusters
 type AggregatedResults struct {
 	Results []ClusterResult
@@ -44,6 +111,16 @@ func DefaultConfig() ExecutorConfig {
 	}
 }
 
+// NewConfig returns the configuration for the given settings. The executor
+// counts whole seconds, so a partial second of timeout is rounded up.
+func NewConfig(maxConcurrency int, timeout time.Duration, continueOnError bool) ExecutorConfig {
+	return ExecutorConfig{
+		MaxConcurrency:  maxConcurrency,
+		TimeoutSeconds:  int(math.Ceil(timeout.Seconds())),
+		ContinueOnError: continueOnError,
+	}
+}
+
 // NewAggregatedResults creates an initialized AggregatedResults
 func NewAggregatedResults(clusters []discovery.ClusterInfo) *AggregatedResults {
 	return &AggregatedResults{
@@ -0,0 +1,392 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// Delays between attempts to re-establish a dropped watch. Variables so tests can shorten them.
+var (
+	watchRetryInitial = time.Second
+	watchRetryMax     = 30 * time.Second
+)
+
+// WatchStatus reports a change in the state of a cluster's watch
+type WatchStatus string
+
+const (
+	// WatchStatusReconnecting means the watch dropped and is being re-established
+	WatchStatusReconnecting WatchStatus = "Reconnecting"
+
+	// WatchStatusConnected means a dropped watch was re-established
+	WatchStatusConnected WatchStatus = "Connected"
+
+	// WatchStatusFailed means the cluster cannot be watched and was given up on
+	WatchStatusFailed WatchStatus = "Failed"
+)
+
+// WatchOptions configures a multi-cluster watch
+type WatchOptions struct {
+	// WatchOnly skips the initial listing and only reports changes, like kubectl get --watch-only
+	WatchOnly bool
+}
+
+// WatchEvent is a single change observed on one cluster. Object events carry
+// Type and Object; status events carry Status and, for failures, Error.
+type WatchEvent struct {
+	Cluster string
+	Type    watch.EventType
+	Object  *unstructured.Unstructured
+
+	Status WatchStatus
+	Error  error
+}
+
+// IsStatus reports whether the event describes the watch itself rather than an object
+func (ev WatchEvent) IsStatus() bool {
+	return ev.Status != ""
+}
+
+// Watch watches the resources selected by query on every cluster and merges the
+// events into a single channel. Unless opts.WatchOnly is set, the current objects
+// are sent first as Added events. Dropped watches are resumed from the last seen
+// resourceVersion (kept current through bookmarks); when that version has expired
+// the resources are listed again and the differences are sent as events. The
+// channel is closed once ctx is cancelled or every cluster has failed.
+func (e *Executor) Watch(ctx context.Context, clusters []discovery.ClusterInfo, query ResourceQuery, opts WatchOptions) <-chan WatchEvent {
+	events := make(chan WatchEvent)
+
+	var wg sync.WaitGroup
+	for _, cluster := range clusters {
+		wg.Add(1)
+		go func(c discovery.ClusterInfo) {
+			defer wg.Done()
+			e.watchCluster(ctx, c, query, opts, events)
+		}(cluster)
+	}
+
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	return events
+}
+
+// clusterWatch holds the state of the watch on a single cluster
+type clusterWatch struct {
+	cluster  string
+	resource dynamic.ResourceInterface
+	query    ResourceQuery
+	events   chan<- WatchEvent
+
+	// resourceVersion is where the next watch resumes
+	resourceVersion string
+
+	// known maps namespace/name to the resourceVersion last reported, so a relist
+	// after an expired watch only reports what actually changed
+	known map[string]string
+}
+
+// watchCluster runs the watch loop for a single cluster until ctx is cancelled
+func (e *Executor) watchCluster(ctx context.Context, cluster discovery.ClusterInfo, query ResourceQuery, opts WatchOptions, events chan<- WatchEvent) {
+	fail := func(err error) {
+		send(ctx, events, WatchEvent{Cluster: cluster.Name, Status: WatchStatusFailed, Error: err})
+	}
+
+	if err := e.VerifyCluster(ctx, cluster); err != nil {
+		fail(err)
+		return
+	}
+
+	dynamicClient, err := e.clients.DynamicClient(cluster)
+	if err != nil {
+		fail(fmt.Errorf("failed to create dynamic client: %w", err))
+		return
+	}
+
+	discoveryClient, err := e.clients.DiscoveryClient(cluster)
+	if err != nil {
+		fail(fmt.Errorf("failed to create discovery client: %w", err))
+		return
+	}
+
+	mapping, err := e.resolveMapping(discoveryClient, query.Resource)
+	if err != nil {
+		fail(fmt.Errorf("failed to resolve resource type: %w", err))
+		return
+	}
+
+	// A watch follows one namespace, or all of them
+	namespaces, err := e.queryNamespaces(ctx, cluster, query)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if len(namespaces) != 1 {
+		fail(fmt.Errorf("watch needs a single namespace, %d selected", len(namespaces)))
+		return
+	}
+	query.Namespace = namespaces[0]
+
+	w := &clusterWatch{
+		cluster:  cluster.Name,
+		resource: namespacedResource(dynamicClient, mapping, query.Namespace),
+		query:    query,
+		events:   events,
+		known:    make(map[string]string),
+	}
+
+	delay := watchRetryInitial
+	retry := func(err error) bool {
+		if isPermanentWatchError(err) {
+			fail(err)
+			return false
+		}
+		if !send(ctx, events, WatchEvent{Cluster: cluster.Name, Status: WatchStatusReconnecting, Error: err}) {
+			return false
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, watchRetryMax)
+		return true
+	}
+
+	// The initial list establishes the resourceVersion to watch from
+	for {
+		err := w.list(ctx, !opts.WatchOnly)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil || !retry(err) {
+			return
+		}
+	}
+
+	reconnecting := false
+	for {
+		watcher, err := w.resource.Watch(ctx, w.listOptions(w.resourceVersion))
+		if err != nil {
+			if ctx.Err() != nil || !retry(fmt.Errorf("failed to watch resources: %w", err)) {
+				return
+			}
+			reconnecting = true
+			continue
+		}
+
+		if reconnecting {
+			if !send(ctx, events, WatchEvent{Cluster: cluster.Name, Status: WatchStatusConnected}) {
+				watcher.Stop()
+				return
+			}
+			reconnecting = false
+		}
+
+		err = w.consume(ctx, watcher)
+		watcher.Stop()
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The watch made progress, so start over with a short delay
+		delay = watchRetryInitial
+
+		if err == nil {
+			// The server ends every watch after its timeout; resume where it stopped
+			if w.resourceVersion != "" {
+				continue
+			}
+			err = fmt.Errorf("watch closed by server")
+		}
+
+		if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+			// The resourceVersion is too old to resume from; relist and report the difference
+			if !send(ctx, events, WatchEvent{Cluster: cluster.Name, Status: WatchStatusReconnecting, Error: err}) {
+				return
+			}
+			reconnecting = true
+			if err = w.list(ctx, true); err == nil {
+				continue
+			}
+		}
+
+		if ctx.Err() != nil || !retry(err) {
+			return
+		}
+		reconnecting = true
+	}
+}
+
+// isPermanentWatchError reports whether err cannot be cured by retrying, e.g.
+// missing permissions or a resource type the cluster does not serve. Expired
+// resource versions and transient failures are retried.
+func isPermanentWatchError(err error) bool {
+	return apierrors.IsForbidden(err) ||
+		apierrors.IsNotFound(err) ||
+		apierrors.IsUnauthorized(err) ||
+		apierrors.IsBadRequest(err) ||
+		apierrors.IsMethodNotSupported(err) ||
+		meta.IsNoMatchError(err)
+}
+
+// listOptions returns the list and watch options for the query
+func (w *clusterWatch) listOptions(resourceVersion string) metav1.ListOptions {
+	opts := metav1.ListOptions{
+		LabelSelector:       w.query.LabelSelector,
+		ResourceVersion:     resourceVersion,
+		AllowWatchBookmarks: true,
+	}
+	if w.query.Name != "" && !isWildcard(w.query.Name) {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", w.query.Name).String()
+	}
+	return opts
+}
+
+// list lists the current objects and records the resourceVersion to watch from.
+// With report set, objects that are new or changed since they were last reported
+// are sent as events, and objects that disappeared are sent as deletions.
+func (w *clusterWatch) list(ctx context.Context, report bool) error {
+	opts := w.listOptions("")
+	opts.AllowWatchBookmarks = false
+
+	list, err := w.resource.List(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to list resources: %w", err)
+	}
+	w.resourceVersion = list.GetResourceVersion()
+
+	seen := make(map[string]bool, len(list.Items))
+	for i := range list.Items {
+		item := &list.Items[i]
+		if !w.matches(item) {
+			continue
+		}
+
+		key := objectKey(item)
+		seen[key] = true
+
+		previous, known := w.known[key]
+		w.known[key] = item.GetResourceVersion()
+		if !report || (known && previous == item.GetResourceVersion()) {
+			continue
+		}
+
+		eventType := watch.Added
+		if known {
+			eventType = watch.Modified
+		}
+		if !send(ctx, w.events, WatchEvent{Cluster: w.cluster, Type: eventType, Object: item}) {
+			return ctx.Err()
+		}
+	}
+
+	for key := range w.known {
+		if seen[key] {
+			continue
+		}
+		delete(w.known, key)
+		if !report {
+			continue
+		}
+
+		namespace, name := splitObjectKey(key)
+		deleted := &unstructured.Unstructured{Object: map[string]interface{}{}}
+		deleted.SetNamespace(namespace)
+		deleted.SetName(name)
+		if !send(ctx, w.events, WatchEvent{Cluster: w.cluster, Type: watch.Deleted, Object: deleted}) {
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// consume forwards events from watcher until it closes or fails. It returns the
+// error reported by the server, if any.
+func (w *clusterWatch) consume(ctx context.Context, watcher watch.Interface) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return nil
+			}
+
+			if event.Type == watch.Error {
+				return apierrors.FromObject(event.Object)
+			}
+
+			obj, ok := event.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+
+			if rv := obj.GetResourceVersion(); rv != "" {
+				w.resourceVersion = rv
+			}
+
+			// Bookmarks only advance the resourceVersion
+			if event.Type == watch.Bookmark || !w.matches(obj) {
+				continue
+			}
+
+			key := objectKey(obj)
+			if event.Type == watch.Deleted {
+				delete(w.known, key)
+			} else {
+				w.known[key] = obj.GetResourceVersion()
+			}
+
+			if !send(ctx, w.events, WatchEvent{Cluster: w.cluster, Type: event.Type, Object: obj}) {
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+// matches applies the wildcard name filter, which the API server cannot evaluate
+func (w *clusterWatch) matches(obj *unstructured.Unstructured) bool {
+	if !isWildcard(w.query.Name) {
+		return true
+	}
+	matched, err := filepath.Match(w.query.Name, obj.GetName())
+	return err == nil && matched
+}
+
+// send delivers an event unless ctx is cancelled first
+func send(ctx context.Context, events chan<- WatchEvent, event WatchEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// objectKey identifies an object within a cluster
+func objectKey(obj metav1.Object) string {
+	return obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// splitObjectKey is the inverse of objectKey
+func splitObjectKey(key string) (namespace, name string) {
+	namespace, name, _ = strings.Cut(key, "/")
+	return namespace, name
+}
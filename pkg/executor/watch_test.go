@@ -0,0 +1,336 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var podsGVR = schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+
+// newFakeWatchClient creates a fake dynamic client for pods and registers it for cluster
+func newFakeWatchClient(provider *client.FakeProvider, cluster string, objs ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{podsGVR: "PodList"},
+		objs...,
+	)
+	provider.SetClients(cluster, client.FakeClusterClients{
+		Dynamic:   dynamicClient,
+		Discovery: &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}},
+	})
+	return dynamicClient
+}
+
+// nextEvent waits for the next event on the channel
+func nextEvent(t *testing.T, events <-chan WatchEvent) WatchEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		if !ok {
+			t.Fatal("event channel closed unexpectedly")
+		}
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for watch event")
+	}
+	return WatchEvent{}
+}
+
+// shortenWatchRetry makes reconnect attempts immediate for the duration of a test
+func shortenWatchRetry(t *testing.T) {
+	initial, maxDelay := watchRetryInitial, watchRetryMax
+	watchRetryInitial, watchRetryMax = time.Millisecond, time.Millisecond
+	t.Cleanup(func() {
+		watchRetryInitial, watchRetryMax = initial, maxDelay
+	})
+}
+
+func TestExecutorWatch_InitialListAndEvents(t *testing.T) {
+	provider := client.NewFakeProvider()
+	dynamicClient := newFakeWatchClient(provider, "cluster1", newFakePod("default", "nginx-1"))
+
+	// Report the watch as established before creating objects, so no event is missed
+	watching := make(chan struct{})
+	dynamicClient.PrependWatchReactor("pods", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		defer close(watching)
+		return false, nil, nil
+	})
+
+	exec := NewExecutor(provider)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	clusters := []discovery.ClusterInfo{{Name: "cluster1"}}
+	events := exec.Watch(ctx, clusters, ResourceQuery{Resource: "pods", Namespace: "default"}, WatchOptions{})
+
+	event := nextEvent(t, events)
+	if event.Cluster != "cluster1" || event.Type != watch.Added || event.Object.GetName() != "nginx-1" {
+		t.Errorf("expected initial Added event for nginx-1, got %+v", event)
+	}
+
+	<-watching
+	if _, err := dynamicClient.Resource(podsGVR).Namespace("default").Create(ctx, newFakePod("default", "nginx-2"), metav1.CreateOptions{}); err != nil {
+		t.Fatalf("failed to create pod: %v", err)
+	}
+
+	event = nextEvent(t, events)
+	if event.Type != watch.Added || event.Object.GetName() != "nginx-2" {
+		t.Errorf("expected Added event for nginx-2, got %+v", event)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestExecutorWatch_WatchOnly(t *testing.T) {
+	provider := client.NewFakeProvider()
+	dynamicClient := newFakeWatchClient(provider, "cluster1", newFakePod("default", "nginx-1"))
+
+	fakeWatcher := watch.NewFake()
+	dynamicClient.PrependWatchReactor("pods", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		return true, fakeWatcher, nil
+	})
+
+	exec := NewExecutor(provider)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := exec.Watch(ctx, []discovery.ClusterInfo{{Name: "cluster1"}}, ResourceQuery{Resource: "pods"}, WatchOptions{WatchOnly: true})
+
+	go fakeWatcher.Modify(newFakePod("default", "nginx-1"))
+
+	// The existing pod is not listed; only the change is reported
+	event := nextEvent(t, events)
+	if event.Type != watch.Modified || event.Object.GetName() != "nginx-1" {
+		t.Errorf("expected Modified event for nginx-1, got %+v", event)
+	}
+}
+
+func TestExecutorWatch_WildcardAndBookmarks(t *testing.T) {
+	provider := client.NewFakeProvider()
+	dynamicClient := newFakeWatchClient(provider, "cluster1")
+
+	fakeWatcher := watch.NewFake()
+	dynamicClient.PrependWatchReactor("pods", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		return true, fakeWatcher, nil
+	})
+
+	exec := NewExecutor(provider)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := exec.Watch(ctx, []discovery.ClusterInfo{{Name: "cluster1"}}, ResourceQuery{Resource: "pods", Name: "nginx-*"}, WatchOptions{})
+
+	go func() {
+		bookmark := newFakePod("", "")
+		bookmark.SetResourceVersion("42")
+		fakeWatcher.Action(watch.Bookmark, bookmark)
+		fakeWatcher.Add(newFakePod("default", "redis-1"))
+		fakeWatcher.Add(newFakePod("default", "nginx-1"))
+	}()
+
+	// Bookmarks and non-matching names are not reported
+	event := nextEvent(t, events)
+	if event.Type != watch.Added || event.Object.GetName() != "nginx-1" {
+		t.Errorf("expected Added event for nginx-1, got %+v", event)
+	}
+}
+
+func TestExecutorWatch_Reconnect(t *testing.T) {
+	shortenWatchRetry(t)
+
+	provider := client.NewFakeProvider()
+	dynamicClient := newFakeWatchClient(provider, "cluster1")
+
+	var watches atomic.Int32
+	watchers := []*watch.FakeWatcher{watch.NewFake(), watch.NewFake()}
+	dynamicClient.PrependWatchReactor("pods", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		n := watches.Add(1)
+		if int(n) > len(watchers) {
+			return true, watch.NewFake(), nil
+		}
+		return true, watchers[n-1], nil
+	})
+
+	exec := NewExecutor(provider)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := exec.Watch(ctx, []discovery.ClusterInfo{{Name: "cluster1"}}, ResourceQuery{Resource: "pods"}, WatchOptions{})
+
+	// The first watch fails
+	go watchers[0].Error(&apierrors.NewInternalError(fmt.Errorf("connection reset")).ErrStatus)
+
+	event := nextEvent(t, events)
+	if event.Status != WatchStatusReconnecting || event.Error == nil {
+		t.Errorf("expected Reconnecting status with error, got %+v", event)
+	}
+
+	event = nextEvent(t, events)
+	if event.Status != WatchStatusConnected {
+		t.Errorf("expected Connected status, got %+v", event)
+	}
+
+	go watchers[1].Add(newFakePod("default", "nginx-1"))
+	event = nextEvent(t, events)
+	if event.Type != watch.Added || event.Object.GetName() != "nginx-1" {
+		t.Errorf("expected Added event after reconnect, got %+v", event)
+	}
+}
+
+func TestExecutorWatch_ServerTimeout(t *testing.T) {
+	shortenWatchRetry(t)
+
+	provider := client.NewFakeProvider()
+	dynamicClient := newFakeWatchClient(provider, "cluster1")
+
+	var watches atomic.Int32
+	var resumedFrom atomic.Value
+	watchers := []*watch.FakeWatcher{watch.NewFake(), watch.NewFake()}
+	dynamicClient.PrependWatchReactor("pods", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		n := watches.Add(1)
+		if n == 2 {
+			resumedFrom.Store(action.(clienttesting.WatchActionImpl).GetWatchRestrictions().ResourceVersion)
+		}
+		if int(n) > len(watchers) {
+			return true, watch.NewFake(), nil
+		}
+		return true, watchers[n-1], nil
+	})
+
+	exec := NewExecutor(provider)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := exec.Watch(ctx, []discovery.ClusterInfo{{Name: "cluster1"}}, ResourceQuery{Resource: "pods"}, WatchOptions{})
+
+	pod := newFakePod("default", "nginx-1")
+	pod.SetResourceVersion("5")
+	go func() {
+		watchers[0].Add(pod)
+		// The watch times out on the server
+		watchers[0].Stop()
+	}()
+	if event := nextEvent(t, events); event.Type != watch.Added {
+		t.Fatalf("expected Added event, got %+v", event)
+	}
+
+	// The watch is resumed without a status event
+	go watchers[1].Add(newFakePod("default", "nginx-2"))
+	event := nextEvent(t, events)
+	if event.Status != "" || event.Type != watch.Added || event.Object.GetName() != "nginx-2" {
+		t.Errorf("expected Added event for nginx-2 without a status change, got %+v", event)
+	}
+	if rv, _ := resumedFrom.Load().(string); rv != "5" {
+		t.Errorf("expected the watch to resume from resourceVersion 5, got %q", rv)
+	}
+}
+
+func TestExecutorWatch_ExpiredRelist(t *testing.T) {
+	shortenWatchRetry(t)
+
+	provider := client.NewFakeProvider()
+	dynamicClient := newFakeWatchClient(provider, "cluster1",
+		newFakePod("default", "nginx-1"),
+		newFakePod("default", "nginx-2"),
+	)
+
+	var watches atomic.Int32
+	first := watch.NewFake()
+	dynamicClient.PrependWatchReactor("pods", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		if watches.Add(1) == 1 {
+			return true, first, nil
+		}
+		return true, watch.NewFake(), nil
+	})
+
+	exec := NewExecutor(provider)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := exec.Watch(ctx, []discovery.ClusterInfo{{Name: "cluster1"}}, ResourceQuery{Resource: "pods", Namespace: "default"}, WatchOptions{})
+
+	for i := 0; i < 2; i++ {
+		if event := nextEvent(t, events); event.Type != watch.Added {
+			t.Fatalf("expected initial Added event, got %+v", event)
+		}
+	}
+
+	// nginx-2 disappears while the watch is down and the resourceVersion expires
+	if err := dynamicClient.Resource(podsGVR).Namespace("default").Delete(ctx, "nginx-2", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("failed to delete pod: %v", err)
+	}
+	expired := apierrors.NewResourceExpired("too old resource version")
+	go first.Error(&expired.ErrStatus)
+
+	event := nextEvent(t, events)
+	if event.Status != WatchStatusReconnecting || !apierrors.IsResourceExpired(event.Error) {
+		t.Errorf("expected Reconnecting status for expired watch, got %+v", event)
+	}
+
+	event = nextEvent(t, events)
+	if event.Type != watch.Deleted || event.Object.GetName() != "nginx-2" {
+		t.Errorf("expected Deleted event for nginx-2 from relist, got %+v", event)
+	}
+
+	event = nextEvent(t, events)
+	if event.Status != WatchStatusConnected {
+		t.Errorf("expected Connected status, got %+v", event)
+	}
+}
+
+func TestExecutorWatch_FailedCluster(t *testing.T) {
+	exec := NewExecutor(client.NewFakeProvider())
+
+	events := exec.Watch(context.Background(), []discovery.ClusterInfo{{Name: "unknown"}}, ResourceQuery{Resource: "pods"}, WatchOptions{})
+
+	event := nextEvent(t, events)
+	if event.Status != WatchStatusFailed || event.Error == nil {
+		t.Errorf("expected Failed status, got %+v", event)
+	}
+
+	// With every cluster failed, the stream ends
+	if _, ok := <-events; ok {
+		t.Error("expected event channel to be closed")
+	}
+}
+
+func TestExecutorWatch_PermanentError(t *testing.T) {
+	shortenWatchRetry(t)
+
+	provider := client.NewFakeProvider()
+	dynamicClient := newFakeWatchClient(provider, "cluster1")
+	dynamicClient.PrependReactor("list", "pods", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(podsGVR.GroupResource(), "", nil)
+	})
+
+	exec := NewExecutor(provider)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := exec.Watch(ctx, []discovery.ClusterInfo{{Name: "cluster1"}}, ResourceQuery{Resource: "pods", Namespace: "default"}, WatchOptions{})
+
+	event := nextEvent(t, events)
+	if event.Status != WatchStatusFailed || !apierrors.IsForbidden(event.Error) {
+		t.Errorf("expected Failed status for a forbidden list, got %+v", event)
+	}
+
+	if _, ok := <-events; ok {
+		t.Error("expected event channel to be closed")
+	}
+}
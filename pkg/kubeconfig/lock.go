@@ -0,0 +1,40 @@
+package kubeconfig
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// lockRetryInterval is how often a held lock is tried again
+const lockRetryInterval = 50 * time.Millisecond
+
+// lockFile takes an exclusive advisory lock on path, creating the file if
+// needed and waiting up to timeout for other holders. The returned function
+// releases the lock.
+func lockFile(path string, timeout time.Duration) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		locked, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+		}
+		if locked {
+			return func() {
+				unlock(f)
+				f.Close()
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, fmt.Errorf("timed out after %s waiting for %s, held by another kubectl mc process", timeout, path)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
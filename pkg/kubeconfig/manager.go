@@ -2,16 +2,34 @@ package kubeconfig
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Manager handles cluster-to-context mappings
+const (
+	// lockSuffix names the advisory lock file held while the mapping file is updated
+	lockSuffix = ".lock"
+
+	// backupSuffix names the copy of the mapping file before the last update
+	backupSuffix = ".bak"
+
+	// lockTimeout is how long an update waits for another process to release the lock
+	lockTimeout = 10 * time.Second
+)
+
+// Manager handles cluster-to-context mappings. Updates are safe across
+// processes: each one locks the file, re-reads it, applies the change and
+// replaces the file atomically, keeping a backup of the previous version.
 type Manager struct {
 	configPath string
-	config     *MappingConfig
+
+	mu     sync.RWMutex
+	config *MappingConfig
 }
 
 // NewManager creates a new kubeconfig mapping manager
@@ -32,11 +50,7 @@ func NewManager(configPath string) (*Manager, error) {
 	if err := m.load(); err != nil {
 		// If file doesn't exist, initialize with empty config
 		if os.IsNotExist(err) {
-			m.config = &MappingConfig{
-				APIVersion: "kubectl-mc.k8s.io/v1alpha1",
-				Kind:       "ClusterMapping",
-				Clusters:   []ClusterMapping{},
-			}
+			m.config = m.emptyConfig()
 		} else {
 			return nil, err
 		}
@@ -47,82 +61,347 @@ func NewManager(configPath string) (*Manager, error) {
 
 // GetContext returns the kubeconfig context for a cluster name
 func (m *Manager) GetContext(clusterName string) (string, error) {
-	for _, mapping := range m.config.Clusters {
-		if mapping.Name == clusterName {
-			return mapping.Context, nil
-		}
+	if mapping, ok := m.GetMapping(clusterName); ok {
+		return mapping.Context, nil
 	}
 	return "", fmt.Errorf("no context mapping found for cluster %s", clusterName)
 }
 
 // SetMapping adds or updates a cluster-to-context mapping
 func (m *Manager) SetMapping(clusterName, context, namespace string) error {
-	// Check if mapping already exists
-	for i, mapping := range m.config.Clusters {
-		if mapping.Name == clusterName {
-			m.config.Clusters[i].Context = context
-			m.config.Clusters[i].Namespace = namespace
-			return m.save()
+	return m.update(func(config *MappingConfig) error {
+		// Check if mapping already exists
+		if i := findMapping(config, clusterName); i >= 0 {
+			// A recorded identity belongs to the old context
+			if config.Clusters[i].Context != context {
+				config.Clusters[i].KubeSystemUID = ""
+			}
+			config.Clusters[i].Context = context
+			config.Clusters[i].Namespace = namespace
+			return nil
 		}
+
+		// Add new mapping
+		config.Clusters = append(config.Clusters, ClusterMapping{
+			Name:      clusterName,
+			Context:   context,
+			Namespace: namespace,
+		})
+		return nil
+	})
+}
+
+// GetMapping returns the mapping of a cluster
+func (m *Manager) GetMapping(clusterName string) (ClusterMapping, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if i := findMapping(m.config, clusterName); i >= 0 {
+		return m.config.Clusters[i], true
 	}
+	return ClusterMapping{}, false
+}
 
-	// Add new mapping
-	m.config.Clusters = append(m.config.Clusters, ClusterMapping{
-		Name:      clusterName,
-		Context:   context,
-		Namespace: namespace,
+// SetKubeSystemUID records the kube-system namespace UID of a mapped cluster
+func (m *Manager) SetKubeSystemUID(clusterName, uid string) error {
+	return m.update(func(config *MappingConfig) error {
+		i := findMapping(config, clusterName)
+		if i < 0 {
+			return fmt.Errorf("no context mapping found for cluster %s", clusterName)
+		}
+		config.Clusters[i].KubeSystemUID = uid
+		return nil
 	})
+}
+
+// RemoveMappings deletes the mappings of the named clusters and returns them.
+// Nothing is removed when one of the clusters is not mapped.
+func (m *Manager) RemoveMappings(clusterNames ...string) ([]ClusterMapping, error) {
+	var removed []ClusterMapping
+	err := m.update(func(config *MappingConfig) error {
+		remove := make(map[string]bool, len(clusterNames))
+		for _, name := range clusterNames {
+			if findMapping(config, name) < 0 {
+				return fmt.Errorf("no context mapping found for cluster %s", name)
+			}
+			remove[name] = true
+		}
 
-	return m.save()
+		kept := make([]ClusterMapping, 0, len(config.Clusters))
+		for _, mapping := range config.Clusters {
+			if remove[mapping.Name] {
+				removed = append(removed, mapping)
+				continue
+			}
+			kept = append(kept, mapping)
+		}
+		config.Clusters = kept
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
 }
 
-// ListMappings returns all cluster mappings
+// ImportMappings adds or replaces the given mappings. With replace, mappings
+// of clusters not in the list are removed.
+func (m *Manager) ImportMappings(mappings []ClusterMapping, replace bool) error {
+	if err := ValidateMappings(mappings); err != nil {
+		return err
+	}
+
+	return m.update(func(config *MappingConfig) error {
+		if replace {
+			config.Clusters = []ClusterMapping{}
+		}
+		for _, mapping := range mappings {
+			if i := findMapping(config, mapping.Name); i >= 0 {
+				config.Clusters[i] = mapping
+			} else {
+				config.Clusters = append(config.Clusters, mapping)
+			}
+		}
+		return nil
+	})
+}
+
+// Export returns a copy of the mapping file contents
+func (m *Manager) Export() MappingConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	config := *m.config
+	config.Clusters = append([]ClusterMapping{}, m.config.Clusters...)
+	return config
+}
+
+// Path returns the location of the mapping file
+func (m *Manager) Path() string {
+	return m.configPath
+}
+
+// ParseMappings reads mappings in the mapping file format, as written by
+// `kubectl mc mapping list -o yaml`
+func ParseMappings(data []byte) ([]ClusterMapping, error) {
+	var config MappingConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse mappings: %w", err)
+	}
+	if config.Kind != "" && config.Kind != "ClusterMapping" {
+		return nil, fmt.Errorf("unsupported kind %q, expected ClusterMapping", config.Kind)
+	}
+	if err := ValidateMappings(config.Clusters); err != nil {
+		return nil, err
+	}
+	return config.Clusters, nil
+}
+
+// ValidateMappings checks that every mapping names a cluster and a context,
+// once, and that its overrides are usable
+func ValidateMappings(mappings []ClusterMapping) error {
+	seen := make(map[string]bool, len(mappings))
+	for i, mapping := range mappings {
+		if mapping.Name == "" {
+			return fmt.Errorf("mapping %d has no cluster name", i+1)
+		}
+		if mapping.Context == "" {
+			return fmt.Errorf("mapping for cluster %s has no context", mapping.Name)
+		}
+		if seen[mapping.Name] {
+			return fmt.Errorf("cluster %s is mapped more than once", mapping.Name)
+		}
+		if len(mapping.AsGroups) > 0 && mapping.As == "" {
+			return fmt.Errorf("mapping for cluster %s sets asGroups without as", mapping.Name)
+		}
+		if mapping.ProxyURL != "" {
+			if err := validateProxyURL(mapping.ProxyURL); err != nil {
+				return fmt.Errorf("mapping for cluster %s: %w", mapping.Name, err)
+			}
+		}
+		seen[mapping.Name] = true
+	}
+	return nil
+}
+
+// validateProxyURL checks that a proxy URL uses a scheme client-go can dial
+func validateProxyURL(proxyURL string) error {
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxyURL %q: %w", proxyURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("invalid proxyURL %q: scheme must be http, https or socks5", proxyURL)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid proxyURL %q: no host", proxyURL)
+	}
+	return nil
+}
+
+// ListMappings returns a copy of all cluster mappings
 func (m *Manager) ListMappings() []ClusterMapping {
-	return m.config.Clusters
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]ClusterMapping{}, m.config.Clusters...)
+}
+
+// GetHubContext returns the configured hub context if set
+func (m *Manager) GetHubContext() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.config.HubContext
+}
+
+// SetHubContext sets the default hub context
+func (m *Manager) SetHubContext(context string) error {
+	return m.update(func(config *MappingConfig) error {
+		config.HubContext = context
+		return nil
+	})
+}
+
+// findMapping returns the index of the cluster's mapping, or -1
+func findMapping(config *MappingConfig, clusterName string) int {
+	for i, mapping := range config.Clusters {
+		if mapping.Name == clusterName {
+			return i
+		}
+	}
+	return -1
 }
 
 // load reads the mapping config from disk
 func (m *Manager) load() error {
-	data, err := os.ReadFile(m.configPath)
+	config, err := readMappingConfig(m.configPath)
 	if err != nil {
 		return err
 	}
+	m.config = config
+	return nil
+}
 
-	m.config = &MappingConfig{}
-	if err := yaml.Unmarshal(data, m.config); err != nil {
-		return fmt.Errorf("failed to parse mapping config: %w", err)
+// readMappingConfig parses the mapping file at path
+func readMappingConfig(path string) (*MappingConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	config := &MappingConfig{}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse mapping config: %w", err)
+	}
+	if config.Clusters == nil {
+		config.Clusters = []ClusterMapping{}
+	}
+	return config, nil
 }
 
-// save writes the mapping config to disk
-func (m *Manager) save() error {
+// update applies change to the mapping file while holding its lock. The file
+// is read again first, so mappings written by other processes since this
+// manager loaded it are kept rather than overwritten. Nothing is written when
+// change fails.
+func (m *Manager) update(change func(config *MappingConfig) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// Create directory if it doesn't exist
-	dir := filepath.Dir(m.configPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	data, err := yaml.Marshal(m.config)
+	unlock, err := lockFile(m.configPath+lockSuffix, lockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	config, err := readMappingConfig(m.configPath)
+	switch {
+	case os.IsNotExist(err):
+		config = m.emptyConfig()
+	case err != nil:
+		return err
+	}
+
+	if err := change(config); err != nil {
+		return err
+	}
+	if err := m.write(config); err != nil {
+		return err
+	}
+
+	m.config = config
+	return nil
+}
+
+// write replaces the mapping file atomically: the new contents are written to
+// a temporary file that is renamed over the old one, whose previous version is
+// kept as a backup. Readers see either the old or the new file, never a
+// partial one.
+func (m *Manager) write(config *MappingConfig) error {
+	data, err := yaml.Marshal(config)
 	if err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(m.configPath); err == nil {
+		mode = info.Mode().Perm()
+		if err := copyFile(m.configPath, m.configPath+backupSuffix, mode); err != nil {
+			return fmt.Errorf("failed to back up config file: %w", err)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.configPath), filepath.Base(m.configPath)+".tmp-*")
+	if err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	defer os.Remove(tmp.Name())
 
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), m.configPath); err != nil {
+		return fmt.Errorf("failed to replace config file: %w", err)
+	}
 	return nil
 }
 
-// GetHubContext returns the configured hub context if set
-func (m *Manager) GetHubContext() string {
-	return m.config.HubContext
+// emptyConfig returns the contents of a new mapping file
+func (m *Manager) emptyConfig() *MappingConfig {
+	return &MappingConfig{
+		APIVersion: "kubectl-mc.k8s.io/v1alpha1",
+		Kind:       "ClusterMapping",
+		Clusters:   []ClusterMapping{},
+	}
 }
 
-// SetHubContext sets the default hub context
-func (m *Manager) SetHubContext(context string) error {
-	m.config.HubContext = context
-	return m.save()
+// copyFile copies src to dst, replacing dst atomically
+func copyFile(src, dst string, mode os.FileMode) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+
+	tmp := dst + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
 }
@@ -1,9 +1,14 @@
 package kubeconfig
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSetAndGetMapping(t *testing.T) {
@@ -109,6 +114,12 @@ func TestListMappings(t *testing.T) {
 	if len(mappings) != len(clusters) {
 		t.Errorf("expected %d mappings, got %d", len(clusters), len(mappings))
 	}
+
+	// Changing the returned slice leaves the manager's mappings alone
+	mappings[0].Context = "changed"
+	if context, err := manager.GetContext("cluster1"); err != nil || context != "context1" {
+		t.Errorf("expected context context1, got %q (%v)", context, err)
+	}
 }
 
 func TestConfigPersistence(t *testing.T) {
@@ -202,3 +213,272 @@ func TestLoadInvalidYAML(t *testing.T) {
 		t.Error("expected error loading invalid YAML, got nil")
 	}
 }
+
+func TestKubeSystemUID(t *testing.T) {
+	manager, err := NewManager(filepath.Join(t.TempDir(), "test-config.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := manager.SetKubeSystemUID("cluster1", "uid-1"); err == nil {
+		t.Error("expected error for unmapped cluster, got nil")
+	}
+
+	if err := manager.SetMapping("cluster1", "context1", "namespace1"); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+	if err := manager.SetKubeSystemUID("cluster1", "uid-1"); err != nil {
+		t.Fatalf("failed to set kube-system UID: %v", err)
+	}
+	if mapping, ok := manager.GetMapping("cluster1"); !ok || mapping.KubeSystemUID != "uid-1" {
+		t.Errorf("expected kube-system UID 'uid-1', got %+v", mapping)
+	}
+
+	// Keeping the context keeps the identity, changing it forgets the identity
+	if err := manager.SetMapping("cluster1", "context1", "namespace2"); err != nil {
+		t.Fatalf("failed to update mapping: %v", err)
+	}
+	if mapping, _ := manager.GetMapping("cluster1"); mapping.KubeSystemUID != "uid-1" {
+		t.Errorf("expected kube-system UID to be kept, got '%s'", mapping.KubeSystemUID)
+	}
+	if err := manager.SetMapping("cluster1", "context2", "namespace2"); err != nil {
+		t.Fatalf("failed to update mapping: %v", err)
+	}
+	if mapping, _ := manager.GetMapping("cluster1"); mapping.KubeSystemUID != "" {
+		t.Errorf("expected kube-system UID to be cleared, got '%s'", mapping.KubeSystemUID)
+	}
+}
+
+func TestRemoveMappings(t *testing.T) {
+	manager, err := NewManager(filepath.Join(t.TempDir(), "test-config.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	for _, name := range []string{"cluster1", "cluster2", "cluster3"} {
+		if err := manager.SetMapping(name, "ctx-"+name, ""); err != nil {
+			t.Fatalf("failed to set mapping: %v", err)
+		}
+	}
+
+	// An unknown cluster fails the whole removal
+	if _, err := manager.RemoveMappings("cluster1", "missing"); err == nil {
+		t.Error("expected error for unmapped cluster, got nil")
+	}
+	if len(manager.ListMappings()) != 3 {
+		t.Errorf("expected 3 mappings after failed removal, got %d", len(manager.ListMappings()))
+	}
+
+	removed, err := manager.RemoveMappings("cluster1", "cluster3")
+	if err != nil {
+		t.Fatalf("failed to remove mappings: %v", err)
+	}
+	if len(removed) != 2 || removed[0].Context != "ctx-cluster1" {
+		t.Errorf("expected the removed mappings to be returned, got %+v", removed)
+	}
+
+	reloaded, err := NewManager(manager.Path())
+	if err != nil {
+		t.Fatalf("failed to reload manager: %v", err)
+	}
+	if mappings := reloaded.ListMappings(); len(mappings) != 1 || mappings[0].Name != "cluster2" {
+		t.Errorf("expected only cluster2 to remain, got %+v", mappings)
+	}
+}
+
+func TestImportMappings(t *testing.T) {
+	manager, err := NewManager(filepath.Join(t.TempDir(), "test-config.yaml"))
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+	if err := manager.SetMapping("cluster1", "old-context", ""); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+	if err := manager.SetMapping("cluster2", "context2", ""); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+
+	mappings, err := ParseMappings([]byte(`apiVersion: kubectl-mc.k8s.io/v1alpha1
+kind: ClusterMapping
+clusters:
+- name: cluster1
+  context: new-context
+- name: cluster3
+  context: context3
+  kubeSystemUID: uid-3
+`))
+	if err != nil {
+		t.Fatalf("failed to parse mappings: %v", err)
+	}
+
+	if err := manager.ImportMappings(mappings, false); err != nil {
+		t.Fatalf("failed to import mappings: %v", err)
+	}
+	if context, _ := manager.GetContext("cluster1"); context != "new-context" {
+		t.Errorf("expected cluster1 to be updated, got %s", context)
+	}
+	if mapping, ok := manager.GetMapping("cluster3"); !ok || mapping.KubeSystemUID != "uid-3" {
+		t.Errorf("expected cluster3 to be imported with its identity, got %+v", mapping)
+	}
+	if len(manager.ListMappings()) != 3 {
+		t.Errorf("expected 3 mappings after merge, got %d", len(manager.ListMappings()))
+	}
+
+	if err := manager.ImportMappings(mappings, true); err != nil {
+		t.Fatalf("failed to import mappings: %v", err)
+	}
+	if _, ok := manager.GetMapping("cluster2"); ok {
+		t.Error("expected replace to remove cluster2")
+	}
+}
+
+func TestParseMappings_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"wrong kind":        "kind: Config\n",
+		"missing context":   "clusters:\n- name: cluster1\n",
+		"missing name":      "clusters:\n- context: context1\n",
+		"duplicate":         "clusters:\n- {name: c, context: a}\n- {name: c, context: b}\n",
+		"groups without as": "clusters:\n- {name: c, context: a, asGroups: [admins]}\n",
+		"proxy scheme":      "clusters:\n- {name: c, context: a, proxyURL: 'ftp://bastion:8080'}\n",
+		"proxy host":        "clusters:\n- {name: c, context: a, proxyURL: 'socks5://'}\n",
+	}
+	for name, input := range tests {
+		if _, err := ParseMappings([]byte(input)); err == nil {
+			t.Errorf("%s: expected error, got nil", name)
+		}
+	}
+}
+
+func TestParseMappings_Overrides(t *testing.T) {
+	mappings, err := ParseMappings([]byte(`
+clusters:
+- name: prod-eu-1
+  context: admin@prod-eu-1
+  kubeconfig: ~/.kube/eu.yaml
+  user: sso
+  defaultNamespace: payments
+  as: deployer
+  asGroups: [ops]
+  proxyURL: socks5://localhost:1080
+  tlsServerName: api.prod-eu-1.internal
+`))
+	if err != nil {
+		t.Fatalf("failed to parse mappings: %v", err)
+	}
+
+	expected := ClusterMapping{
+		Name:             "prod-eu-1",
+		Context:          "admin@prod-eu-1",
+		Kubeconfig:       "~/.kube/eu.yaml",
+		User:             "sso",
+		DefaultNamespace: "payments",
+		As:               "deployer",
+		AsGroups:         []string{"ops"},
+		ProxyURL:         "socks5://localhost:1080",
+		TLSServerName:    "api.prod-eu-1.internal",
+	}
+	if !reflect.DeepEqual(mappings[0], expected) {
+		t.Errorf("expected %+v, got %+v", expected, mappings[0])
+	}
+}
+
+func TestConcurrentManagers(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test-config.yaml")
+
+	// Managers created before any write stand in for separate processes: each
+	// update must keep the mappings the others wrote in the meantime
+	const writers = 8
+	managers := make([]*Manager, writers)
+	for i := range managers {
+		manager, err := NewManager(configPath)
+		if err != nil {
+			t.Fatalf("failed to create manager: %v", err)
+		}
+		managers[i] = manager
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i, manager := range managers {
+		wg.Add(1)
+		go func(i int, manager *Manager) {
+			defer wg.Done()
+			errs <- manager.SetMapping(fmt.Sprintf("cluster%d", i), fmt.Sprintf("context%d", i), "")
+		}(i, manager)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("failed to set mapping: %v", err)
+		}
+	}
+
+	reloaded, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("failed to reload manager: %v", err)
+	}
+	if got := len(reloaded.ListMappings()); got != writers {
+		t.Errorf("expected %d mappings, got %d", writers, got)
+	}
+}
+
+func TestUpdateKeepsBackup(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "test-config.yaml")
+	manager, err := NewManager(configPath)
+	if err != nil {
+		t.Fatalf("failed to create manager: %v", err)
+	}
+
+	if err := manager.SetMapping("cluster1", "context1", ""); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+	if err := manager.SetMapping("cluster1", "context2", ""); err != nil {
+		t.Fatalf("failed to set mapping: %v", err)
+	}
+
+	backup, err := NewManager(configPath + backupSuffix)
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if context, _ := backup.GetContext("cluster1"); context != "context1" {
+		t.Errorf("expected the backup to hold the previous version, got context '%s'", context)
+	}
+
+	// A failed change leaves the file alone
+	if _, err := manager.RemoveMappings("missing"); err == nil {
+		t.Error("expected error for unmapped cluster, got nil")
+	}
+	if context, _ := manager.GetContext("cluster1"); context != "context2" {
+		t.Errorf("expected context 'context2', got '%s'", context)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(configPath))
+	if err != nil {
+		t.Fatalf("failed to list directory: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), ".tmp") {
+			t.Errorf("expected no temporary files to be left, found %s", entry.Name())
+		}
+	}
+}
+
+func TestLockFileTimeout(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), "test.lock")
+
+	release, err := lockFile(lockPath, time.Second)
+	if err != nil {
+		t.Fatalf("failed to take lock: %v", err)
+	}
+
+	if _, err := lockFile(lockPath, 100*time.Millisecond); err == nil {
+		t.Error("expected a held lock to time out")
+	}
+
+	release()
+	release, err = lockFile(lockPath, time.Second)
+	if err != nil {
+		t.Fatalf("expected the released lock to be available, got %v", err)
+	}
+	release()
+}
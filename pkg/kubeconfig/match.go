@@ -0,0 +1,252 @@
+package kubeconfig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// Match strategies, in the order they are tried
+const (
+	MatchByServer        = "server"
+	MatchByCAFingerprint = "ca-fingerprint"
+	MatchByContextName   = "context-name"
+	MatchByClusterName   = "cluster-name"
+	MatchByTemplate      = "template"
+)
+
+// KubeContext is a kubeconfig context with the cluster details used for matching
+type KubeContext struct {
+	// Name is the context name
+	Name string
+
+	// Cluster is the name of the kubeconfig cluster the context points to
+	Cluster string
+
+	// Server is the API server URL of the cluster
+	Server string
+
+	// CAFingerprints are the SHA-256 fingerprints of the cluster's CA certificates
+	CAFingerprints []string
+}
+
+// ContextMatch is the result of matching a discovered cluster to kubeconfig contexts
+type ContextMatch struct {
+	// Cluster is the discovered cluster
+	Cluster discovery.ClusterInfo
+
+	// Context is the matched context, empty when there is no unique match
+	Context string
+
+	// MatchedBy names the strategies that selected the context
+	MatchedBy []string
+
+	// Candidates are the contexts that could not be told apart when the match is ambiguous
+	Candidates []string
+}
+
+// Matched reports whether exactly one context was found for the cluster
+func (m ContextMatch) Matched() bool {
+	return m.Context != ""
+}
+
+// Matcher matches discovered clusters to kubeconfig contexts by API server
+// URL, CA fingerprint, context or cluster name, and rename templates
+type Matcher struct {
+	templates []*template.Template
+}
+
+// NewMatcher creates a matcher. Templates render a context name from a
+// cluster, e.g. "kind-{{.Name}}" or "arn:aws:eks:*:*:cluster/{{.Name}}", and
+// may contain glob patterns. Fields: .Name, .DisplayName, .Namespace, .Labels.
+func NewMatcher(templates []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, text := range templates {
+		tmpl, err := template.New(text).Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid context template %q: %w", text, err)
+		}
+		m.templates = append(m.templates, tmpl)
+	}
+	return m, nil
+}
+
+// Match finds the context of each cluster. Strategies are tried in order;
+// when one finds several contexts, the later ones narrow them down.
+func (m *Matcher) Match(clusters []discovery.ClusterInfo, contexts []KubeContext) ([]ContextMatch, error) {
+	strategies := []struct {
+		name    string
+		matches func(discovery.ClusterInfo, KubeContext) (bool, error)
+	}{
+		{MatchByServer, matchServer},
+		{MatchByCAFingerprint, matchCAFingerprint},
+		{MatchByContextName, func(c discovery.ClusterInfo, k KubeContext) (bool, error) { return k.Name == c.Name, nil }},
+		{MatchByClusterName, func(c discovery.ClusterInfo, k KubeContext) (bool, error) { return k.Cluster == c.Name, nil }},
+		{MatchByTemplate, m.matchTemplate},
+	}
+
+	results := make([]ContextMatch, 0, len(clusters))
+	for _, cluster := range clusters {
+		result := ContextMatch{Cluster: cluster}
+
+		var candidates []string
+		for _, strategy := range strategies {
+			var matched []string
+			for _, kubeContext := range contexts {
+				ok, err := strategy.matches(cluster, kubeContext)
+				if err != nil {
+					return nil, err
+				}
+				if ok && (candidates == nil || contains(candidates, kubeContext.Name)) {
+					matched = append(matched, kubeContext.Name)
+				}
+			}
+			// Record only the strategies that found or narrowed the candidates
+			if len(matched) == 0 || (candidates != nil && len(matched) == len(candidates)) {
+				continue
+			}
+
+			candidates = matched
+			result.MatchedBy = append(result.MatchedBy, strategy.name)
+			if len(candidates) == 1 {
+				break
+			}
+		}
+
+		if len(candidates) == 1 {
+			result.Context = candidates[0]
+		} else {
+			sort.Strings(candidates)
+			result.Candidates = candidates
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// matchServer compares the cluster's access provider endpoints with the context's server
+func matchServer(cluster discovery.ClusterInfo, kubeContext KubeContext) (bool, error) {
+	if kubeContext.Server == "" {
+		return false, nil
+	}
+	server := normalizeServer(kubeContext.Server)
+	for _, provider := range cluster.AccessProviders {
+		if normalizeServer(provider.Server) == server {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// matchCAFingerprint compares the CA certificates published by the cluster with the context's
+func matchCAFingerprint(cluster discovery.ClusterInfo, kubeContext KubeContext) (bool, error) {
+	for _, provider := range cluster.AccessProviders {
+		for _, fingerprint := range CAFingerprints(provider.CertificateAuthorityData) {
+			if contains(kubeContext.CAFingerprints, fingerprint) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchTemplate checks the context name against the names rendered by the templates
+func (m *Matcher) matchTemplate(cluster discovery.ClusterInfo, kubeContext KubeContext) (bool, error) {
+	for _, tmpl := range m.templates {
+		var rendered bytes.Buffer
+		if err := tmpl.Execute(&rendered, cluster); err != nil {
+			return false, fmt.Errorf("failed to render context template %q for cluster %s: %w", tmpl.Name(), cluster.Name, err)
+		}
+		pattern := rendered.String()
+		if pattern == kubeContext.Name {
+			return true, nil
+		}
+		if matched, err := filepath.Match(pattern, kubeContext.Name); err == nil && matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// normalizeServer makes equivalent API server URLs compare equal, e.g.
+// https://API.example.com:443/ and https://api.example.com
+func normalizeServer(server string) string {
+	u, err := url.Parse(strings.TrimSpace(server))
+	if err != nil || u.Host == "" {
+		return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(server)), "/")
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (scheme == "https" && port == "443") || (scheme == "http" && port == "80") {
+		port = ""
+	}
+	if port != "" {
+		host = host + ":" + port
+	}
+	return scheme + "://" + host + strings.TrimSuffix(u.Path, "/")
+}
+
+// CAFingerprints returns the SHA-256 fingerprints of the certificates in a PEM bundle
+func CAFingerprints(pemData []byte) []string {
+	var fingerprints []string
+	for {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			return fingerprints
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		sum := sha256.Sum256(block.Bytes)
+		fingerprints = append(fingerprints, hex.EncodeToString(sum[:]))
+	}
+}
+
+// KubeContexts lists the contexts of a kubeconfig with their cluster's server
+// and CA fingerprints
+func KubeContexts(config clientcmdapi.Config) []KubeContext {
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	contexts := make([]KubeContext, 0, len(names))
+	for _, name := range names {
+		kubeContext := KubeContext{Name: name, Cluster: config.Contexts[name].Cluster}
+		if cluster, ok := config.Clusters[kubeContext.Cluster]; ok {
+			kubeContext.Server = cluster.Server
+			caData := cluster.CertificateAuthorityData
+			if len(caData) == 0 && cluster.CertificateAuthority != "" {
+				// An unreadable CA file only rules out matching by fingerprint
+				caData, _ = os.ReadFile(cluster.CertificateAuthority)
+			}
+			kubeContext.CAFingerprints = CAFingerprints(caData)
+		}
+		contexts = append(contexts, kubeContext)
+	}
+	return contexts
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
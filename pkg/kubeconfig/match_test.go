@@ -0,0 +1,135 @@
+package kubeconfig
+
+import (
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// testCA returns a PEM bundle; fingerprints only hash the DER bytes, so they
+// need not be a real certificate
+func testCA(der string) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte(der)})
+}
+
+func testKubeconfig() clientcmdapi.Config {
+	return clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"eks-fra":   {Server: "https://ABC.eks.amazonaws.com:443/"},
+			"gke-ams":   {Server: "https://10.0.0.1", CertificateAuthorityData: testCA("ams-ca")},
+			"kind-dev":  {Server: "https://127.0.0.1:6443"},
+			"shared":    {Server: "https://shared.example.com"},
+			"other-dev": {Server: "https://127.0.0.1:7443"},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"arn:aws:eks:eu-central-1:123:cluster/prod-fra": {Cluster: "eks-fra"},
+			"gke_project_europe-west4_prod-ams":             {Cluster: "gke-ams"},
+			"kind-dev":                                      {Cluster: "kind-dev"},
+			"staging":                                       {Cluster: "staging"},
+			"shared-admin":                                  {Cluster: "shared"},
+			"shared-viewer":                                 {Cluster: "shared"},
+			"dev-2":                                         {Cluster: "other-dev"},
+		},
+	}
+}
+
+func TestMatcherMatch(t *testing.T) {
+	clusters := []discovery.ClusterInfo{
+		{Name: "prod-fra", AccessProviders: []discovery.AccessProvider{{Server: "https://abc.eks.amazonaws.com"}}},
+		{Name: "prod-ams", AccessProviders: []discovery.AccessProvider{{Server: "https://34.1.2.3", CertificateAuthorityData: testCA("ams-ca")}}},
+		{Name: "staging"},
+		{Name: "shared", AccessProviders: []discovery.AccessProvider{{Server: "https://shared.example.com"}}},
+		{Name: "dev"},
+		{Name: "unknown"},
+	}
+
+	matcher, err := NewMatcher([]string{"kind-{{.Name}}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results, err := matcher.Match(clusters, KubeContexts(testKubeconfig()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []struct {
+		context    string
+		matchedBy  string
+		candidates string
+	}{
+		{context: "arn:aws:eks:eu-central-1:123:cluster/prod-fra", matchedBy: MatchByServer},
+		{context: "gke_project_europe-west4_prod-ams", matchedBy: MatchByCAFingerprint},
+		{context: "staging", matchedBy: MatchByContextName},
+		{matchedBy: MatchByServer, candidates: "shared-admin,shared-viewer"},
+		{context: "kind-dev", matchedBy: MatchByTemplate},
+		{},
+	}
+	for i, want := range expected {
+		got := results[i]
+		if got.Context != want.context {
+			t.Errorf("%s: expected context %q, got %q", got.Cluster.Name, want.context, got.Context)
+		}
+		if strings.Join(got.MatchedBy, ",") != want.matchedBy {
+			t.Errorf("%s: expected matched by %q, got %v", got.Cluster.Name, want.matchedBy, got.MatchedBy)
+		}
+		if strings.Join(got.Candidates, ",") != want.candidates {
+			t.Errorf("%s: expected candidates %q, got %v", got.Cluster.Name, want.candidates, got.Candidates)
+		}
+	}
+}
+
+func TestMatcherMatch_Narrowing(t *testing.T) {
+	// Both contexts share the API server; the context name breaks the tie
+	config := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{"shared": {Server: "https://shared.example.com"}},
+		Contexts: map[string]*clientcmdapi.Context{
+			"shared":        {Cluster: "shared"},
+			"shared-viewer": {Cluster: "shared"},
+		},
+	}
+	clusters := []discovery.ClusterInfo{{Name: "shared", AccessProviders: []discovery.AccessProvider{{Server: "https://shared.example.com"}}}}
+
+	matcher, _ := NewMatcher(nil)
+	results, err := matcher.Match(clusters, KubeContexts(config))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Context != "shared" || strings.Join(results[0].MatchedBy, ",") != "server,context-name" {
+		t.Errorf("expected shared matched by server,context-name, got %+v", results[0])
+	}
+}
+
+func TestMatcherTemplateGlob(t *testing.T) {
+	matcher, err := NewMatcher([]string{"arn:aws:eks:*:*:cluster/{{.Name}}"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	results, err := matcher.Match([]discovery.ClusterInfo{{Name: "prod-fra"}}, KubeContexts(testKubeconfig()))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Context != "arn:aws:eks:eu-central-1:123:cluster/prod-fra" {
+		t.Errorf("expected the EKS context, got %+v", results[0])
+	}
+
+	if _, err := NewMatcher([]string{"kind-{{.Name"}); err == nil {
+		t.Error("expected an invalid template to be rejected")
+	}
+}
+
+func TestNormalizeServer(t *testing.T) {
+	tests := map[string]string{
+		"https://API.example.com:443/": "https://api.example.com",
+		"https://api.example.com:6443": "https://api.example.com:6443",
+		"http://localhost:80":          "http://localhost",
+		"https://example.com/k8s/":     "https://example.com/k8s",
+	}
+	for input, expected := range tests {
+		if got := normalizeServer(input); got != expected {
+			t.Errorf("normalizeServer(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
@@ -3,23 +3,51 @@ package kubeconfig
 // ClusterMapping defines the mapping between ClusterProfile names and kubeconfig contexts
 type ClusterMapping struct {
 	// Name is the ClusterProfile name
-	Name string `yaml:"name"`
+	Name string `json:"name" yaml:"name"`
 
 	// Context is the kubeconfig context name
-	Context string `yaml:"context"`
+	Context string `json:"context" yaml:"context"`
 
 	// Namespace where the ClusterProfile exists
-	Namespace string `yaml:"namespace,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+
+	// Kubeconfig is the kubeconfig file holding the context; empty uses the
+	// default loading rules ($KUBECONFIG or ~/.kube/config)
+	Kubeconfig string `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
+
+	// User overrides the auth user of the context
+	User string `json:"user,omitempty" yaml:"user,omitempty"`
+
+	// DefaultNamespace overrides the namespace of the context
+	DefaultNamespace string `json:"defaultNamespace,omitempty" yaml:"defaultNamespace,omitempty"`
+
+	// As is the user to impersonate on the cluster
+	As string `json:"as,omitempty" yaml:"as,omitempty"`
+
+	// AsGroups are the groups to impersonate; they require As
+	AsGroups []string `json:"asGroups,omitempty" yaml:"asGroups,omitempty"`
+
+	// ProxyURL is the http, https or socks5 proxy used to reach the API server,
+	// e.g. a bastion tunnel
+	ProxyURL string `json:"proxyURL,omitempty" yaml:"proxyURL,omitempty"`
+
+	// TLSServerName overrides the server name used to verify the API server
+	// certificate, e.g. when the server is reached through a tunnel
+	TLSServerName string `json:"tlsServerName,omitempty" yaml:"tlsServerName,omitempty"`
+
+	// KubeSystemUID is the UID of the kube-system namespace recorded when the
+	// mapping was verified; it identifies the cluster the context must reach
+	KubeSystemUID string `json:"kubeSystemUID,omitempty" yaml:"kubeSystemUID,omitempty"`
 }
 
 // MappingConfig is the configuration file format for cluster mappings
 type MappingConfig struct {
-	APIVersion string `yaml:"apiVersion"`
-	Kind       string `yaml:"kind"`
+	APIVersion string `json:"apiVersion" yaml:"apiVersion"`
+	Kind       string `json:"kind" yaml:"kind"`
 
 	// HubContext is the optional default hub context
-	HubContext string `yaml:"hubContext,omitempty"`
+	HubContext string `json:"hubContext,omitempty" yaml:"hubContext,omitempty"`
 
 	// Clusters is the list of cluster mappings
-	Clusters []ClusterMapping `yaml:"clusters"`
+	Clusters []ClusterMapping `json:"clusters" yaml:"clusters"`
 }
@@ -0,0 +1,384 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/aggregator"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+)
+
+// clusterResponse is the response of a named cluster
+type clusterResponse struct {
+	cluster  string
+	response *response
+}
+
+// decodedResponse is a successful JSON response of a named cluster
+type decodedResponse struct {
+	cluster string
+	object  map[string]interface{}
+}
+
+// mergedResponse is the answer of the fleet to a fanned out request, with a
+// warning per cluster that failed or warned
+type mergedResponse struct {
+	response *response
+	warnings []string
+}
+
+// merge combines the responses of the clusters that answered a request.
+// failures holds the clusters that could not be reached.
+//
+// Lists and tables are concatenated, annotating each object with its cluster;
+// lists of different kinds merge into a List, and a table or list that other
+// clusters answer with another kind is a bad gateway. A single object is returned when exactly one cluster has it, and is a
+// conflict when several do. When no cluster succeeds, a failure shared by
+// every cluster, e.g. NotFound, is returned as is.
+func merge(answers []clusterResponse, failures map[string]error) mergedResponse {
+	var merged mergedResponse
+	for _, name := range sortedNames(failures) {
+		merged.warnings = appendWarning(merged.warnings, name, failures[name].Error())
+	}
+
+	var succeeded, failed []clusterResponse
+	for _, answer := range answers {
+		for _, warning := range clusterWarnings(answer) {
+			merged.warnings = appendWarning(merged.warnings, answer.cluster, warning)
+		}
+		if answer.response.status < http.StatusBadRequest {
+			succeeded = append(succeeded, answer)
+		} else {
+			failed = append(failed, answer)
+		}
+	}
+
+	if len(succeeded) == 0 {
+		merged.response = mergeFailures(failed, failures)
+		return merged
+	}
+
+	decoded := make([]decodedResponse, 0, len(succeeded))
+	for _, answer := range succeeded {
+		var object map[string]interface{}
+		if err := json.Unmarshal(answer.response.body, &object); err != nil {
+			// Not a Kubernetes object, e.g. logs: only a single answer can be returned
+			decoded = nil
+			break
+		}
+		decoded = append(decoded, decodedResponse{cluster: answer.cluster, object: object})
+	}
+
+	kind, mismatch := decodedKind(decoded)
+	collection := mismatch != nil || kind == "Table" || strings.HasSuffix(kind, "List")
+
+	// A missing single object is expected on most clusters; a missing
+	// resource type in a list is worth a warning
+	for _, answer := range failed {
+		if !collection && answer.response.status == http.StatusNotFound {
+			continue
+		}
+		merged.warnings = appendWarning(merged.warnings, answer.cluster, statusMessage(answer.response))
+	}
+
+	switch {
+	case mismatch != nil:
+		merged.response = statusResponse(newBadGateway(mismatch.Error()))
+	case kind == "Table":
+		merged.response = jsonResponse(http.StatusOK, mergeTables(decoded))
+	case strings.HasSuffix(kind, "List"):
+		list := mergeLists(decoded)
+		if list["kind"] != kind {
+			list["kind"], list["apiVersion"] = kind, "v1"
+		}
+		merged.response = jsonResponse(http.StatusOK, list)
+	case len(succeeded) > 1:
+		merged.response = conflictResponse(succeeded, decoded)
+	case decoded != nil:
+		annotate(decoded[0].object, decoded[0].cluster)
+		merged.response = jsonResponse(succeeded[0].response.status, decoded[0].object)
+	default:
+		merged.response = &response{
+			status: succeeded[0].response.status,
+			header: http.Header{"Content-Type": succeeded[0].response.header.Values("Content-Type")},
+			body:   succeeded[0].response.body,
+		}
+	}
+	return merged
+}
+
+// decodedKind returns the kind shared by every decoded response. Lists of
+// different kinds share the kind List; a table or list next to another kind
+// is returned as a mismatch naming each cluster's kind.
+func decodedKind(decoded []decodedResponse) (string, error) {
+	if len(decoded) == 0 {
+		return "", nil
+	}
+
+	kinds := make([]string, 0, len(decoded))
+	shared, lists, collections := true, 0, 0
+	for _, d := range decoded {
+		kind, _ := d.object["kind"].(string)
+		kinds = append(kinds, kind)
+		if kind != kinds[0] {
+			shared = false
+		}
+		if strings.HasSuffix(kind, "List") {
+			lists++
+		}
+		if kind == "Table" || strings.HasSuffix(kind, "List") {
+			collections++
+		}
+	}
+
+	switch {
+	case shared:
+		return kinds[0], nil
+	case lists == len(kinds):
+		return "List", nil
+	case collections == 0:
+		return "", nil
+	}
+
+	answers := make([]string, 0, len(decoded))
+	for i, d := range decoded {
+		kind := kinds[i]
+		if kind == "" {
+			kind = "no kind"
+		}
+		answers = append(answers, fmt.Sprintf("cluster %s: %s", d.cluster, kind))
+	}
+	return "", fmt.Errorf("clusters answered with different kinds (%s)", strings.Join(answers, ", "))
+}
+
+// mergeLists concatenates the items of lists, annotating each with its cluster
+func mergeLists(decoded []decodedResponse) map[string]interface{} {
+	list := decoded[0].object
+	items := []interface{}{}
+	for _, d := range decoded {
+		clusterItems, _ := d.object["items"].([]interface{})
+		for _, item := range clusterItems {
+			if object, ok := item.(map[string]interface{}); ok {
+				annotate(object, d.cluster)
+			}
+			items = append(items, item)
+		}
+	}
+	list["items"] = items
+	clearListMeta(list)
+	return list
+}
+
+// mergeTables concatenates the rows of server-side tables under the columns of
+// the first table, prepended with a Cluster column
+func mergeTables(decoded []decodedResponse) map[string]interface{} {
+	table := decoded[0].object
+	columns, _ := table["columnDefinitions"].([]interface{})
+	names := columnNames(columns)
+
+	rows := []interface{}{}
+	for _, d := range decoded {
+		// Clusters of other versions may order or name columns differently
+		index := make(map[string]int)
+		clusterColumns, _ := d.object["columnDefinitions"].([]interface{})
+		for i, name := range columnNames(clusterColumns) {
+			index[name] = i
+		}
+
+		clusterRows, _ := d.object["rows"].([]interface{})
+		for _, r := range clusterRows {
+			row, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			cells, _ := row["cells"].([]interface{})
+			remapped := make([]interface{}, 0, len(names)+1)
+			remapped = append(remapped, d.cluster)
+			for _, name := range names {
+				var cell interface{}
+				if i, ok := index[name]; ok && i < len(cells) {
+					cell = cells[i]
+				}
+				remapped = append(remapped, cell)
+			}
+			row["cells"] = remapped
+
+			if object, ok := row["object"].(map[string]interface{}); ok {
+				annotate(object, d.cluster)
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	clusterColumn := map[string]interface{}{
+		"name":        "Cluster",
+		"type":        "string",
+		"format":      "",
+		"description": "Cluster the object was retrieved from",
+		"priority":    0,
+	}
+	table["columnDefinitions"] = append([]interface{}{clusterColumn}, columns...)
+	table["rows"] = rows
+	clearListMeta(table)
+	return table
+}
+
+// columnNames returns the names of table column definitions
+func columnNames(columns []interface{}) []string {
+	names := make([]string, 0, len(columns))
+	for _, c := range columns {
+		column, _ := c.(map[string]interface{})
+		name, _ := column["name"].(string)
+		names = append(names, name)
+	}
+	return names
+}
+
+// clearListMeta removes the resource version and continue token of a merged
+// list, which are only meaningful on a single cluster
+func clearListMeta(list map[string]interface{}) {
+	metadata, ok := list["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	delete(metadata, "resourceVersion")
+	delete(metadata, "continue")
+	delete(metadata, "remainingItemCount")
+}
+
+// annotate records the cluster of an object in its annotations
+func annotate(object map[string]interface{}, cluster string) {
+	u := unstructured.Unstructured{Object: object}
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[aggregator.ClusterAnnotation] = cluster
+	u.SetAnnotations(annotations)
+}
+
+// conflictResponse refuses to pick one of several clusters holding the same object
+func conflictResponse(succeeded []clusterResponse, decoded []decodedResponse) *response {
+	clusters := make([]string, 0, len(succeeded))
+	for _, answer := range succeeded {
+		clusters = append(clusters, answer.cluster)
+	}
+
+	what := "the object"
+	if len(decoded) > 0 {
+		u := unstructured.Unstructured{Object: decoded[0].object}
+		what = fmt.Sprintf("%s %q", u.GetKind(), u.GetName())
+	}
+
+	return statusResponse(&apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusConflict,
+		Reason:  metav1.StatusReasonConflict,
+		Message: fmt.Sprintf("%s was found in clusters %s; get it from one cluster under %s<name>/", what, strings.Join(clusters, ", "), ClusterPathPrefix),
+	}})
+}
+
+// mergeFailures answers a request no cluster succeeded on. A status shared by
+// every cluster that answered is passed through; anything else is a bad gateway.
+func mergeFailures(failed []clusterResponse, failures map[string]error) *response {
+	shared := len(failed) > 0
+	for _, answer := range failed {
+		if answer.response.status != failed[0].response.status {
+			shared = false
+		}
+	}
+	if shared {
+		return &response{
+			status: failed[0].response.status,
+			header: http.Header{"Content-Type": failed[0].response.header.Values("Content-Type")},
+			body:   failed[0].response.body,
+		}
+	}
+
+	var messages []string
+	for _, name := range sortedNames(failures) {
+		messages = append(messages, fmt.Sprintf("cluster %s: %v", name, failures[name]))
+	}
+	for _, answer := range failed {
+		messages = append(messages, fmt.Sprintf("cluster %s: %s", answer.cluster, statusMessage(answer.response)))
+	}
+	if len(messages) == 0 {
+		messages = append(messages, "no clusters selected")
+	}
+	return statusResponse(newBadGateway(strings.Join(messages, "; ")))
+}
+
+// statusMessage returns the message of a failed response
+func statusMessage(resp *response) string {
+	var status metav1.Status
+	if err := json.Unmarshal(resp.body, &status); err == nil && status.Message != "" {
+		return status.Message
+	}
+	if body := strings.TrimSpace(string(resp.body)); body != "" {
+		return body
+	}
+	return http.StatusText(resp.status)
+}
+
+// clusterWarnings returns the texts of the warnings a cluster sent
+func clusterWarnings(answer clusterResponse) []string {
+	headers, _ := utilnet.ParseWarningHeaders(answer.response.header.Values("Warning"))
+	texts := make([]string, 0, len(headers))
+	for _, header := range headers {
+		texts = append(texts, header.Text)
+	}
+	return texts
+}
+
+// appendWarning appends a warning header about a cluster
+func appendWarning(warnings []string, cluster, text string) []string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	header, err := utilnet.NewWarningHeader(299, "-", fmt.Sprintf("cluster %s: %s", cluster, text))
+	if err != nil {
+		return warnings
+	}
+	return append(warnings, header)
+}
+
+// jsonResponse encodes object as a JSON response
+func jsonResponse(status int, object interface{}) *response {
+	body, err := json.Marshal(object)
+	if err != nil {
+		return statusResponse(apierrors.NewInternalError(fmt.Errorf("failed to encode response: %w", err)))
+	}
+	return &response{
+		status: status,
+		header: http.Header{"Content-Type": []string{"application/json"}},
+		body:   body,
+	}
+}
+
+// statusResponse encodes err as a Kubernetes Status response
+func statusResponse(err *apierrors.StatusError) *response {
+	status := err.ErrStatus
+	status.Kind = "Status"
+	status.APIVersion = "v1"
+	return jsonResponse(int(status.Code), status)
+}
+
+// sortedNames returns the cluster names of failures in order
+func sortedNames(failures map[string]error) []string {
+	names := make([]string, 0, len(failures))
+	for name := range failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,427 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// ClusterPathPrefix routes a request to a single cluster: /clusters/<name>/<API path>
+const ClusterPathPrefix = "/clusters/"
+
+// maxResponseBytes bounds the response of a single cluster to a fanned out request
+const maxResponseBytes = 256 << 20
+
+// Server is an http.Handler serving the Kubernetes API of a fleet of clusters.
+//
+// Requests under ClusterPathPrefix are proxied to the named cluster as they are,
+// including watches. Other GET requests are sent to every cluster through the
+// executor: lists and server-side tables are merged, with each object annotated
+// with its cluster, and a single object is returned when exactly one cluster
+// has it. Discovery requests are answered by the first cluster that responds.
+type Server struct {
+	exec     *executor.Executor
+	clients  client.ClusterClientProvider
+	clusters []discovery.ClusterInfo
+	byName   map[string]discovery.ClusterInfo
+
+	// acceptHosts restricts the Host header of requests; empty accepts any host
+	acceptHosts []*regexp.Regexp
+
+	// rejectPaths and rejectMethods refuse requests whose API path or method
+	// matches, e.g. exec into pods
+	rejectPaths   []*regexp.Regexp
+	rejectMethods []*regexp.Regexp
+
+	upstreamsMu sync.Mutex
+	upstreams   map[string]*upstream
+}
+
+// upstream is how the server reaches one cluster
+type upstream struct {
+	host   *url.URL
+	client *http.Client
+}
+
+// response is one cluster's answer to a request
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// NewServer creates a server fanning requests out to clusters with exec,
+// reaching each cluster with the credentials of clients
+func NewServer(exec *executor.Executor, clients client.ClusterClientProvider, clusters []discovery.ClusterInfo) *Server {
+	sorted := append([]discovery.ClusterInfo(nil), clusters...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	byName := make(map[string]discovery.ClusterInfo, len(sorted))
+	for _, cluster := range sorted {
+		byName[cluster.Name] = cluster
+	}
+
+	return &Server{
+		exec:      exec,
+		clients:   clients,
+		clusters:  sorted,
+		byName:    byName,
+		upstreams: make(map[string]*upstream),
+	}
+}
+
+// SetAcceptHosts restricts the server to requests whose Host header, without
+// the port, matches one of patterns. It guards a local proxy against DNS
+// rebinding; nil accepts any host.
+func (s *Server) SetAcceptHosts(patterns []*regexp.Regexp) {
+	s.acceptHosts = patterns
+}
+
+// SetRejectPaths refuses requests whose API path, without the
+// ClusterPathPrefix and cluster name, matches one of patterns. Since each
+// cluster is reached with the credentials of its mapping, this keeps callers
+// of the proxy from e.g. exec into pods; nil accepts any path.
+func (s *Server) SetRejectPaths(patterns []*regexp.Regexp) {
+	s.rejectPaths = patterns
+}
+
+// SetRejectMethods refuses requests whose HTTP method matches one of
+// patterns; nil accepts any method.
+func (s *Server) SetRejectMethods(patterns []*regexp.Regexp) {
+	s.rejectMethods = patterns
+}
+
+// ServeHTTP routes a request to a single cluster or to the whole fleet
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.acceptHost(r.Host) {
+		writeError(w, apierrors.NewForbidden(schema.GroupResource{}, "", fmt.Errorf("host %q is not accepted by the proxy", r.Host)))
+		return
+	}
+
+	name, apiPath, single := "", r.URL.Path, false
+	if rest, ok := strings.CutPrefix(r.URL.Path, ClusterPathPrefix); ok {
+		var clusterPath string
+		name, clusterPath, _ = strings.Cut(rest, "/")
+		apiPath, single = "/"+clusterPath, true
+	}
+
+	if matchAny(s.rejectMethods, r.Method) || matchAny(s.rejectPaths, path.Clean(apiPath)) {
+		writeError(w, apierrors.NewForbidden(schema.GroupResource{}, "", fmt.Errorf("%s %s is rejected by the proxy", r.Method, apiPath)))
+		return
+	}
+
+	if single {
+		s.serveCluster(w, r, name, apiPath)
+		return
+	}
+	s.serveFleet(w, r)
+}
+
+// matchAny reports whether value matches one of patterns
+func matchAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptHost reports whether the Host header of a request is accepted
+func (s *Server) acceptHost(host string) bool {
+	if len(s.acceptHosts) == 0 {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return matchAny(s.acceptHosts, host)
+}
+
+// serveCluster proxies a request to the named cluster
+func (s *Server) serveCluster(w http.ResponseWriter, r *http.Request, name, path string) {
+	cluster, ok := s.byName[name]
+	if !ok {
+		writeError(w, apierrors.NewNotFound(schema.GroupResource{Resource: "clusters"}, name))
+		return
+	}
+
+	if err := s.exec.VerifyCluster(r.Context(), cluster); err != nil {
+		writeError(w, newBadGateway(fmt.Sprintf("cluster %s: %v", cluster.Name, err)))
+		return
+	}
+
+	up, err := s.upstream(cluster)
+	if err != nil {
+		writeError(w, newBadGateway(fmt.Sprintf("cluster %s: %v", cluster.Name, err)))
+		return
+	}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL.Path = path
+			pr.Out.URL.RawPath = ""
+			pr.SetURL(up.host)
+			stripCredentials(pr.Out.Header)
+		},
+		Transport: up.client.Transport,
+		// Flush immediately, so watches and log streams are not buffered
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			writeError(w, newBadGateway(fmt.Sprintf("cluster %s: %v", cluster.Name, err)))
+		},
+	}
+	proxy.ServeHTTP(w, r)
+}
+
+// serveFleet sends a request to every cluster and merges the responses
+func (s *Server) serveFleet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, &apierrors.StatusError{ErrStatus: metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusMethodNotAllowed,
+			Reason:  metav1.StatusReasonMethodNotAllowed,
+			Message: fmt.Sprintf("%s requests are not sent to the whole fleet; send them to a single cluster under %s<name>/", r.Method, ClusterPathPrefix),
+		}})
+		return
+	}
+
+	query := r.URL.Query()
+	if watch := query.Get("watch"); watch == "true" || watch == "1" {
+		writeError(w, apierrors.NewBadRequest(fmt.Sprintf("watch requests are not sent to the whole fleet; watch a single cluster under %s<name>/", ClusterPathPrefix)))
+		return
+	}
+
+	if isDiscoveryPath(r.URL.Path) {
+		s.serveFirst(w, r)
+		return
+	}
+
+	// Pagination cannot span clusters, so every cluster returns its whole list
+	query.Del("limit")
+	query.Del("continue")
+
+	var mu sync.Mutex
+	responses := make(map[string]*response, len(s.clusters))
+	results := s.exec.Run(r.Context(), s.clusters, func(ctx context.Context, target executor.ClusterTarget) executor.ClusterResult {
+		resp, err := s.get(ctx, target.Cluster, r.URL.Path, query.Encode(), jsonAccept(r.Header.Get("Accept")))
+		if err != nil {
+			return executor.ClusterResult{ClusterName: target.Cluster.Name, Error: err}
+		}
+
+		mu.Lock()
+		responses[target.Cluster.Name] = resp
+		mu.Unlock()
+		return executor.ClusterResult{ClusterName: target.Cluster.Name, Success: true}
+	})
+
+	var answers []clusterResponse
+	for _, result := range results.Results {
+		if !result.Success {
+			continue
+		}
+		answers = append(answers, clusterResponse{cluster: result.ClusterName, response: responses[result.ClusterName]})
+	}
+
+	merged := merge(answers, results.Summary.Errors)
+	for _, warning := range merged.warnings {
+		w.Header().Add("Warning", warning)
+	}
+	writeResponse(w, merged.response)
+}
+
+// serveFirst answers a request with the response of the first cluster, in
+// name order, that answers successfully
+func (s *Server) serveFirst(w http.ResponseWriter, r *http.Request) {
+	var last *response
+	var errs []string
+	for _, cluster := range s.clusters {
+		if err := s.exec.VerifyCluster(r.Context(), cluster); err != nil {
+			errs = append(errs, fmt.Sprintf("cluster %s: %v", cluster.Name, err))
+			continue
+		}
+
+		resp, err := s.get(r.Context(), cluster, r.URL.Path, r.URL.RawQuery, r.Header.Get("Accept"))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("cluster %s: %v", cluster.Name, err))
+			continue
+		}
+		if resp.status < http.StatusBadRequest {
+			writeResponse(w, resp)
+			return
+		}
+		last = resp
+	}
+
+	if last != nil {
+		writeResponse(w, last)
+		return
+	}
+	if len(errs) == 0 {
+		errs = append(errs, "no clusters selected")
+	}
+	writeError(w, newBadGateway(strings.Join(errs, "; ")))
+}
+
+// get sends a GET request for path to a cluster and reads the response
+func (s *Server) get(ctx context.Context, cluster discovery.ClusterInfo, path, rawQuery, accept string) (*response, error) {
+	up, err := s.upstream(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	target := *up.host
+	target.Path = strings.TrimSuffix(target.Path, "/") + path
+	target.RawQuery = rawQuery
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := up.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > maxResponseBytes {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxResponseBytes)
+	}
+
+	return &response{status: resp.StatusCode, header: resp.Header, body: body}, nil
+}
+
+// upstream returns the server URL and HTTP client of a cluster, creating them
+// on first use
+func (s *Server) upstream(cluster discovery.ClusterInfo) (*upstream, error) {
+	s.upstreamsMu.Lock()
+	defer s.upstreamsMu.Unlock()
+
+	if up, ok := s.upstreams[cluster.Name]; ok {
+		return up, nil
+	}
+
+	config, err := s.clients.RESTConfig(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get REST config: %w", err)
+	}
+
+	host, _, err := rest.DefaultServerUrlFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server URL: %w", err)
+	}
+
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client: %w", err)
+	}
+
+	up := &upstream{host: host, client: httpClient}
+	s.upstreams[cluster.Name] = up
+	return up, nil
+}
+
+// isDiscoveryPath reports whether path serves API discovery, OpenAPI, version
+// or health information rather than resources
+func isDiscoveryPath(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	switch parts[0] {
+	case "":
+		return true
+	case "api":
+		// /api and /api/v1
+		return len(parts) <= 2
+	case "apis":
+		// /apis, /apis/<group> and /apis/<group>/<version>
+		return len(parts) <= 3
+	case "version", "openapi", "healthz", "readyz", "livez":
+		return true
+	}
+	return false
+}
+
+// jsonAccept keeps the media types of an Accept header that can be merged,
+// dropping protobuf
+func jsonAccept(accept string) string {
+	var kept []string
+	for _, mediaType := range strings.Split(accept, ",") {
+		mediaType = strings.TrimSpace(mediaType)
+		if mediaType == "" || strings.Contains(mediaType, "protobuf") {
+			continue
+		}
+		kept = append(kept, mediaType)
+	}
+	if len(kept) == 0 {
+		return "application/json"
+	}
+	return strings.Join(kept, ",")
+}
+
+// stripCredentials removes the credentials and impersonation headers of the
+// client, so clusters only see those of their mapping
+func stripCredentials(header http.Header) {
+	header.Del("Authorization")
+	for name := range header {
+		if strings.HasPrefix(name, "Impersonate-") {
+			header.Del(name)
+		}
+	}
+}
+
+// writeResponse writes a cluster's response with its content type and warnings
+func writeResponse(w http.ResponseWriter, resp *response) {
+	if contentType := resp.header.Get("Content-Type"); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	for _, warning := range resp.header.Values("Warning") {
+		w.Header().Add("Warning", warning)
+	}
+	w.WriteHeader(resp.status)
+	_, _ = w.Write(resp.body)
+}
+
+// writeError writes err as a Kubernetes Status
+func writeError(w http.ResponseWriter, err error) {
+	statusErr, ok := err.(*apierrors.StatusError)
+	if !ok {
+		statusErr = apierrors.NewInternalError(err)
+	}
+	writeResponse(w, statusResponse(statusErr))
+}
+
+// newBadGateway returns the error of a request no cluster could answer
+func newBadGateway(message string) *apierrors.StatusError {
+	return &apierrors.StatusError{ErrStatus: metav1.Status{
+		Status:  metav1.StatusFailure,
+		Code:    http.StatusBadGateway,
+		Reason:  metav1.StatusReasonServiceUnavailable,
+		Message: message,
+	}}
+}
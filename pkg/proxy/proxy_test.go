@@ -0,0 +1,520 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/suchpuppet/kubectl-mc/pkg/aggregator"
+	"github.com/suchpuppet/kubectl-mc/pkg/client"
+	"github.com/suchpuppet/kubectl-mc/pkg/discovery"
+	"github.com/suchpuppet/kubectl-mc/pkg/executor"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/rest"
+)
+
+// fakeCluster is an API server answering fixed responses per path and
+// recording the requests it received
+type fakeCluster struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []*http.Request
+}
+
+// fakeRoute is the response of a fake cluster to a path
+type fakeRoute struct {
+	status int
+	body   string
+	header http.Header
+}
+
+// newFakeCluster starts a fake cluster; paths without a route answer 404
+func newFakeCluster(t *testing.T, routes map[string]fakeRoute) *fakeCluster {
+	t.Helper()
+	cluster := &fakeCluster{}
+	cluster.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cluster.mu.Lock()
+		cluster.requests = append(cluster.requests, r.Clone(r.Context()))
+		cluster.mu.Unlock()
+
+		route, ok := routes[r.URL.Path]
+		if !ok {
+			route = fakeRoute{status: http.StatusNotFound, body: `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"NotFound","code":404,"message":"not found"}`}
+		}
+		for name, values := range route.header {
+			w.Header()[name] = values
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if route.status == 0 {
+			route.status = http.StatusOK
+		}
+		w.WriteHeader(route.status)
+		fmt.Fprint(w, route.body)
+	}))
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+// lastRequest returns the last request the fake cluster received
+func (c *fakeCluster) lastRequest(t *testing.T) *http.Request {
+	t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) == 0 {
+		t.Fatalf("expected a request on %s", c.URL)
+	}
+	return c.requests[len(c.requests)-1]
+}
+
+// newTestServer serves the fake clusters by name. Names without a fake
+// cluster are discovered but have no clients.
+func newTestServer(clusters map[string]*fakeCluster, names ...string) *Server {
+	provider := client.NewFakeProvider()
+	var infos []discovery.ClusterInfo
+	for _, name := range names {
+		infos = append(infos, discovery.ClusterInfo{Name: name})
+		if cluster, ok := clusters[name]; ok {
+			provider.SetClients(name, client.FakeClusterClients{RESTConfig: &rest.Config{Host: cluster.URL}})
+		}
+	}
+	return NewServer(executor.NewExecutor(provider), provider, infos)
+}
+
+// serve sends a request to the server and returns the recorded response
+func serve(server *Server, method, target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
+// podList returns a PodList holding pods of the given names
+func podList(names ...string) string {
+	items := make([]string, 0, len(names))
+	for _, name := range names {
+		items = append(items, pod(name))
+	}
+	return fmt.Sprintf(`{"kind":"PodList","apiVersion":"v1","metadata":{"resourceVersion":"42","continue":"abc"},"items":[%s]}`, strings.Join(items, ","))
+}
+
+// pod returns a pod in the default namespace
+func pod(name string) string {
+	return fmt.Sprintf(`{"kind":"Pod","apiVersion":"v1","metadata":{"name":%q,"namespace":"default"}}`, name)
+}
+
+// decode decodes a JSON response body
+func decode(t *testing.T, rec *httptest.ResponseRecorder) *unstructured.Unstructured {
+	t.Helper()
+	var object map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &object); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return &unstructured.Unstructured{Object: object}
+}
+
+func TestServer_MergesLists(t *testing.T) {
+	clusters := map[string]*fakeCluster{
+		"cluster1": newFakeCluster(t, map[string]fakeRoute{"/api/v1/namespaces/default/pods": {body: podList("web-1", "web-2")}}),
+		"cluster2": newFakeCluster(t, map[string]fakeRoute{"/api/v1/namespaces/default/pods": {body: podList("web-3")}}),
+	}
+	server := newTestServer(clusters, "cluster2", "cluster1")
+
+	rec := serve(server, http.MethodGet, "/api/v1/namespaces/default/pods?limit=500&labelSelector=app%3Dweb", http.Header{
+		"Accept": {"application/vnd.kubernetes.protobuf,application/json"},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	list, err := decode(t, rec).ToList()
+	if err != nil {
+		t.Fatalf("failed to read list: %v", err)
+	}
+	var got []string
+	for _, item := range list.Items {
+		got = append(got, item.GetAnnotations()[aggregator.ClusterAnnotation]+"/"+item.GetName())
+	}
+	if want := "cluster1/web-1,cluster1/web-2,cluster2/web-3"; strings.Join(got, ",") != want {
+		t.Errorf("expected items %s, got %s", want, strings.Join(got, ","))
+	}
+	if list.GetResourceVersion() != "" || list.GetContinue() != "" {
+		t.Errorf("expected resourceVersion and continue to be cleared, got %q and %q", list.GetResourceVersion(), list.GetContinue())
+	}
+
+	req := clusters["cluster1"].lastRequest(t)
+	if req.URL.Query().Has("limit") {
+		t.Errorf("expected limit not to be forwarded, got query %q", req.URL.RawQuery)
+	}
+	if req.URL.Query().Get("labelSelector") != "app=web" {
+		t.Errorf("expected labelSelector to be forwarded, got query %q", req.URL.RawQuery)
+	}
+	if accept := req.Header.Get("Accept"); accept != "application/json" {
+		t.Errorf("expected Accept application/json, got %q", accept)
+	}
+}
+
+func TestServer_MergesTables(t *testing.T) {
+	table := func(columns []string, cells ...[]string) string {
+		var defs, rows []string
+		for _, column := range columns {
+			defs = append(defs, fmt.Sprintf(`{"name":%q,"type":"string"}`, column))
+		}
+		for _, row := range cells {
+			encoded, _ := json.Marshal(row)
+			rows = append(rows, fmt.Sprintf(`{"cells":%s,"object":{"kind":"PartialObjectMetadata","metadata":{"name":%q}}}`, encoded, row[0]))
+		}
+		return fmt.Sprintf(`{"kind":"Table","apiVersion":"meta.k8s.io/v1","metadata":{"resourceVersion":"7"},"columnDefinitions":[%s],"rows":[%s]}`,
+			strings.Join(defs, ","), strings.Join(rows, ","))
+	}
+
+	clusters := map[string]*fakeCluster{
+		"cluster1": newFakeCluster(t, map[string]fakeRoute{"/api/v1/pods": {body: table([]string{"Name", "Status"}, []string{"web-1", "Running"})}}),
+		// An older cluster ordering its columns differently and missing Status
+		"cluster2": newFakeCluster(t, map[string]fakeRoute{"/api/v1/pods": {body: table([]string{"Age", "Name"}, []string{"web-2", "5m"})}}),
+	}
+	server := newTestServer(clusters, "cluster1", "cluster2")
+
+	rec := serve(server, http.MethodGet, "/api/v1/pods", http.Header{"Accept": {"application/json;as=Table;v=v1;g=meta.k8s.io"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var merged struct {
+		ColumnDefinitions []struct {
+			Name string `json:"name"`
+		} `json:"columnDefinitions"`
+		Rows []struct {
+			Cells  []interface{}          `json:"cells"`
+			Object map[string]interface{} `json:"object"`
+		} `json:"rows"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &merged); err != nil {
+		t.Fatalf("failed to decode table: %v", err)
+	}
+
+	var columns []string
+	for _, column := range merged.ColumnDefinitions {
+		columns = append(columns, column.Name)
+	}
+	if got, want := strings.Join(columns, ","), "Cluster,Name,Status"; got != want {
+		t.Errorf("expected columns %s, got %s", want, got)
+	}
+
+	want := [][]interface{}{{"cluster1", "web-1", "Running"}, {"cluster2", "5m", nil}}
+	if len(merged.Rows) != len(want) {
+		t.Fatalf("expected %d rows, got %d", len(want), len(merged.Rows))
+	}
+	for i, row := range merged.Rows {
+		if fmt.Sprint(row.Cells) != fmt.Sprint(want[i]) {
+			t.Errorf("expected row %d to be %v, got %v", i, want[i], row.Cells)
+		}
+		object := unstructured.Unstructured{Object: row.Object}
+		if got := object.GetAnnotations()[aggregator.ClusterAnnotation]; got != want[i][0] {
+			t.Errorf("expected row %d object to be annotated with %v, got %q", i, want[i][0], got)
+		}
+	}
+}
+
+func TestServer_MixedKinds(t *testing.T) {
+	path := "/api/v1/namespaces/default/pods"
+	tests := []struct {
+		name       string
+		bodies     []string
+		wantStatus int
+		wantKind   string
+		wantItems  int
+	}{
+		{name: "lists of different kinds", bodies: []string{podList("web-1"), `{"kind":"List","apiVersion":"v1","metadata":{},"items":[` + pod("web-2") + `]}`}, wantStatus: http.StatusOK, wantKind: "List", wantItems: 2},
+		{name: "table and list", bodies: []string{`{"kind":"Table","apiVersion":"meta.k8s.io/v1","metadata":{},"columnDefinitions":[],"rows":[]}`, podList("web-2")}, wantStatus: http.StatusBadGateway},
+		{name: "list and single object", bodies: []string{podList("web-1"), pod("web-2")}, wantStatus: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters := map[string]*fakeCluster{
+				"cluster1": newFakeCluster(t, map[string]fakeRoute{path: {body: tt.bodies[0]}}),
+				"cluster2": newFakeCluster(t, map[string]fakeRoute{path: {body: tt.bodies[1]}}),
+			}
+			server := newTestServer(clusters, "cluster1", "cluster2")
+
+			rec := serve(server, http.MethodGet, path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if !strings.Contains(rec.Body.String(), "different kinds") {
+					t.Errorf("expected the kind mismatch to be named, got %s", rec.Body.String())
+				}
+				return
+			}
+
+			list, err := decode(t, rec).ToList()
+			if err != nil {
+				t.Fatalf("failed to read list: %v", err)
+			}
+			if list.GetKind() != tt.wantKind || len(list.Items) != tt.wantItems {
+				t.Errorf("expected %s with %d items, got %s with %d", tt.wantKind, tt.wantItems, list.GetKind(), len(list.Items))
+			}
+		})
+	}
+}
+
+func TestServer_SingleObject(t *testing.T) {
+	path := "/api/v1/namespaces/default/pods/web-1"
+	tests := []struct {
+		name        string
+		holders     []string
+		wantStatus  int
+		wantCluster string
+	}{
+		{name: "found on one cluster", holders: []string{"cluster2"}, wantStatus: http.StatusOK, wantCluster: "cluster2"},
+		{name: "found on several clusters", holders: []string{"cluster1", "cluster2"}, wantStatus: http.StatusConflict},
+		{name: "found nowhere", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters := map[string]*fakeCluster{}
+			for _, name := range []string{"cluster1", "cluster2"} {
+				routes := map[string]fakeRoute{}
+				for _, holder := range tt.holders {
+					if holder == name {
+						routes[path] = fakeRoute{body: pod("web-1")}
+					}
+				}
+				clusters[name] = newFakeCluster(t, routes)
+			}
+			server := newTestServer(clusters, "cluster1", "cluster2")
+
+			rec := serve(server, http.MethodGet, path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantCluster != "" {
+				if got := decode(t, rec).GetAnnotations()[aggregator.ClusterAnnotation]; got != tt.wantCluster {
+					t.Errorf("expected object to be annotated with %s, got %q", tt.wantCluster, got)
+				}
+			}
+			if warnings := rec.Header().Values("Warning"); len(warnings) != 0 {
+				t.Errorf("expected no warnings for clusters without the object, got %v", warnings)
+			}
+		})
+	}
+}
+
+func TestServer_PartialFailure(t *testing.T) {
+	clusters := map[string]*fakeCluster{
+		"cluster1": newFakeCluster(t, map[string]fakeRoute{"/api/v1/pods": {body: podList("web-1")}}),
+		"cluster2": newFakeCluster(t, map[string]fakeRoute{"/api/v1/pods": {
+			status: http.StatusForbidden,
+			body:   `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403,"message":"pods is forbidden"}`,
+		}}),
+	}
+	// cluster3 has no mapping
+	server := newTestServer(clusters, "cluster1", "cluster2", "cluster3")
+
+	rec := serve(server, http.MethodGet, "/api/v1/pods", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	list, err := decode(t, rec).ToList()
+	if err != nil {
+		t.Fatalf("failed to read list: %v", err)
+	}
+	if len(list.Items) != 1 {
+		t.Errorf("expected 1 item, got %d", len(list.Items))
+	}
+
+	warnings := strings.Join(rec.Header().Values("Warning"), "\n")
+	for _, want := range []string{`cluster cluster2: pods is forbidden`, `cluster cluster3: `} {
+		if !strings.Contains(warnings, want) {
+			t.Errorf("expected warnings to contain %q, got %q", want, warnings)
+		}
+	}
+}
+
+func TestServer_AllClustersFail(t *testing.T) {
+	clusters := map[string]*fakeCluster{
+		"cluster1": newFakeCluster(t, map[string]fakeRoute{"/api/v1/pods": {status: http.StatusInternalServerError, body: "boom"}}),
+		"cluster2": newFakeCluster(t, map[string]fakeRoute{"/api/v1/pods": {status: http.StatusForbidden, body: "denied"}}),
+	}
+	server := newTestServer(clusters, "cluster1", "cluster2")
+
+	rec := serve(server, http.MethodGet, "/api/v1/pods", nil)
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("expected status 502, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, want := range []string{"cluster cluster1: boom", "cluster cluster2: denied"} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("expected body to contain %q, got %q", want, rec.Body.String())
+		}
+	}
+}
+
+func TestServer_ClusterRoute(t *testing.T) {
+	clusters := map[string]*fakeCluster{
+		"cluster1": newFakeCluster(t, nil),
+		"cluster2": newFakeCluster(t, map[string]fakeRoute{"/api/v1/namespaces/default/pods": {status: http.StatusCreated, body: pod("web-1")}}),
+	}
+	server := newTestServer(clusters, "cluster1", "cluster2")
+
+	rec := serve(server, http.MethodPost, "/clusters/cluster2/api/v1/namespaces/default/pods?dryRun=All", http.Header{
+		"Authorization":    {"Bearer local"},
+		"Impersonate-User": {"admin"},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req := clusters["cluster2"].lastRequest(t)
+	if req.URL.Path != "/api/v1/namespaces/default/pods" || req.URL.RawQuery != "dryRun=All" {
+		t.Errorf("expected request for /api/v1/namespaces/default/pods?dryRun=All, got %s", req.URL.RequestURI())
+	}
+	for _, header := range []string{"Authorization", "Impersonate-User"} {
+		if value := req.Header.Get(header); value != "" {
+			t.Errorf("expected %s not to be forwarded, got %q", header, value)
+		}
+	}
+
+	if rec := serve(server, http.MethodGet, "/clusters/unknown/api/v1/pods", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown cluster, got %d", rec.Code)
+	}
+}
+
+func TestServer_FleetRejects(t *testing.T) {
+	server := newTestServer(map[string]*fakeCluster{"cluster1": newFakeCluster(t, nil)}, "cluster1")
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{name: "write", method: http.MethodDelete, target: "/api/v1/namespaces/default/pods/web-1", wantStatus: http.StatusMethodNotAllowed},
+		{name: "watch", method: http.MethodGet, target: "/api/v1/pods?watch=true", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(server, tt.method, tt.target, nil)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), ClusterPathPrefix) {
+				t.Errorf("expected the error to point to %s, got %s", ClusterPathPrefix, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_Discovery(t *testing.T) {
+	clusters := map[string]*fakeCluster{
+		"cluster1": newFakeCluster(t, map[string]fakeRoute{"/api/v1": {status: http.StatusServiceUnavailable, body: "unavailable"}}),
+		"cluster2": newFakeCluster(t, map[string]fakeRoute{"/api/v1": {body: `{"kind":"APIResourceList","groupVersion":"v1","resources":[]}`}}),
+		"cluster3": newFakeCluster(t, map[string]fakeRoute{"/api/v1": {body: `{"kind":"APIResourceList","groupVersion":"v1","resources":[{"name":"pods"}]}`}}),
+	}
+	server := newTestServer(clusters, "cluster3", "cluster1", "cluster2")
+
+	rec := serve(server, http.MethodGet, "/api/v1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"resources":[]`) {
+		t.Errorf("expected the discovery document of cluster2, got %s", rec.Body.String())
+	}
+}
+
+func TestIsDiscoveryPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/", want: true},
+		{path: "/api", want: true},
+		{path: "/api/v1", want: true},
+		{path: "/apis/apps/v1", want: true},
+		{path: "/version", want: true},
+		{path: "/openapi/v3/apis/apps/v1", want: true},
+		{path: "/api/v1/pods", want: false},
+		{path: "/apis/apps/v1/deployments", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isDiscoveryPath(tt.path); got != tt.want {
+			t.Errorf("expected isDiscoveryPath(%q) to be %t, got %t", tt.path, tt.want, got)
+		}
+	}
+}
+
+func TestServer_AcceptHosts(t *testing.T) {
+	server := newTestServer(map[string]*fakeCluster{"cluster1": newFakeCluster(t, map[string]fakeRoute{"/version": {body: `{}`}})}, "cluster1")
+	server.SetAcceptHosts([]*regexp.Regexp{regexp.MustCompile(`^localhost$`)})
+
+	tests := []struct {
+		host       string
+		wantStatus int
+	}{
+		{host: "localhost:8001", wantStatus: http.StatusOK},
+		{host: "attacker.example.com", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/version", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("expected status %d for host %s, got %d", tt.wantStatus, tt.host, rec.Code)
+		}
+	}
+}
+
+func TestServer_RejectPaths(t *testing.T) {
+	cluster := newFakeCluster(t, map[string]fakeRoute{
+		"/api/v1/namespaces/default/pods/web-1":      {body: pod("web-1")},
+		"/api/v1/namespaces/default/pods/web-1/exec": {body: `{}`},
+	})
+	server := newTestServer(map[string]*fakeCluster{"cluster1": cluster}, "cluster1")
+	server.SetRejectPaths([]*regexp.Regexp{regexp.MustCompile(`^/api/.*/pods/.*/exec`)})
+	server.SetRejectMethods([]*regexp.Regexp{regexp.MustCompile(`^DELETE$`)})
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+	}{
+		{name: "get", method: http.MethodGet, target: "/clusters/cluster1/api/v1/namespaces/default/pods/web-1", wantStatus: http.StatusOK},
+		{name: "exec", method: http.MethodPost, target: "/clusters/cluster1/api/v1/namespaces/default/pods/web-1/exec?command=sh", wantStatus: http.StatusForbidden},
+		{name: "exec with dot segments", method: http.MethodPost, target: "/clusters/cluster1/api/v1/namespaces/default/pods/web-1/./exec", wantStatus: http.StatusForbidden},
+		{name: "rejected method", method: http.MethodDelete, target: "/clusters/cluster1/api/v1/namespaces/default/pods/web-1", wantStatus: http.StatusForbidden},
+		{name: "fleet exec", method: http.MethodPost, target: "/api/v1/namespaces/default/pods/web-1/exec", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(server, tt.method, tt.target, nil)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	for _, req := range cluster.requests {
+		if req.Method != http.MethodGet {
+			t.Errorf("expected rejected requests not to reach the cluster, got %s %s", req.Method, req.URL.Path)
+		}
+	}
+}